@@ -6,5 +6,6 @@ import (
 )
 
 func (s *Server) router() {
-	routes.ReceiverRoutes(s.app, handler.NewReceiverHandler(s.receiverService))
+	routes.ReceiverRoutes(s.app, handler.NewReceiverHandler(s.receiverService), handler.Idempotent(s.idempotency))
+	routes.ImportRoutes(s.app, handler.NewImportHandler(s.importer))
 }
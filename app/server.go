@@ -4,20 +4,30 @@ import (
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/lucasszmt/transfeera-challenge/domain/idempotency"
 	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
 	"os"
 )
 
+// bodyLimit allows import files much larger than the fiber default of 4MB, as they are processed in the background
+const bodyLimit = 32 * 1024 * 1024
+
 type Server struct {
 	app             *fiber.App
 	receiverService receiver.UseCase
+	importer        receiver.ImportUseCase
+	idempotency     *idempotency.Guard
 }
 
-func NewServer(receiverService receiver.UseCase) *Server {
+func NewServer(receiverService receiver.UseCase, importer receiver.ImportUseCase, idempotency *idempotency.Guard) *Server {
 	server := &Server{
-		app:             fiber.New(),
+		app:             fiber.New(fiber.Config{BodyLimit: bodyLimit}),
 		receiverService: receiverService,
+		importer:        importer,
+		idempotency:     idempotency,
 	}
+	server.app.Use(requestid.New())
 	server.app.Use(logger.New())
 	server.router()
 	return server
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lucasszmt/transfeera-challenge/domain/idempotency"
+	"net/http"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed from a previous request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Idempotent replays the stored response to requests retried with the same Idempotency-Key header, the
+// request actor, method, path and body must be the same as the ones of the first request, so a key reused
+// by another actor is refused instead of replaying a response that isn't theirs. Requests without the
+// header are handled as usual, and responses with a server error aren't stored, so they can be retried
+func Idempotent(guard *idempotency.Guard) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if len(key) == 0 {
+			return c.Next()
+		}
+		fingerprint := idempotency.Fingerprint([]byte(audit(c).Actor), []byte(c.Method()), []byte(c.Path()), c.Body())
+		replay, err := guard.Begin(key, fingerprint)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, idempotency.ErrInvalidKey):
+				status = http.StatusBadRequest
+			case errors.Is(err, idempotency.ErrFingerprintMismatch):
+				status = http.StatusUnprocessableEntity
+			case errors.Is(err, idempotency.ErrRequestInProgress):
+				status = http.StatusConflict
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"status": false,
+				"errors": err.Error(),
+			})
+		}
+		if replay != nil {
+			c.Set(IdempotentReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, replay.ContentType)
+			return c.Status(replay.StatusCode).Send(replay.Body)
+		}
+
+		if err = c.Next(); err != nil {
+			guard.Release(key, fingerprint)
+			return err
+		}
+		if c.Response().StatusCode() >= http.StatusInternalServerError {
+			guard.Release(key, fingerprint)
+			return nil
+		}
+		guard.Complete(key, fingerprint, idempotency.Response{
+			StatusCode:  c.Response().StatusCode(),
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        c.Response().Body(),
+		})
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lucasszmt/transfeera-challenge/domain/idempotency"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps the idempotency keys in a map, without expiration
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+}
+
+func (m *memoryStore) Reserve(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record, ok := m.records[key]; ok {
+		return record, nil
+	}
+	m.records[key] = &idempotency.Record{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (m *memoryStore) Complete(key, fingerprint string, resp idempotency.Response) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	body := append([]byte(nil), resp.Body...)
+	m.records[key].Response = &idempotency.Response{StatusCode: resp.StatusCode, ContentType: resp.ContentType, Body: body}
+	return nil
+}
+
+func (m *memoryStore) Release(key, fingerprint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+func (m *memoryStore) Purge(expiredBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotent(t *testing.T) {
+	type request struct {
+		key   string
+		body  string
+		actor string
+	}
+	type expectedResponse struct {
+		Code     int
+		Data     string
+		Replayed string
+	}
+	const host = "http://localhost"
+	const route = "/api/v1/receiver"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name       string
+		running    string
+		failStatus int
+		requests   []request
+		want       []expectedResponse
+	}{
+		{
+			name:     "Should replay the response of a retried request",
+			requests: []request{{"key-1", `{"name":"a"}`, ""}, {"key-1", `{"name":"a"}`, ""}},
+			want: []expectedResponse{
+				{http.StatusCreated, `{"data":"call 1","status":true}`, ""},
+				{http.StatusCreated, `{"data":"call 1","status":true}`, "true"},
+			},
+		},
+		{
+			name:     "Should refuse a key reused with another body",
+			requests: []request{{"key-1", `{"name":"a"}`, ""}, {"key-1", `{"name":"b"}`, ""}},
+			want: []expectedResponse{
+				{http.StatusCreated, `{"data":"call 1","status":true}`, ""},
+				{http.StatusUnprocessableEntity, `{"errors":"idempotency key was already used with a different request","status":false}`, ""},
+			},
+		},
+		{
+			name:     "Should refuse a key reused by another actor",
+			requests: []request{{"key-1", `{"name":"a"}`, "alice"}, {"key-1", `{"name":"a"}`, "bob"}},
+			want: []expectedResponse{
+				{http.StatusCreated, `{"data":"call 1","status":true}`, ""},
+				{http.StatusUnprocessableEntity, `{"errors":"idempotency key was already used with a different request","status":false}`, ""},
+			},
+		},
+		{
+			name:     "Should run requests without a key every time",
+			requests: []request{{"", `{"name":"a"}`, ""}, {"", `{"name":"a"}`, ""}},
+			want: []expectedResponse{
+				{http.StatusCreated, `{"data":"call 1","status":true}`, ""},
+				{http.StatusCreated, `{"data":"call 2","status":true}`, ""},
+			},
+		},
+		{
+			name:     "Should refuse a key held by a running request",
+			running:  "key-1",
+			requests: []request{{"key-1", `{"name":"a"}`, ""}},
+			want: []expectedResponse{
+				{http.StatusConflict, `{"errors":"a request with the same idempotency key is still in progress","status":false}`, ""},
+			},
+		},
+		{
+			name:       "Should not store server errors",
+			failStatus: http.StatusServiceUnavailable,
+			requests:   []request{{"key-1", `{"name":"a"}`, ""}, {"key-1", `{"name":"a"}`, ""}},
+			want: []expectedResponse{
+				{http.StatusServiceUnavailable, `{"data":"call 1","status":false}`, ""},
+				{http.StatusServiceUnavailable, `{"data":"call 2","status":false}`, ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{records: map[string]*idempotency.Record{}}
+			if len(tt.running) > 0 {
+				store.records[tt.running] = &idempotency.Record{
+					Fingerprint: idempotency.Fingerprint(nil, []byte("POST"), []byte(route), []byte(`{"name":"a"}`)),
+				}
+			}
+			calls := 0
+			app := fiber.New()
+			app.Post(route, Idempotent(idempotency.NewGuard(log.MockLogger{}, store, time.Hour)), func(c *fiber.Ctx) error {
+				calls++
+				if tt.failStatus > 0 {
+					return c.Status(tt.failStatus).JSON(fiber.Map{"status": false, "data": fmt.Sprint("call ", calls)})
+				}
+				return c.Status(http.StatusCreated).JSON(fiber.Map{"status": true, "data": fmt.Sprint("call ", calls)})
+			})
+			for i, r := range tt.requests {
+				req := httptest.NewRequest("POST", fmt.Sprint(host, route), strings.NewReader(r.body))
+				req.Header.Add("Content-Type", "application/json")
+				if len(r.key) > 0 {
+					req.Header.Add(IdempotencyKeyHeader, r.key)
+				}
+				if len(r.actor) > 0 {
+					req.Header.Add(ActorHeader, r.actor)
+				}
+				resp, err := app.Test(req, timeout)
+				require.NoErrorf(t, err, "failed to make a test request")
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				require.NoErrorf(t, err, "failed to read response body. Cause  %v", err)
+				require.Equal(t, tt.want[i].Code, resp.StatusCode)
+				require.Equal(t, tt.want[i].Data, string(body))
+				require.Equal(t, tt.want[i].Replayed, resp.Header.Get(IdempotentReplayedHeader))
+			}
+		})
+	}
+}
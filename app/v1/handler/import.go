@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ImportHandler interface {
+	Submit() fiber.Handler
+	Get() fiber.Handler
+	Errors() fiber.Handler
+}
+
+type importHandler struct {
+	importer receiver.ImportUseCase
+}
+
+func NewImportHandler(useCase receiver.ImportUseCase) ImportHandler {
+	return &importHandler{importer: useCase}
+}
+
+// Submit queues the import of the uploaded CSV, answering with the job to be followed at its Location
+func (i *importHandler) Submit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := dtos.ImportReceiversRequest{}
+		if err := c.QueryParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid query params",
+			})
+		}
+		header, err := c.FormFile(ImportFileField)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": fmt.Sprintf("a csv file is required in the %s field", ImportFileField),
+			})
+		}
+		file, err := header.Open()
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "unable to read the uploaded file",
+			})
+		}
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "unable to read the uploaded file",
+			})
+		}
+		job, err := i.importer.SubmitImport(audit(c), content, req)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		c.Location(fmt.Sprintf("%s/%s", strings.TrimSuffix(c.Path(), "/"), job.Id))
+		return c.Status(http.StatusAccepted).JSON(fiber.Map{
+			"status": true,
+			"data":   job,
+		})
+	}
+}
+
+func (i *importHandler) Get() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := i.importer.ImportJob(c.Params("id"))
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": true,
+			"data":   job,
+		})
+	}
+}
+
+// Errors downloads the invalid rows of the job as a CSV with their line and error, the report grows while the
+// job runs
+func (i *importHandler) Errors() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		rows, err := i.importer.ImportJobErrors(id)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"line", "error"})
+		for _, row := range rows {
+			_ = w.Write([]string{strconv.Itoa(row.Line), row.Error})
+		}
+		w.Flush()
+		c.Attachment(fmt.Sprintf("import-%s-errors.csv", id))
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		return c.Status(http.StatusOK).Send(buf.Bytes())
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type importServiceMock struct {
+	Err        error
+	SubmitMock func(file []byte, req dtos.ImportReceiversRequest) (*dtos.ImportJobResponse, error)
+	JobMock    func(id string) (*dtos.ImportJobResponse, error)
+	ErrorsMock func(id string) ([]dtos.ImportJobError, error)
+}
+
+func (i importServiceMock) SubmitImport(audit dtos.Audit, file []byte, req dtos.ImportReceiversRequest) (*dtos.ImportJobResponse, error) {
+	switch {
+	case i.SubmitMock != nil:
+		return i.SubmitMock(file, req)
+	default:
+		return nil, i.Err
+	}
+}
+
+func (i importServiceMock) ImportJob(id string) (*dtos.ImportJobResponse, error) {
+	switch {
+	case i.JobMock != nil:
+		return i.JobMock(id)
+	default:
+		return nil, i.Err
+	}
+}
+
+func (i importServiceMock) ImportJobErrors(id string) ([]dtos.ImportJobError, error) {
+	switch {
+	case i.ErrorsMock != nil:
+		return i.ErrorsMock(id)
+	default:
+		return nil, i.Err
+	}
+}
+
+func Test_importHandler_Submit(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/imports"
+	const timeout = int(time.Hour * 1)
+	id := uuid.MustParse("9260c278-031f-4d2e-976e-b093dd0452fc")
+	createdAt := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		service      receiver.ImportUseCase
+		query        string
+		file         string
+		wantCode     int
+		wantLocation string
+		wantData     string
+	}{
+		{
+			name: "Should queue the import and point to the job",
+			service: importServiceMock{SubmitMock: func(file []byte, req dtos.ImportReceiversRequest) (*dtos.ImportJobResponse, error) {
+				if string(file) != "name,doc\nFlea,471.550.590-80\n" || !req.AllOrNothing {
+					return nil, errors.New("unexpected import")
+				}
+				return &dtos.ImportJobResponse{Id: id, Status: receiver.JobQueued, AllOrNothing: true, Total: 1, CreatedAt: createdAt}, nil
+			}},
+			query:        "?all_or_nothing=true",
+			file:         "name,doc\nFlea,471.550.590-80\n",
+			wantCode:     http.StatusAccepted,
+			wantLocation: "/api/v1/imports/9260c278-031f-4d2e-976e-b093dd0452fc",
+			wantData:     `{"data":{"id":"9260c278-031f-4d2e-976e-b093dd0452fc","status":"queued","dry_run":false,"all_or_nothing":true,"total":1,"processed":0,"created":0,"failed":0,"created_at":"2023-02-01T10:00:00Z"},"status":true}`,
+		},
+		{
+			name:     "Should return a bad request without a file",
+			service:  importServiceMock{},
+			wantCode: http.StatusBadRequest,
+			wantData: `{"errors":"a csv file is required in the file field","status":false}`,
+		},
+		{
+			name:     "Should return a unprocessable entity for an invalid file",
+			service:  importServiceMock{Err: fmt.Errorf("%w: missing column \"doc\"", receiver.ErrInvalidImportFile)},
+			file:     "name\nFlea\n",
+			wantCode: http.StatusUnprocessableEntity,
+			wantData: `{"errors":"invalid import file: missing column \"doc\"","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post(route, NewImportHandler(tt.service).Submit())
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			if len(tt.file) > 0 {
+				part, err := form.CreateFormFile(ImportFileField, "receivers.csv")
+				require.NoError(t, err)
+				_, err = part.Write([]byte(tt.file))
+				require.NoError(t, err)
+			}
+			require.NoError(t, form.Close())
+			req := httptest.NewRequest("POST", fmt.Sprint(host, route, tt.query), body)
+			req.Header.Add("Content-Type", form.FormDataContentType())
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			require.Equal(t, tt.wantLocation, resp.Header.Get(fiber.HeaderLocation))
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, string(data))
+		})
+	}
+}
+
+func Test_importHandler_Get(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/imports/:id"
+	const timeout = int(time.Hour * 1)
+	id := uuid.MustParse("9260c278-031f-4d2e-976e-b093dd0452fc")
+	createdAt := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)
+	startedAt := createdAt.Add(time.Second)
+	tests := []struct {
+		name     string
+		service  receiver.ImportUseCase
+		id       string
+		wantCode int
+		wantData string
+	}{
+		{
+			name: "Should return the progress of the job",
+			service: importServiceMock{JobMock: func(id string) (*dtos.ImportJobResponse, error) {
+				return &dtos.ImportJobResponse{Id: uuid.MustParse(id), Status: receiver.JobRunning, Total: 1000,
+					Processed: 500, Created: 498, Failed: 2, CreatedAt: createdAt, StartedAt: &startedAt}, nil
+			}},
+			id:       id.String(),
+			wantCode: http.StatusOK,
+			wantData: `{"data":{"id":"9260c278-031f-4d2e-976e-b093dd0452fc","status":"running","dry_run":false,"all_or_nothing":false,"total":1000,"processed":500,"created":498,"failed":2,"created_at":"2023-02-01T10:00:00Z","started_at":"2023-02-01T10:00:01Z"},"status":true}`,
+		},
+		{
+			name:     "Should return not found for an unknown job",
+			service:  importServiceMock{Err: receiver.ErrImportJobNotFound},
+			id:       id.String(),
+			wantCode: http.StatusNotFound,
+			wantData: `{"errors":"import job not found","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get(route, NewImportHandler(tt.service).Get())
+			req := httptest.NewRequest("GET", fmt.Sprint(host, "/api/v1/imports/", tt.id), nil)
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, string(data))
+		})
+	}
+}
+
+func Test_importHandler_Errors(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/imports/:id/errors"
+	const timeout = int(time.Hour * 1)
+	id := "9260c278-031f-4d2e-976e-b093dd0452fc"
+	tests := []struct {
+		name            string
+		service         receiver.ImportUseCase
+		wantCode        int
+		wantDisposition string
+		wantData        string
+	}{
+		{
+			name: "Should download the invalid rows as csv",
+			service: importServiceMock{ErrorsMock: func(id string) ([]dtos.ImportJobError, error) {
+				return []dtos.ImportJobError{
+					{Line: 3, Error: "invalid import row: expected 2 fields but got 1"},
+					{Line: 7, Error: "receiver already exists: pix key repeated from line 2"},
+				}, nil
+			}},
+			wantCode:        http.StatusOK,
+			wantDisposition: `attachment; filename="import-9260c278-031f-4d2e-976e-b093dd0452fc-errors.csv"`,
+			wantData: "line,error\n" +
+				"3,invalid import row: expected 2 fields but got 1\n" +
+				"7,receiver already exists: pix key repeated from line 2\n",
+		},
+		{
+			name:     "Should return not found for an unknown job",
+			service:  importServiceMock{Err: receiver.ErrImportJobNotFound},
+			wantCode: http.StatusNotFound,
+			wantData: `{"errors":"import job not found","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get(route, NewImportHandler(tt.service).Errors())
+			req := httptest.NewRequest("GET", fmt.Sprint(host, "/api/v1/imports/", id, "/errors"), nil)
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			require.Equal(t, tt.wantDisposition, resp.Header.Get(fiber.HeaderContentDisposition))
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, string(data))
+		})
+	}
+}
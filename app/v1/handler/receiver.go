@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
@@ -8,15 +9,37 @@ import (
 	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
 	"github.com/lucasszmt/transfeera-challenge/utils"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ActorHeader carries the identification of the user operating the API, recorded in the receiver history
+	ActorHeader = "X-User"
+	// ImportFileField is the multipart form field holding the CSV of a receivers import
+	ImportFileField = "file"
 )
 
 type ReceiverHandler interface {
 	Create() fiber.Handler
+	CreateFromBRCode() fiber.Handler
+	Import() fiber.Handler
 	Update() fiber.Handler
+	Transition() fiber.Handler
+	Validate() fiber.Handler
 	List() fiber.Handler
 	Get() fiber.Handler
+	History() fiber.Handler
 	Search() fiber.Handler
+	Export() fiber.Handler
 	Delete() fiber.Handler
+	Restore() fiber.Handler
+	ListPixKeys() fiber.Handler
+	AddPixKey() fiber.Handler
+	RemovePixKey() fiber.Handler
+	SetPreferredPixKey() fiber.Handler
+	BRCode() fiber.Handler
 }
 
 type receiverHandler struct {
@@ -43,8 +66,45 @@ func (r *receiverHandler) Create() fiber.Handler {
 				"errors": fmt.Sprintf("invalid data request: %s", err),
 			})
 		}
-		resp, err := r.recvService.CreateReceiver(req)
+		resp, err := r.recvService.CreateReceiver(audit(c), req)
+		if err != nil {
+			var duplicate *receiver.DuplicateReceiverError
+			if errors.As(err, &duplicate) {
+				return duplicateResponse(c, duplicate)
+			}
+			return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+				"status": false,
+				"errors": fmt.Sprintf("unable to create receiver cause %s", err),
+			})
+		}
+		return c.Status(http.StatusCreated).JSON(fiber.Map{
+			"status": true,
+			"data":   fmt.Sprintf("user with id %s created", resp.Id()),
+		})
+	}
+}
+
+func (r *receiverHandler) CreateFromBRCode() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := dtos.CreateFromBRCodeRequest{}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := utils.ValidateStruct(req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": fmt.Sprintf("invalid data request: %s", err),
+			})
+		}
+		resp, err := r.recvService.CreateReceiverFromBRCode(audit(c), req)
 		if err != nil {
+			var duplicate *receiver.DuplicateReceiverError
+			if errors.As(err, &duplicate) {
+				return duplicateResponse(c, duplicate)
+			}
 			return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
 				"status": false,
 				"errors": fmt.Sprintf("unable to create receiver cause %s", err),
@@ -57,6 +117,43 @@ func (r *receiverHandler) Create() fiber.Handler {
 	}
 }
 
+// Import creates receivers out of the CSV sent in the file field of a multipart form, answering with the
+// report of every row
+func (r *receiverHandler) Import() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := dtos.ImportReceiversRequest{}
+		if err := c.QueryParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid query params",
+			})
+		}
+		header, err := c.FormFile(ImportFileField)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": fmt.Sprintf("a csv file is required in the %s field", ImportFileField),
+			})
+		}
+		file, err := header.Open()
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "unable to read the uploaded file",
+			})
+		}
+		defer file.Close()
+		report, err := r.recvService.ImportReceivers(audit(c), file, req)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": report.Failed == 0,
+			"report": report,
+		})
+	}
+}
+
 func (r *receiverHandler) Update() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		req := dtos.UpdateReceiverRequest{}
@@ -73,9 +170,28 @@ func (r *receiverHandler) Update() fiber.Handler {
 				"errors": fmt.Sprintf("invalid data request: %s", err),
 			})
 		}
-		err = r.recvService.UpdateReceiver(req)
+		if match := c.Get(fiber.HeaderIfMatch); len(match) > 0 {
+			if req.Version, err = parseETag(match); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+					"status": false,
+					"errors": fmt.Sprintf("invalid If-Match header: %s", err),
+				})
+			}
+		}
+		err = r.recvService.UpdateReceiver(audit(c), req)
 		if err != nil {
-			return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			status := http.StatusUnprocessableEntity
+			switch {
+			case errors.Is(err, receiver.ErrReceiverNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, receiver.ErrVersionRequired):
+				status = http.StatusPreconditionRequired
+			case errors.Is(err, receiver.ErrVersionMismatch):
+				status = http.StatusPreconditionFailed
+			case errors.Is(err, receiver.ErrReceiverAlreadyExists):
+				status = http.StatusConflict
+			}
+			return c.Status(status).JSON(fiber.Map{
 				"status": false,
 				"errors": fmt.Sprintf("unable to update the receiver requested: %s", err),
 			})
@@ -87,24 +203,93 @@ func (r *receiverHandler) Update() fiber.Handler {
 	}
 }
 
-func (r *receiverHandler) List() fiber.Handler {
+func (r *receiverHandler) Transition() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		param := struct {
-			Page uint
+			Id string `params:"id"`
 		}{}
-		if err := c.QueryParser(&param); err != nil {
+		req := dtos.TransitionStatusRequest{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := utils.ValidateStruct(req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": fmt.Sprintf("invalid data request: %s", err),
+			})
+		}
+		if err := r.recvService.TransitionReceiver(audit(c), param.Id, req); err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": true,
+			"data":   fmt.Sprintf("user with id %s moved to %s", param.Id, req.Status),
+		})
+	}
+}
+
+func (r *receiverHandler) Validate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id string `params:"id"`
+		}{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		result, err := r.recvService.ValidateReceiver(audit(c), param.Id)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status":     true,
+			"validation": result,
+		})
+	}
+}
+
+func (r *receiverHandler) List() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := dtos.ListReceiversRequest{}
+		if err := c.QueryParser(&req); err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"status": true,
 				"error":  fmt.Sprintf("invalid param for pages, it should be an integer"),
 			})
 		}
-		receivers, err := r.recvService.ListReceivers(int(param.Page))
+		filter, filterParams, err := receiverFilter(c, "page", "page_size", "cursor", "total")
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": err.Error(),
+			})
+		}
+		page, err := r.recvService.ListReceivers(req, filter)
 		if err != nil {
+			if errors.Is(err, receiver.ErrInvalidCursor) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+					"status": false,
+					"errors": err.Error(),
+				})
+			}
 			return err
 		}
+		paginationLinks(c, req, filterParams, page.Pagination)
 		return c.Status(http.StatusOK).JSON(fiber.Map{
-			"status":    true,
-			"receivers": receivers,
+			"status":     true,
+			"receivers":  page.Receivers,
+			"pagination": page.Pagination,
 		})
 	}
 }
@@ -133,6 +318,7 @@ func (r *receiverHandler) Get() fiber.Handler {
 				"errors": "some unexpected err has happened",
 			})
 		}
+		c.Set(fiber.HeaderETag, etag(resp.Version))
 		return c.Status(http.StatusOK).JSON(fiber.Map{
 			"status":    true,
 			"receivers": resp,
@@ -140,6 +326,28 @@ func (r *receiverHandler) Get() fiber.Handler {
 	}
 }
 
+func (r *receiverHandler) History() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id string `params:"id"`
+		}{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		history, err := r.recvService.ReceiverHistory(param.Id)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status":  true,
+			"history": history,
+		})
+	}
+}
+
 func (r *receiverHandler) Search() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		params := dtos.SearchRequest{}
@@ -155,9 +363,30 @@ func (r *receiverHandler) Search() fiber.Handler {
 				"error":  "query param required",
 			})
 		}
+		filter, _, err := receiverFilter(c, "query", "limit", "syntax")
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"error":  err.Error(),
+			})
+		}
 		//success case
-		recv, err := r.recvService.SearchReceivers(params)
+		recv, err := r.recvService.SearchReceivers(params, filter)
 		if err != nil {
+			var syntaxErr *receiver.QuerySyntaxError
+			if errors.As(err, &syntaxErr) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+					"status":   false,
+					"error":    err.Error(),
+					"position": syntaxErr.Position,
+				})
+			}
+			if errors.Is(err, receiver.ErrInvalidQuery) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+					"status": false,
+					"error":  err.Error(),
+				})
+			}
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"status": false,
 				"error":  "receivers not found",
@@ -170,6 +399,51 @@ func (r *receiverHandler) Search() fiber.Handler {
 	}
 }
 
+// Export streams the receivers matching the search query and the filters, the format comes from the format query
+// param or, without it, from the Accept header. The status is sent before the receivers are read, so a failure while
+// streaming closes the connection instead, leaving the client with an incomplete response rather than a truncated file
+// that looks complete
+func (r *receiverHandler) Export() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := dtos.ExportReceiversRequest{}
+		if err := c.QueryParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid query params",
+			})
+		}
+		filter, _, err := receiverFilter(c, "query", "format", "columns", "mask")
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": err.Error(),
+			})
+		}
+		if len(req.Format) == 0 {
+			req.Format = exportFormat(c)
+			if len(req.Format) == 0 {
+				return c.Status(http.StatusNotAcceptable).JSON(fiber.Map{
+					"status": false,
+					"errors": fmt.Sprintf("receivers can only be exported as %s", strings.Join(exportMediaTypes(), ", ")),
+				})
+			}
+		}
+		export, err := r.recvService.ExportReceivers(req, filter)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		c.Attachment(export.FileName())
+		c.Set(fiber.HeaderContentType, export.ContentType())
+		ctx := c.Context()
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			if err := export.Stream(w); err != nil {
+				_ = ctx.Conn().Close()
+			}
+		})
+		return nil
+	}
+}
+
 func (r *receiverHandler) Delete() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		req := dtos.DeleReceiverRequest{}
@@ -186,7 +460,7 @@ func (r *receiverHandler) Delete() fiber.Handler {
 				"errors": "ids field is required, and it needs to be an array",
 			})
 		}
-		if err := r.recvService.DeleteReceivers(req); err != nil {
+		if err := r.recvService.DeleteReceivers(audit(c), req); err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"status": false,
 				"errors": fmt.Sprintf("an err has happened while deliting the following items %v", req.Ids),
@@ -195,3 +469,286 @@ func (r *receiverHandler) Delete() fiber.Handler {
 		return c.SendStatus(http.StatusNoContent)
 	}
 }
+
+func (r *receiverHandler) Restore() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := dtos.RestoreReceiversRequest{}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := utils.ValidateStruct(req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "ids field is required, and it needs to be an array",
+			})
+		}
+		if err := r.recvService.RestoreReceivers(audit(c), req); err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": true,
+			"data":   fmt.Sprintf("receivers %v restored", req.Ids),
+		})
+	}
+}
+
+func (r *receiverHandler) ListPixKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id string `params:"id"`
+		}{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		keys, err := r.recvService.ListPixKeys(param.Id)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status":   true,
+			"pix_keys": keys,
+		})
+	}
+}
+
+func (r *receiverHandler) AddPixKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id string `params:"id"`
+		}{}
+		req := dtos.AddPixKeyRequest{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := utils.ValidateStruct(req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": fmt.Sprintf("invalid data request: %s", err),
+			})
+		}
+		key, err := r.recvService.AddPixKey(audit(c), param.Id, req)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusCreated).JSON(fiber.Map{
+			"status": true,
+			"data":   fmt.Sprintf("pix key with id %s created", key.Id()),
+		})
+	}
+}
+
+func (r *receiverHandler) RemovePixKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id    string `params:"id"`
+			KeyId string `params:"keyId"`
+		}{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := r.recvService.RemovePixKey(audit(c), param.Id, param.KeyId); err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.SendStatus(http.StatusNoContent)
+	}
+}
+
+func (r *receiverHandler) SetPreferredPixKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id    string `params:"id"`
+			KeyId string `params:"keyId"`
+		}{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := r.recvService.SetPreferredPixKey(audit(c), param.Id, param.KeyId); err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": true,
+			"data":   fmt.Sprintf("pix key with id %s set as preferred", param.KeyId),
+		})
+	}
+}
+
+func (r *receiverHandler) BRCode() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		param := struct {
+			Id string `params:"id"`
+		}{}
+		req := dtos.BRCodeRequest{}
+		if err := c.ParamsParser(&param); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid data request",
+			})
+		}
+		if err := c.QueryParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"status": false,
+				"errors": "invalid query params",
+			})
+		}
+		code, err := r.recvService.GenerateBRCode(param.Id, req)
+		if err != nil {
+			return domainErrorResponse(c, err)
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": true,
+			"brcode": code,
+		})
+	}
+}
+
+// audit identifies the author of the request, the request id is set by the requestid middleware, or taken
+// from the X-Request-ID header sent by the client
+func audit(c *fiber.Ctx) dtos.Audit {
+	return dtos.Audit{
+		Actor:     c.Get(ActorHeader),
+		RequestID: c.GetRespHeader(fiber.HeaderXRequestID, c.Get(fiber.HeaderXRequestID)),
+	}
+}
+
+// paginationLinks sets the RFC 8288 Link header of a page, pages asked by number link to the other page numbers and
+// the others to cursors. Page size and total are carried over to the linked pages
+func paginationLinks(c *fiber.Ctx, req dtos.ListReceiversRequest, filterParams url.Values, pagination dtos.Pagination) {
+	link := func(key, value string) string {
+		query := url.Values{}
+		for param, values := range filterParams {
+			query[param] = values
+		}
+		if req.PageSize > 0 {
+			query.Set("page_size", strconv.Itoa(pagination.PageSize))
+		}
+		if req.Total {
+			query.Set("total", "true")
+		}
+		if len(key) > 0 {
+			query.Set(key, value)
+		}
+		if len(query) == 0 {
+			return c.BaseURL() + c.Path()
+		}
+		return c.BaseURL() + c.Path() + "?" + query.Encode()
+	}
+	links := []string{link("", ""), "first"}
+	switch {
+	case pagination.Page > 0:
+		if len(pagination.NextCursor) > 0 {
+			links = append(links, link("page", strconv.Itoa(pagination.Page+1)), "next")
+		}
+		if pagination.Page > 1 {
+			links = append(links, link("page", strconv.Itoa(pagination.Page-1)), "prev")
+		}
+	default:
+		if len(pagination.NextCursor) > 0 {
+			links = append(links, link("cursor", pagination.NextCursor), "next")
+		}
+		if len(pagination.PrevCursor) > 0 {
+			links = append(links, link("cursor", pagination.PrevCursor), "prev")
+		}
+	}
+	c.Links(links...)
+}
+
+// receiverFilter reads the receiver filter from the query parameters left once the ones of the endpoint are taken,
+// which are returned to be kept on the links to other pages
+func receiverFilter(c *fiber.Ctx, endpointParams ...string) (receiver.Filter, url.Values, error) {
+	params := url.Values{}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		params.Add(string(key), string(value))
+	})
+	for _, param := range endpointParams {
+		params.Del(param)
+	}
+	filter, err := receiver.NewFilter(params)
+	return filter, params, err
+}
+
+// exportFormat negotiates the export format with the Accept header, the first format is taken without one
+func exportFormat(c *fiber.Ctx) string {
+	accepted := c.Accepts(exportMediaTypes()...)
+	for _, format := range receiver.ExportFormats {
+		if receiver.ExportMediaTypes[format] == accepted {
+			return format
+		}
+	}
+	return ""
+}
+
+func exportMediaTypes() []string {
+	mediaTypes := make([]string, len(receiver.ExportFormats))
+	for i, format := range receiver.ExportFormats {
+		mediaTypes[i] = receiver.ExportMediaTypes[format]
+	}
+	return mediaTypes
+}
+
+// etag writes the receiver version as a strong entity tag
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseETag reads the receiver version out of an If-Match header, weak tags are accepted since the
+// version is all the tag carries
+func parseETag(tag string) (int, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, errors.New("the entity tag must be a quoted receiver version")
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, errors.New("the entity tag must be a quoted receiver version")
+	}
+	return version, nil
+}
+
+// domainErrorResponse maps the receiver domain errors to their http status, anything unknown is
+// treated as a business rule violation
+func domainErrorResponse(c *fiber.Ctx, err error) error {
+	status := http.StatusUnprocessableEntity
+	switch {
+	case errors.Is(err, receiver.ErrReceiverNotFound), errors.Is(err, receiver.ErrPixKeyNotFound),
+		errors.Is(err, receiver.ErrImportJobNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, receiver.ErrPixKeyAlreadyRegistered), errors.Is(err, receiver.ErrStatusChanged),
+		errors.Is(err, receiver.ErrReceiverAlreadyExists):
+		status = http.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"status": false,
+		"errors": err.Error(),
+	})
+}
+
+// duplicateResponse answers the creation of a receiver already registered with the id of the existing one
+func duplicateResponse(c *fiber.Ctx, duplicate *receiver.DuplicateReceiverError) error {
+	return c.Status(http.StatusConflict).JSON(fiber.Map{
+		"status": false,
+		"errors": fmt.Sprintf("unable to create receiver cause %s", duplicate),
+		"id":     duplicate.ExistingID,
+	})
+}
@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/brcode"
 	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
 	"github.com/lucasszmt/transfeera-challenge/domain/entity"
 	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
 	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
 	"github.com/stretchr/testify/require"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -22,14 +26,26 @@ import (
 type receiverServiceMock struct {
 	Err                 error
 	CreateReceiverMock  func(request dtos.CreateReceiverRequest) (*entity.Receiver, error)
-	SearchReceiversMock func(request dtos.SearchRequest) ([]dtos.GetReceiverResponse, error)
+	SearchReceiversMock func(request dtos.SearchRequest, filter receiver.Filter) ([]dtos.SearchReceiverResponse, error)
 	UpdateReceiverMock  func(req dtos.UpdateReceiverRequest) error
-	ListReceiversMock   func(page int) ([]dtos.ListReceiversResponse, error)
+	ListReceiversMock   func(req dtos.ListReceiversRequest, filter receiver.Filter) (*dtos.ListReceiversPage, error)
 	GetReceiverMock     func(id string) (*dtos.GetReceiverResponse, error)
-	DeleteReceiverMock  func(req dtos.DeleReceiverRequest) error
+	DeleteReceiverMock  func(audit dtos.Audit, req dtos.DeleReceiverRequest) error
+	RestoreMock         func(req dtos.RestoreReceiversRequest) error
+	ListPixKeysMock     func(id string) ([]dtos.PixKeyResponse, error)
+	AddPixKeyMock       func(id string, req dtos.AddPixKeyRequest) (*entity.ReceiverPixKey, error)
+	RemovePixKeyMock    func(id string, keyID string) error
+	SetPreferredMock    func(id string, keyID string) error
+	GenerateBRCodeMock  func(id string, req dtos.BRCodeRequest) (string, error)
+	CreateFromBRCode    func(req dtos.CreateFromBRCodeRequest) (*entity.Receiver, error)
+	TransitionMock      func(id string, req dtos.TransitionStatusRequest) error
+	ValidateMock        func(id string) (*dtos.ValidationResponse, error)
+	HistoryMock         func(id string) ([]dtos.HistoryEntryResponse, error)
+	ImportMock          func(file io.Reader, req dtos.ImportReceiversRequest) (*dtos.ImportReport, error)
+	ExportMock          func(req dtos.ExportReceiversRequest, filter receiver.Filter) (*receiver.Export, error)
 }
 
-func (r receiverServiceMock) CreateReceiver(request dtos.CreateReceiverRequest) (*entity.Receiver, error) {
+func (r receiverServiceMock) CreateReceiver(audit dtos.Audit, request dtos.CreateReceiverRequest) (*entity.Receiver, error) {
 	switch {
 	case r.CreateReceiverMock != nil:
 		return r.CreateReceiverMock(request)
@@ -38,16 +54,25 @@ func (r receiverServiceMock) CreateReceiver(request dtos.CreateReceiverRequest)
 	}
 }
 
-func (r receiverServiceMock) SearchReceivers(request dtos.SearchRequest) ([]dtos.GetReceiverResponse, error) {
+func (r receiverServiceMock) ImportReceivers(audit dtos.Audit, file io.Reader, req dtos.ImportReceiversRequest) (*dtos.ImportReport, error) {
+	switch {
+	case r.ImportMock != nil:
+		return r.ImportMock(file, req)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverServiceMock) SearchReceivers(request dtos.SearchRequest, filter receiver.Filter) ([]dtos.SearchReceiverResponse, error) {
 	switch {
 	case r.SearchReceiversMock != nil:
-		return r.SearchReceiversMock(request)
+		return r.SearchReceiversMock(request, filter)
 	default:
 		return nil, r.Err
 	}
 }
 
-func (r receiverServiceMock) UpdateReceiver(req dtos.UpdateReceiverRequest) error {
+func (r receiverServiceMock) UpdateReceiver(audit dtos.Audit, req dtos.UpdateReceiverRequest) error {
 	switch {
 	case r.UpdateReceiverMock != nil:
 		return r.UpdateReceiverMock(req)
@@ -56,10 +81,10 @@ func (r receiverServiceMock) UpdateReceiver(req dtos.UpdateReceiverRequest) erro
 	}
 }
 
-func (r receiverServiceMock) ListReceivers(page int) ([]dtos.ListReceiversResponse, error) {
+func (r receiverServiceMock) ListReceivers(req dtos.ListReceiversRequest, filter receiver.Filter) (*dtos.ListReceiversPage, error) {
 	switch {
 	case r.ListReceiversMock != nil:
-		return r.ListReceiversMock(page)
+		return r.ListReceiversMock(req, filter)
 	default:
 		return nil, r.Err
 	}
@@ -74,15 +99,114 @@ func (r receiverServiceMock) GetReceiver(id string) (*dtos.GetReceiverResponse,
 	}
 }
 
-func (r receiverServiceMock) DeleteReceivers(ids dtos.DeleReceiverRequest) error {
+func (r receiverServiceMock) DeleteReceivers(audit dtos.Audit, ids dtos.DeleReceiverRequest) error {
 	switch {
 	case r.DeleteReceiverMock != nil:
-		return r.DeleteReceiverMock(ids)
+		return r.DeleteReceiverMock(audit, ids)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverServiceMock) RestoreReceivers(audit dtos.Audit, req dtos.RestoreReceiversRequest) error {
+	switch {
+	case r.RestoreMock != nil:
+		return r.RestoreMock(req)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverServiceMock) ListPixKeys(id string) ([]dtos.PixKeyResponse, error) {
+	switch {
+	case r.ListPixKeysMock != nil:
+		return r.ListPixKeysMock(id)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverServiceMock) AddPixKey(audit dtos.Audit, id string, req dtos.AddPixKeyRequest) (*entity.ReceiverPixKey, error) {
+	switch {
+	case r.AddPixKeyMock != nil:
+		return r.AddPixKeyMock(id, req)
+	default:
+		return &entity.ReceiverPixKey{}, r.Err
+	}
+}
+
+func (r receiverServiceMock) RemovePixKey(audit dtos.Audit, id string, keyID string) error {
+	switch {
+	case r.RemovePixKeyMock != nil:
+		return r.RemovePixKeyMock(id, keyID)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverServiceMock) SetPreferredPixKey(audit dtos.Audit, id string, keyID string) error {
+	switch {
+	case r.SetPreferredMock != nil:
+		return r.SetPreferredMock(id, keyID)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverServiceMock) CreateReceiverFromBRCode(audit dtos.Audit, req dtos.CreateFromBRCodeRequest) (*entity.Receiver, error) {
+	switch {
+	case r.CreateFromBRCode != nil:
+		return r.CreateFromBRCode(req)
+	default:
+		return &entity.Receiver{}, r.Err
+	}
+}
+
+func (r receiverServiceMock) TransitionReceiver(audit dtos.Audit, id string, req dtos.TransitionStatusRequest) error {
+	switch {
+	case r.TransitionMock != nil:
+		return r.TransitionMock(id, req)
 	default:
 		return r.Err
 	}
 }
 
+func (r receiverServiceMock) ValidateReceiver(audit dtos.Audit, id string) (*dtos.ValidationResponse, error) {
+	switch {
+	case r.ValidateMock != nil:
+		return r.ValidateMock(id)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverServiceMock) ReceiverHistory(id string) ([]dtos.HistoryEntryResponse, error) {
+	switch {
+	case r.HistoryMock != nil:
+		return r.HistoryMock(id)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverServiceMock) GenerateBRCode(id string, req dtos.BRCodeRequest) (string, error) {
+	switch {
+	case r.GenerateBRCodeMock != nil:
+		return r.GenerateBRCodeMock(id, req)
+	default:
+		return "", r.Err
+	}
+}
+
+func (r receiverServiceMock) ExportReceivers(req dtos.ExportReceiversRequest, filter receiver.Filter) (*receiver.Export, error) {
+	switch {
+	case r.ExportMock != nil:
+		return r.ExportMock(req, filter)
+	default:
+		return nil, r.Err
+	}
+}
+
 func Test_receiverHandler_Create(t *testing.T) {
 	type args struct {
 		service receiver.UseCase
@@ -118,6 +242,47 @@ func Test_receiverHandler_Create(t *testing.T) {
 			},
 			want: expectedResponse{Code: http.StatusCreated},
 		},
+		{
+			name: "should create user paid by bank account with success",
+			args: args{
+				receiverServiceMock{
+					CreateReceiverMock: func(req dtos.CreateReceiverRequest) (*entity.Receiver, error) {
+						return &entity.Receiver{}, nil
+					},
+				},
+			},
+			req: map[string]interface{}{
+				"name":  "Lucas Szeremeta",
+				"email": "lcuasszmt@gmail.com",
+				"doc":   "084.125.359-52",
+				"bank_account": map[string]interface{}{
+					"bank_code":      "341",
+					"branch":         "0123",
+					"account_number": "12345",
+					"account_digit":  "6",
+					"account_type":   "checking",
+				},
+			},
+			want: expectedResponse{Code: http.StatusCreated},
+		},
+		{
+			name: "should return a Bad Request status for an incomplete bank account",
+			args: args{
+				receiverServiceMock{
+					CreateReceiverMock: func(req dtos.CreateReceiverRequest) (*entity.Receiver, error) {
+						return &entity.Receiver{}, nil
+					},
+				},
+			},
+			req: map[string]interface{}{
+				"name": "Lucas Szeremeta",
+				"doc":  "084.125.359-52",
+				"bank_account": map[string]interface{}{
+					"bank_code": "341",
+				},
+			},
+			want: expectedResponse{Code: http.StatusBadRequest},
+		},
 		{
 			name: "should return a Bad Request status",
 			args: args{
@@ -166,6 +331,29 @@ func Test_receiverHandler_Create(t *testing.T) {
 			},
 			want: expectedResponse{Code: http.StatusUnprocessableEntity},
 		},
+		{
+			name: "should return a Conflict status with the id of the existing receiver",
+			args: args{
+				receiverServiceMock{
+					CreateReceiverMock: func(req dtos.CreateReceiverRequest) (*entity.Receiver, error) {
+						return nil, fmt.Errorf("wrapped: %w", &receiver.DuplicateReceiverError{
+							ExistingID: uuid.MustParse("9260c278-031f-4d2e-976e-b093dd0452fc"),
+							Field:      "pix key",
+						})
+					},
+				},
+			},
+			req: map[string]interface{}{
+				"name":         "Lucas Szeremeta",
+				"doc":          "084.125.359-52",
+				"pix_key_type": "cpf",
+				"pix_key":      "084.125359-52",
+			},
+			want: expectedResponse{
+				Code: http.StatusConflict,
+				Data: `{"errors":"unable to create receiver cause receiver already exists with the same pix key: 9260c278-031f-4d2e-976e-b093dd0452fc","id":"9260c278-031f-4d2e-976e-b093dd0452fc","status":false}`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -183,6 +371,11 @@ func Test_receiverHandler_Create(t *testing.T) {
 				resp.Body.Close()
 			}()
 			require.Equalf(t, tt.want.Code, resp.StatusCode, "failed to ping. Expected status [%d] but got [%d]", tt.want.Code, resp.StatusCode)
+			if tt.want.Data != nil {
+				body, err := io.ReadAll(resp.Body)
+				require.NoErrorf(t, err, "failed to read response body. Cause  %v", err)
+				require.Equal(t, tt.want.Data, string(body))
+			}
 		})
 	}
 }
@@ -199,10 +392,11 @@ func Test_receiverHandler_Update(t *testing.T) {
 	const route = "/api/v1/receiver"
 	const timeout = int(time.Hour * 1)
 	tests := []struct {
-		name string
-		args args
-		req  map[string]interface{}
-		want expectedResponse
+		name    string
+		args    args
+		ifMatch string
+		req     map[string]interface{}
+		want    expectedResponse
 	}{
 		{
 			name: "Should update receiver with success",
@@ -215,6 +409,7 @@ func Test_receiverHandler_Update(t *testing.T) {
 				"pix_key_type": "cpf",
 				"pix_key":      "084.125359-52",
 				"status":       "draft",
+				"version":      1,
 			},
 			want: expectedResponse{
 				Code: http.StatusOK,
@@ -232,7 +427,7 @@ func Test_receiverHandler_Update(t *testing.T) {
 			},
 			want: expectedResponse{
 				Code: http.StatusBadRequest,
-				Data: `{"errors":"invalid data request: Key: 'UpdateReceiverRequest.PixKeyType' Error:Field validation for 'PixKeyType' failed on the 'required' tag","status":false}`,
+				Data: `{"errors":"invalid data request: Key: 'UpdateReceiverRequest.PixKeyType' Error:Field validation for 'PixKeyType' failed on the 'required_without' tag","status":false}`,
 			},
 		}, {
 			name: "Should return a Status Unprocessable Entity response",
@@ -254,6 +449,101 @@ func Test_receiverHandler_Update(t *testing.T) {
 				Code: http.StatusUnprocessableEntity,
 				Data: `{"errors":"unable to update the receiver requested: error creating pix key, cause : invalid cpf or cnpj provided","status":false}`,
 			},
+		}, {
+			name: "Should take the receiver version from the If-Match header",
+			args: args{receiverServiceMock{
+				UpdateReceiverMock: func(req dtos.UpdateReceiverRequest) error {
+					if req.Version != 7 {
+						return fmt.Errorf("unexpected version %d", req.Version)
+					}
+					return nil
+				},
+			}},
+			ifMatch: `W/"7"`,
+			req: map[string]interface{}{
+				"id":           "fbd731d4-d3ac-4305-9d65-72800e821136",
+				"email":        "jcena@gmail.com",
+				"name":         "Lucas",
+				"pix_key":      "084.125359-52",
+				"status":       "valid",
+				"version":      6,
+				"pix_key_type": "cpf",
+			},
+			want: expectedResponse{
+				Code: http.StatusOK,
+				Data: `{"data":"user with id fbd731d4-d3ac-4305-9d65-72800e821136 updated","status":true}`,
+			},
+		}, {
+			name:    "Should return a bad request for a malformed If-Match header",
+			args:    args{receiverServiceMock{}},
+			ifMatch: "7",
+			req: map[string]interface{}{
+				"id":           "fbd731d4-d3ac-4305-9d65-72800e821136",
+				"name":         "Lucas",
+				"pix_key_type": "cpf",
+				"pix_key":      "084.125359-52",
+				"status":       "valid",
+			},
+			want: expectedResponse{
+				Code: http.StatusBadRequest,
+				Data: `{"errors":"invalid If-Match header: the entity tag must be a quoted receiver version","status":false}`,
+			},
+		}, {
+			name: "Should return a precondition required response without a version",
+			args: args{receiverServiceMock{
+				UpdateReceiverMock: func(req dtos.UpdateReceiverRequest) error {
+					return receiver.ErrVersionRequired
+				},
+			}},
+			req: map[string]interface{}{
+				"id":           "fbd731d4-d3ac-4305-9d65-72800e821136",
+				"name":         "Lucas",
+				"pix_key_type": "cpf",
+				"pix_key":      "084.125359-52",
+				"status":       "valid",
+			},
+			want: expectedResponse{
+				Code: http.StatusPreconditionRequired,
+				Data: `{"errors":"unable to update the receiver requested: receiver version is required, send it in the If-Match header or the version field","status":false}`,
+			},
+		}, {
+			name: "Should return a precondition failed response for a stale version",
+			args: args{receiverServiceMock{
+				UpdateReceiverMock: func(req dtos.UpdateReceiverRequest) error {
+					return receiver.ErrVersionMismatch
+				},
+			}},
+			ifMatch: `"2"`,
+			req: map[string]interface{}{
+				"id":           "fbd731d4-d3ac-4305-9d65-72800e821136",
+				"name":         "Lucas",
+				"pix_key_type": "cpf",
+				"pix_key":      "084.125359-52",
+				"status":       "valid",
+			},
+			want: expectedResponse{
+				Code: http.StatusPreconditionFailed,
+				Data: `{"errors":"unable to update the receiver requested: receiver was changed by another request, reload it and retry","status":false}`,
+			},
+		}, {
+			name: "Should return a not found response for a missing receiver",
+			args: args{receiverServiceMock{
+				UpdateReceiverMock: func(req dtos.UpdateReceiverRequest) error {
+					return receiver.ErrReceiverNotFound
+				},
+			}},
+			ifMatch: `"2"`,
+			req: map[string]interface{}{
+				"id":           "fbd731d4-d3ac-4305-9d65-72800e821136",
+				"name":         "Lucas",
+				"pix_key_type": "cpf",
+				"pix_key":      "084.125359-52",
+				"status":       "valid",
+			},
+			want: expectedResponse{
+				Code: http.StatusNotFound,
+				Data: `{"errors":"unable to update the receiver requested: receiver not found","status":false}`,
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -266,6 +556,9 @@ func Test_receiverHandler_Update(t *testing.T) {
 			jsonBody := bytes.NewReader(jsonBytes)
 			req = httptest.NewRequest("PATCH", fmt.Sprint(host, route), jsonBody)
 			req.Header.Add("Content-Type", "application/json")
+			if len(tt.ifMatch) > 0 {
+				req.Header.Add(fiber.HeaderIfMatch, tt.ifMatch)
+			}
 			resp, err := app.Test(req, timeout)
 			require.NoErrorf(t, err, "failed to make a test request")
 			defer func() {
@@ -287,11 +580,26 @@ func Test_receiverHandler_List(t *testing.T) {
 	}
 	type expectedResponse struct {
 		Code int
+		Link string
 		Data interface{}
 	}
 	const host = "http://localhost"
 	const route = "/api/v1/receiver"
 	const timeout = int(time.Hour * 1)
+	receivers := []dtos.ListReceiversResponse{
+		{
+			Id:       uuid.MustParse("fbd731d4-d3ac-4305-9d65-72800e821136"),
+			Name:     "Lucas",
+			Document: "08412535952",
+			Status:   "draft",
+		},
+		{
+			Id:       uuid.MustParse("fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b"),
+			Name:     "Lucas Szeremeta",
+			Document: "08412535952",
+			Status:   "draft",
+		},
+	}
 	tests := []struct {
 		name string
 		args args
@@ -301,57 +609,100 @@ func Test_receiverHandler_List(t *testing.T) {
 		{
 			name: "Should return a list of users with status 200 ok",
 			args: args{
-				receiverServiceMock{ListReceiversMock: func(page int) ([]dtos.ListReceiversResponse, error) {
-					return []dtos.ListReceiversResponse{
-						{
-							Id:       uuid.MustParse("fbd731d4-d3ac-4305-9d65-72800e821136"),
-							Name:     "Lucas",
-							Document: "08412535952",
-							Status:   "draft",
-						},
-						{
-							Id:       uuid.MustParse("fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b"),
-							Name:     "Lucas Szeremeta",
-							Document: "08412535952",
-							Status:   "draft",
-						},
+				receiverServiceMock{ListReceiversMock: func(req dtos.ListReceiversRequest, filter receiver.Filter) (*dtos.ListReceiversPage, error) {
+					if req.Page != 2 {
+						return nil, errors.New("unexpected page")
+					}
+					return &dtos.ListReceiversPage{
+						Receivers:  receivers,
+						Pagination: dtos.Pagination{PageSize: 10, Page: 2, NextCursor: "bmV4dA", PrevCursor: "cHJldg"},
 					}, nil
 				}},
 			},
-			req: "1",
+			req: "?page=2",
 			want: expectedResponse{
 				Code: http.StatusOK,
-				Data: `{"receivers":[{"id":"fbd731d4-d3ac-4305-9d65-72800e821136","name":"Lucas","document":"08412535952","status":"draft"},{"id":"fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b","name":"Lucas Szeremeta","document":"08412535952","status":"draft"}],"status":true}`,
+				Link: `<http://localhost/api/v1/receiver>; rel="first",<http://localhost/api/v1/receiver?page=3>; rel="next",<http://localhost/api/v1/receiver?page=1>; rel="prev"`,
+				Data: `{"pagination":{"page_size":10,"page":2,"next_cursor":"bmV4dA","prev_cursor":"cHJldg"},"receivers":[{"id":"fbd731d4-d3ac-4305-9d65-72800e821136","name":"Lucas","document":"08412535952","status":"draft"},{"id":"fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b","name":"Lucas Szeremeta","document":"08412535952","status":"draft"}],"status":true}`,
 			},
 		}, {
 			name: "Should return a list of users with status 200 ok if no page provided",
 			args: args{
-				receiverServiceMock{ListReceiversMock: func(page int) ([]dtos.ListReceiversResponse, error) {
-					return []dtos.ListReceiversResponse{
-						{
-							Id:       uuid.MustParse("fbd731d4-d3ac-4305-9d65-72800e821136"),
-							Name:     "Lucas",
-							Document: "08412535952",
-							Status:   "draft",
-						},
-						{
-							Id:       uuid.MustParse("fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b"),
-							Name:     "Lucas Szeremeta",
-							Document: "08412535952",
-							Status:   "draft",
-						},
+				receiverServiceMock{ListReceiversMock: func(req dtos.ListReceiversRequest, filter receiver.Filter) (*dtos.ListReceiversPage, error) {
+					return &dtos.ListReceiversPage{
+						Receivers:  receivers,
+						Pagination: dtos.Pagination{PageSize: 10},
+					}, nil
+				}},
+			},
+			want: expectedResponse{
+				Code: http.StatusOK,
+				Link: `<http://localhost/api/v1/receiver>; rel="first"`,
+				Data: `{"pagination":{"page_size":10},"receivers":[{"id":"fbd731d4-d3ac-4305-9d65-72800e821136","name":"Lucas","document":"08412535952","status":"draft"},{"id":"fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b","name":"Lucas Szeremeta","document":"08412535952","status":"draft"}],"status":true}`,
+			},
+		},
+		{
+			name: "Should link the cursors keeping the page size and total",
+			args: args{
+				receiverServiceMock{ListReceiversMock: func(req dtos.ListReceiversRequest, filter receiver.Filter) (*dtos.ListReceiversPage, error) {
+					if req.Cursor != "Y3Vyc29y" || req.PageSize != 2 || !req.Total {
+						return nil, errors.New("unexpected request")
+					}
+					total := 5
+					return &dtos.ListReceiversPage{
+						Receivers:  receivers,
+						Pagination: dtos.Pagination{PageSize: 2, NextCursor: "bmV4dA", PrevCursor: "cHJldg", Total: &total},
+					}, nil
+				}},
+			},
+			req: "?cursor=Y3Vyc29y&page_size=2&total=true",
+			want: expectedResponse{
+				Code: http.StatusOK,
+				Link: `<http://localhost/api/v1/receiver?page_size=2&total=true>; rel="first",<http://localhost/api/v1/receiver?cursor=bmV4dA&page_size=2&total=true>; rel="next",<http://localhost/api/v1/receiver?cursor=cHJldg&page_size=2&total=true>; rel="prev"`,
+				Data: `{"pagination":{"page_size":2,"next_cursor":"bmV4dA","prev_cursor":"cHJldg","total":5},"receivers":[{"id":"fbd731d4-d3ac-4305-9d65-72800e821136","name":"Lucas","document":"08412535952","status":"draft"},{"id":"fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b","name":"Lucas Szeremeta","document":"08412535952","status":"draft"}],"status":true}`,
+			},
+		},
+		{
+			name: "Should filter and sort the receivers keeping the filter on the links",
+			args: args{
+				receiverServiceMock{ListReceiversMock: func(req dtos.ListReceiversRequest, filter receiver.Filter) (*dtos.ListReceiversPage, error) {
+					if len(filter.Statuses) != 1 || filter.Statuses[0] != entity.Draft || filter.DocType != vo.CPF ||
+						filter.Sort != (receiver.Sort{Field: receiver.SortName, Desc: true}) {
+						return nil, errors.New("unexpected filter")
+					}
+					return &dtos.ListReceiversPage{
+						Receivers:  receivers,
+						Pagination: dtos.Pagination{PageSize: 10, NextCursor: "bmV4dA"},
 					}, nil
 				}},
 			},
-			req: "1",
+			req: "?status=draft&doc_type=cpf&sort=-name",
 			want: expectedResponse{
 				Code: http.StatusOK,
-				Data: `{"receivers":[{"id":"fbd731d4-d3ac-4305-9d65-72800e821136","name":"Lucas","document":"08412535952","status":"draft"},{"id":"fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b","name":"Lucas Szeremeta","document":"08412535952","status":"draft"}],"status":true}`,
+				Link: `<http://localhost/api/v1/receiver?doc_type=cpf&sort=-name&status=draft>; rel="first",<http://localhost/api/v1/receiver?cursor=bmV4dA&doc_type=cpf&sort=-name&status=draft>; rel="next"`,
+				Data: `{"pagination":{"page_size":10,"next_cursor":"bmV4dA"},"receivers":[{"id":"fbd731d4-d3ac-4305-9d65-72800e821136","name":"Lucas","document":"08412535952","status":"draft"},{"id":"fdd410e8-1bdd-4c8b-9a4b-e0ffd738e38b","name":"Lucas Szeremeta","document":"08412535952","status":"draft"}],"status":true}`,
+			},
+		},
+		{
+			name: "Should return a bad request for an unknown filter",
+			req:  "?page=1&colour=blue",
+			want: expectedResponse{
+				Code: http.StatusBadRequest,
+				Data: `{"errors":"invalid filter: unknown field \"colour\"","status":false}`,
+			},
+		},
+		{
+			name: "Should return a bad request for an invalid cursor",
+			args: args{receiverServiceMock{Err: receiver.ErrInvalidCursor}},
+			req:  "?cursor=abc",
+			want: expectedResponse{
+				Code: http.StatusBadRequest,
+				Data: `{"errors":"invalid cursor, use one returned by the previous page","status":false}`,
 			},
 		},
 		{
 			name: "Should return a bad request for a invalid page query param",
-			req:  "1a",
+			req:  "?page=1a",
 			want: expectedResponse{
 				Code: http.StatusBadRequest,
 				Data: `{"error":"invalid param for pages, it should be an integer","status":true}`,
@@ -364,11 +715,7 @@ func Test_receiverHandler_List(t *testing.T) {
 			app := fiber.New()
 			app.Get(route, NewReceiverHandler(tt.args.service).List())
 
-			var param string
-			if tt.req != "" {
-				param = fmt.Sprint("?page=", tt.req)
-			}
-			req = httptest.NewRequest("GET", fmt.Sprint(host, route, param), nil)
+			req = httptest.NewRequest("GET", fmt.Sprint(host, route, tt.req), nil)
 			req.Header.Add("Content-Type", "application/json")
 			resp, err := app.Test(req, timeout)
 			require.NoErrorf(t, err, "failed to make a test request")
@@ -376,6 +723,7 @@ func Test_receiverHandler_List(t *testing.T) {
 				resp.Body.Close()
 			}()
 			require.Equalf(t, tt.want.Code, resp.StatusCode, "failed to ping. Expected status [%d] but got [%d]", tt.want.Code, resp.StatusCode)
+			require.Equal(t, tt.want.Link, resp.Header.Get(fiber.HeaderLink))
 			body, err := io.ReadAll(resp.Body)
 
 			require.NoErrorf(t, err, "failed to read response body. Cause  %v", err)
@@ -392,6 +740,7 @@ func Test_receiverHandler_Get(t *testing.T) {
 	type expectedResponse struct {
 		Code int
 		Data interface{}
+		ETag string
 	}
 	const host = "http://localhost"
 	const route = "/api/v1/receiver/:id"
@@ -414,13 +763,15 @@ func Test_receiverHandler_Get(t *testing.T) {
 						Pixkey:   "419.267.660-59",
 						PixType:  "cpf",
 						Status:   "draft",
+						Version:  4,
 					}, nil
 				}},
 			},
 			req: "9260c278-031f-4d2e-976e-b093dd0452fc",
 			want: expectedResponse{
 				http.StatusOK,
-				`{"receivers":{"Id":"9260c278-031f-4d2e-976e-b093dd0452fc","Name":"Ian Mcgregor","Email":"mcgregor@gmail.com","Document":"419.267.660-59","Pixkey":"419.267.660-59","PixType":"cpf","Status":"draft"},"status":true}`,
+				`{"receivers":{"Id":"9260c278-031f-4d2e-976e-b093dd0452fc","Name":"Ian Mcgregor","Email":"mcgregor@gmail.com","Document":"419.267.660-59","Pixkey":"419.267.660-59","PixType":"cpf","BankCode":"","BankBranch":"","BankAccount":"","BankAccountDigit":"","BankAccountType":"","ThirdPartyPixKey":false,"Status":"draft","ValidationError":"","Version":4},"status":true}`,
+				`"4"`,
 			},
 		},
 		{
@@ -434,6 +785,7 @@ func Test_receiverHandler_Get(t *testing.T) {
 			want: expectedResponse{
 				http.StatusNotFound,
 				`{"errors":"receiver not found","status":false}`,
+				"",
 			},
 		},
 	}
@@ -451,6 +803,7 @@ func Test_receiverHandler_Get(t *testing.T) {
 				resp.Body.Close()
 			}()
 			require.Equalf(t, tt.want.Code, resp.StatusCode, "failed to ping. Expected status [%d] but got [%d]", tt.want.Code, resp.StatusCode)
+			require.Equal(t, tt.want.ETag, resp.Header.Get(fiber.HeaderETag))
 			body, err := io.ReadAll(resp.Body)
 
 			require.NoErrorf(t, err, "failed to read response body. Cause  %v", err)
@@ -493,42 +846,73 @@ func Test_receiverHandler_Search(t *testing.T) {
 			},
 		},
 		{
-			name: "Should finds users with success",
-			args: args{receiverServiceMock{SearchReceiversMock: func(req dtos.SearchRequest) ([]dtos.GetReceiverResponse, error) {
-				return []dtos.GetReceiverResponse{
-					{
-						Id:       uuid.MustParse("40b0b875-8c6e-456b-99f9-4aea2bcea693"),
-						Name:     "Lucas Szeremeta",
-						Email:    "lucasszmt@gmail.com",
-						Document: "08412535952",
-						Pixkey:   "08412535952",
-						PixType:  "cpf",
-						Status:   "active",
-					},
-					{
-						Id:       uuid.MustParse("450aa274-3824-4076-a6b5-32585b38f900"),
-						Name:     "Lucas Szeremeta",
-						Email:    "lucasszmt@gmail.com",
-						Document: "08412535952",
-						Pixkey:   "08412535952",
-						PixType:  "cpf",
-						Status:   "draft",
-					},
-				}, nil
+			name: "Should point at the syntax error of a search query",
+			args: args{receiverServiceMock{SearchReceiversMock: func(req dtos.SearchRequest, filter receiver.Filter) ([]dtos.SearchReceiverResponse, error) {
+				if req.Syntax != receiver.SyntaxQuery {
+					return nil, errors.New("unexpected syntax")
+				}
+				_, err := receiver.ParseQuery(req.Query)
+				return nil, err
 			}}},
-			req: `?query=08412535952&limit=2`,
+			req: `?query=status:paid&syntax=query`,
 			want: expectedResponse{
-				http.StatusOK,
-				`{"receivers":[{"Id":"40b0b875-8c6e-456b-99f9-4aea2bcea693","Name":"Lucas Szeremeta","Email":"lucasszmt@gmail.com","Document":"08412535952","Pixkey":"08412535952","PixType":"cpf","Status":"active"},{"Id":"450aa274-3824-4076-a6b5-32585b38f900","Name":"Lucas Szeremeta","Email":"lucasszmt@gmail.com","Document":"08412535952","Pixkey":"08412535952","PixType":"cpf","Status":"draft"}],"status":true}`,
+				http.StatusBadRequest,
+				`{"error":"invalid search query at position 8: unknown status \"paid\"","position":8,"status":false}`,
 			},
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var req *http.Request
-			app := fiber.New()
-			app.Get(route, NewReceiverHandler(tt.args.service).Search())
-
+		{
+			name: "Should fail on a filter value out of the whitelist",
+			req:  `?query=lucas&pix_key_type=bitcoin`,
+			want: expectedResponse{
+				http.StatusBadRequest,
+				`{"error":"invalid filter: pix_key_type: unknown pix key type \"bitcoin\"","status":false}`,
+			},
+		},
+		{
+			name: "Should finds users with success",
+			args: args{receiverServiceMock{SearchReceiversMock: func(req dtos.SearchRequest, filter receiver.Filter) ([]dtos.SearchReceiverResponse, error) {
+				return []dtos.SearchReceiverResponse{
+					{
+						GetReceiverResponse: dtos.GetReceiverResponse{
+							Id:       uuid.MustParse("40b0b875-8c6e-456b-99f9-4aea2bcea693"),
+							Name:     "Lucas Szeremeta",
+							Email:    "lucasszmt@gmail.com",
+							Document: "08412535952",
+							Pixkey:   "08412535952",
+							PixType:  "cpf",
+							Status:   "valid",
+						},
+						Rank:       1,
+						Highlights: map[string]string{"Document": "<mark>08412535952</mark>"},
+					},
+					{
+						GetReceiverResponse: dtos.GetReceiverResponse{
+							Id:       uuid.MustParse("450aa274-3824-4076-a6b5-32585b38f900"),
+							Name:     "Lucas Szeremeta",
+							Email:    "lucasszmt@gmail.com",
+							Document: "08412535952",
+							Pixkey:   "08412535952",
+							PixType:  "cpf",
+							Status:   "draft",
+						},
+						Rank:       0.5,
+						Highlights: map[string]string{"Pixkey": "<mark>08412535952</mark>"},
+					},
+				}, nil
+			}}},
+			req: `?query=08412535952&limit=2`,
+			want: expectedResponse{
+				http.StatusOK,
+				`{"receivers":[{"Id":"40b0b875-8c6e-456b-99f9-4aea2bcea693","Name":"Lucas Szeremeta","Email":"lucasszmt@gmail.com","Document":"08412535952","Pixkey":"08412535952","PixType":"cpf","BankCode":"","BankBranch":"","BankAccount":"","BankAccountDigit":"","BankAccountType":"","ThirdPartyPixKey":false,"Status":"valid","ValidationError":"","Version":0,"Rank":1,"Highlights":{"Document":"\u003cmark\u003e08412535952\u003c/mark\u003e"}},{"Id":"450aa274-3824-4076-a6b5-32585b38f900","Name":"Lucas Szeremeta","Email":"lucasszmt@gmail.com","Document":"08412535952","Pixkey":"08412535952","PixType":"cpf","BankCode":"","BankBranch":"","BankAccount":"","BankAccountDigit":"","BankAccountType":"","ThirdPartyPixKey":false,"Status":"draft","ValidationError":"","Version":0,"Rank":0.5,"Highlights":{"Pixkey":"\u003cmark\u003e08412535952\u003c/mark\u003e"}}],"status":true}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			app := fiber.New()
+			app.Get(route, NewReceiverHandler(tt.args.service).Search())
+
 			req = httptest.NewRequest("GET", fmt.Sprint(host, route, tt.req), nil)
 			req.Header.Add("Content-Type", "application/json")
 			resp, err := app.Test(req, timeout)
@@ -585,7 +969,10 @@ func Test_receiverHandler_Delete(t *testing.T) {
 		},
 		{
 			name: "Should delete an item with sucess",
-			args: args{receiverServiceMock{DeleteReceiverMock: func(req dtos.DeleReceiverRequest) error {
+			args: args{receiverServiceMock{DeleteReceiverMock: func(audit dtos.Audit, req dtos.DeleReceiverRequest) error {
+				if audit.Actor != "operator@transfeera.com" || audit.RequestID != "a9f2d6e0" {
+					return errors.New("audit not provided")
+				}
 				return nil
 			}}},
 			req: map[string]interface{}{
@@ -608,6 +995,98 @@ func Test_receiverHandler_Delete(t *testing.T) {
 
 			req = httptest.NewRequest("DELETE", fmt.Sprint(host, route), bytes.NewReader(json))
 			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add(ActorHeader, "operator@transfeera.com")
+			req.Header.Add(fiber.HeaderXRequestID, "a9f2d6e0")
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equalf(t, tt.want.Code, resp.StatusCode, "failed to ping. Expected status [%d] but got [%d]", tt.want.Code, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+
+			require.NoErrorf(t, err, "failed to read response body. Cause  %v", err)
+
+			require.True(t, string(body) == tt.want.Data, "invalid response. Expected [%s] but got [%s]", tt.want, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_AddPixKey(t *testing.T) {
+	type args struct {
+		service receiver.UseCase
+	}
+	type expectedResponse struct {
+		Code int
+		Data interface{}
+	}
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/:id/pix-keys"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name string
+		args args
+		req  map[string]interface{}
+		want expectedResponse
+	}{
+		{
+			name: "Should add a pix key with success",
+			args: args{receiverServiceMock{}},
+			req: map[string]interface{}{
+				"pix_key_type": "email",
+				"pix_key":      "lucasszmt@gmail.com",
+				"preferred":    true,
+			},
+			want: expectedResponse{
+				Code: http.StatusCreated,
+				Data: `{"data":"pix key with id 00000000-0000-0000-0000-000000000000 created","status":true}`,
+			},
+		},
+		{
+			name: "Should return a bad request for a missing pix key",
+			args: args{receiverServiceMock{}},
+			req: map[string]interface{}{
+				"pix_key_type": "email",
+			},
+			want: expectedResponse{
+				Code: http.StatusBadRequest,
+				Data: `{"errors":"invalid data request: Key: 'AddPixKeyRequest.PixKey' Error:Field validation for 'PixKey' failed on the 'required' tag","status":false}`,
+			},
+		},
+		{
+			name: "Should return a conflict for a key already registered",
+			args: args{receiverServiceMock{Err: receiver.ErrPixKeyAlreadyRegistered}},
+			req: map[string]interface{}{
+				"pix_key_type": "email",
+				"pix_key":      "lucasszmt@gmail.com",
+			},
+			want: expectedResponse{
+				Code: http.StatusConflict,
+				Data: `{"errors":"pix key already registered","status":false}`,
+			},
+		},
+		{
+			name: "Should return not found for an unknown receiver",
+			args: args{receiverServiceMock{Err: receiver.ErrReceiverNotFound}},
+			req: map[string]interface{}{
+				"pix_key_type": "email",
+				"pix_key":      "lucasszmt@gmail.com",
+			},
+			want: expectedResponse{
+				Code: http.StatusNotFound,
+				Data: `{"errors":"receiver not found","status":false}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			app := fiber.New()
+			app.Post(route, NewReceiverHandler(tt.args.service).AddPixKey())
+			jsonBytes, err := json.Marshal(tt.req)
+			require.NoError(t, err)
+			req = httptest.NewRequest("POST", fmt.Sprint(host, "/api/v1/receiver/fbd731d4-d3ac-4305-9d65-72800e821136/pix-keys"), bytes.NewReader(jsonBytes))
+			req.Header.Add("Content-Type", "application/json")
 			resp, err := app.Test(req, timeout)
 			require.NoErrorf(t, err, "failed to make a test request")
 			defer func() {
@@ -622,3 +1101,579 @@ func Test_receiverHandler_Delete(t *testing.T) {
 		})
 	}
 }
+
+func Test_receiverHandler_RemovePixKey(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/:id/pix-keys/:keyId"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name    string
+		service receiver.UseCase
+		want    int
+	}{
+		{"Should remove a pix key with success", receiverServiceMock{}, http.StatusNoContent},
+		{"Should return not found for an unknown key", receiverServiceMock{Err: receiver.ErrPixKeyNotFound}, http.StatusNotFound},
+		{"Should refuse to remove the last payment destination", receiverServiceMock{Err: entity.ErrNoPaymentDestination}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Delete(route, NewReceiverHandler(tt.service).RemovePixKey())
+			req := httptest.NewRequest("DELETE", fmt.Sprint(host, "/api/v1/receiver/fbd731d4-d3ac-4305-9d65-72800e821136/pix-keys/7bc49e64-a623-402c-b9e3-f44b42eda5df"), nil)
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equalf(t, tt.want, resp.StatusCode, "failed to ping. Expected status [%d] but got [%d]", tt.want, resp.StatusCode)
+		})
+	}
+}
+
+func Test_receiverHandler_BRCode(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/:id/brcode"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "Should return the br code with the query params",
+			service: receiverServiceMock{GenerateBRCodeMock: func(id string, req dtos.BRCodeRequest) (string, error) {
+				if req.Amount != "10.50" || req.TxID != "PEDIDO1" || req.City != "BRASILIA" {
+					return "", errors.New("unexpected request")
+				}
+				return "000201", nil
+			}},
+			query:    "?amount=10.50&txid=PEDIDO1&city=BRASILIA",
+			wantCode: http.StatusOK,
+			wantBody: `{"brcode":"000201","status":true}`,
+		},
+		{
+			name:     "Should return not found for an unknown receiver",
+			service:  receiverServiceMock{Err: receiver.ErrReceiverNotFound},
+			wantCode: http.StatusNotFound,
+			wantBody: `{"errors":"receiver not found","status":false}`,
+		},
+		{
+			name:     "Should return unprocessable entity for a receiver without pix key",
+			service:  receiverServiceMock{Err: receiver.ErrReceiverWithoutPixKey},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"errors":"receiver has no pix key","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get(route, NewReceiverHandler(tt.service).BRCode())
+			req := httptest.NewRequest("GET", fmt.Sprint(host, "/api/v1/receiver/fbd731d4-d3ac-4305-9d65-72800e821136/brcode", tt.query), nil)
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_CreateFromBRCode(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/from-brcode"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		req      map[string]interface{}
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Should create a receiver from a br code",
+			service:  receiverServiceMock{},
+			req:      map[string]interface{}{"brcode": "00020126580014br.gov.bcb.pix"},
+			wantCode: http.StatusCreated,
+			wantBody: `{"data":"user with id 00000000-0000-0000-0000-000000000000 created","status":true}`,
+		},
+		{
+			name:     "Should require the br code",
+			service:  receiverServiceMock{},
+			req:      map[string]interface{}{"doc": "08412535952"},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"errors":"invalid data request: Key: 'CreateFromBRCodeRequest.BRCode' Error:Field validation for 'BRCode' failed on the 'required' tag","status":false}`,
+		},
+		{
+			name:     "Should return the parser error for a tampered br code",
+			service:  receiverServiceMock{Err: brcode.ErrInvalidCRC},
+			req:      map[string]interface{}{"brcode": "00020126580014br.gov.bcb.pix"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"errors":"unable to create receiver cause br code crc16 checksum does not match the payload","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post(route, NewReceiverHandler(tt.service).CreateFromBRCode())
+			jsonBytes, err := json.Marshal(tt.req)
+			require.NoError(t, err)
+			req := httptest.NewRequest("POST", fmt.Sprint(host, route), bytes.NewReader(jsonBytes))
+			req.Header.Add("Content-Type", "application/json")
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_Transition(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/:id/status"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		req      map[string]interface{}
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Should move the receiver to the requested status",
+			service:  receiverServiceMock{},
+			req:      map[string]interface{}{"status": "blocked"},
+			wantCode: http.StatusOK,
+			wantBody: `{"data":"user with id fbd731d4-d3ac-4305-9d65-72800e821136 moved to blocked","status":true}`,
+		},
+		{
+			name:     "Should require the status",
+			service:  receiverServiceMock{},
+			req:      map[string]interface{}{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"errors":"invalid data request: Key: 'TransitionStatusRequest.Status' Error:Field validation for 'Status' failed on the 'required' tag","status":false}`,
+		},
+		{
+			name:     "Should return unprocessable entity for an illegal transition",
+			service:  receiverServiceMock{Err: fmt.Errorf("%w: from archived to valid", entity.ErrInvalidStatusTransition)},
+			req:      map[string]interface{}{"status": "valid"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"errors":"receiver status transition not allowed: from archived to valid","status":false}`,
+		},
+		{
+			name:     "Should return conflict for a concurrent transition",
+			service:  receiverServiceMock{Err: receiver.ErrStatusChanged},
+			req:      map[string]interface{}{"status": "valid"},
+			wantCode: http.StatusConflict,
+			wantBody: `{"errors":"receiver status was changed by another request","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Put(route, NewReceiverHandler(tt.service).Transition())
+			jsonBytes, err := json.Marshal(tt.req)
+			require.NoError(t, err)
+			req := httptest.NewRequest("PUT", fmt.Sprint(host, "/api/v1/receiver/fbd731d4-d3ac-4305-9d65-72800e821136/status"), bytes.NewReader(jsonBytes))
+			req.Header.Add("Content-Type", "application/json")
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_Validate(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/:id/validate"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "Should return the validation result",
+			service: receiverServiceMock{ValidateMock: func(id string) (*dtos.ValidationResponse, error) {
+				return &dtos.ValidationResponse{Reason: receiver.ErrDocumentMismatch.Error(), OwnerName: "Ian Mcgregor", ISPB: "18236120"}, nil
+			}},
+			wantCode: http.StatusOK,
+			wantBody: `{"status":true,"validation":{"valid":false,"reason":"pix key owner document does not match the receiver document","owner_name":"Ian Mcgregor","ispb":"18236120"}}`,
+		},
+		{
+			name:     "Should return not found for an unknown receiver",
+			service:  receiverServiceMock{Err: receiver.ErrReceiverNotFound},
+			wantCode: http.StatusNotFound,
+			wantBody: `{"errors":"receiver not found","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post(route, NewReceiverHandler(tt.service).Validate())
+			req := httptest.NewRequest("POST", fmt.Sprint(host, "/api/v1/receiver/fbd731d4-d3ac-4305-9d65-72800e821136/validate"), nil)
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_Restore(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/restore"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		req      map[string]interface{}
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Should restore the receivers",
+			service:  receiverServiceMock{},
+			req:      map[string]interface{}{"ids": []string{"fbd731d4-d3ac-4305-9d65-72800e821136"}},
+			wantCode: http.StatusOK,
+			wantBody: `{"data":"receivers [fbd731d4-d3ac-4305-9d65-72800e821136] restored","status":true}`,
+		},
+		{
+			name:     "Should require the ids",
+			service:  receiverServiceMock{},
+			req:      map[string]interface{}{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"errors":"ids field is required, and it needs to be an array","status":false}`,
+		},
+		{
+			name:     "Should return not found when nothing was restored",
+			service:  receiverServiceMock{Err: receiver.ErrReceiverNotFound},
+			req:      map[string]interface{}{"ids": []string{"fbd731d4-d3ac-4305-9d65-72800e821136"}},
+			wantCode: http.StatusNotFound,
+			wantBody: `{"errors":"receiver not found","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post(route, NewReceiverHandler(tt.service).Restore())
+			jsonBytes, err := json.Marshal(tt.req)
+			require.NoError(t, err)
+			req := httptest.NewRequest("POST", fmt.Sprint(host, route), bytes.NewReader(jsonBytes))
+			req.Header.Add("Content-Type", "application/json")
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_History(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/:id/history"
+	const timeout = int(time.Hour * 1)
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "Should return the receiver history",
+			service: receiverServiceMock{HistoryMock: func(id string) ([]dtos.HistoryEntryResponse, error) {
+				return []dtos.HistoryEntryResponse{{
+					Id:        uuid.MustParse("7bc49e64-a623-402c-b9e3-f44b42eda5df"),
+					Action:    "update",
+					Before:    []byte(`{"email":"chad@rhcp.com"}`),
+					After:     []byte(`{"email":"chadsmith@rhcp.com"}`),
+					Actor:     "operator@transfeera.com",
+					RequestID: "a9f2d6e0",
+					CreatedAt: time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC),
+				}}, nil
+			}},
+			wantCode: http.StatusOK,
+			wantBody: `{"history":[{"id":"7bc49e64-a623-402c-b9e3-f44b42eda5df","action":"update","before":{"email":"chad@rhcp.com"},"after":{"email":"chadsmith@rhcp.com"},"actor":"operator@transfeera.com","request_id":"a9f2d6e0","created_at":"2023-03-10T12:00:00Z"}],"status":true}`,
+		},
+		{
+			name:     "Should return not found for an unknown receiver",
+			service:  receiverServiceMock{Err: receiver.ErrReceiverNotFound},
+			wantCode: http.StatusNotFound,
+			wantBody: `{"errors":"receiver not found","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get(route, NewReceiverHandler(tt.service).History())
+			req := httptest.NewRequest("GET", fmt.Sprint(host, "/api/v1/receiver/fbd731d4-d3ac-4305-9d65-72800e821136/history"), nil)
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer func() {
+				resp.Body.Close()
+			}()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func Test_receiverHandler_Import(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/import"
+	const timeout = int(time.Hour * 1)
+	id := uuid.MustParse("9260c278-031f-4d2e-976e-b093dd0452fc")
+	tests := []struct {
+		name     string
+		service  receiver.UseCase
+		query    string
+		file     string
+		wantCode int
+		wantData string
+	}{
+		{
+			name: "Should return the report of the imported rows",
+			service: receiverServiceMock{ImportMock: func(file io.Reader, req dtos.ImportReceiversRequest) (*dtos.ImportReport, error) {
+				content, err := io.ReadAll(file)
+				if err != nil || string(content) != "name,doc\nFlea,471.550.590-80\n" || !req.DryRun {
+					return nil, errors.New("unexpected import")
+				}
+				return &dtos.ImportReport{DryRun: true, Total: 1, Created: 1, Rows: []dtos.ImportRowResult{
+					{Line: 2, Status: "created", Id: &id},
+				}}, nil
+			}},
+			query:    "?dry_run=true",
+			file:     "name,doc\nFlea,471.550.590-80\n",
+			wantCode: http.StatusOK,
+			wantData: `{"report":{"dry_run":true,"all_or_nothing":false,"total":1,"created":1,"failed":0,"rows":[{"line":2,"status":"created","id":"9260c278-031f-4d2e-976e-b093dd0452fc"}]},"status":true}`,
+		},
+		{
+			name:     "Should return a bad request without a file",
+			service:  receiverServiceMock{},
+			wantCode: http.StatusBadRequest,
+			wantData: `{"errors":"a csv file is required in the file field","status":false}`,
+		},
+		{
+			name:     "Should return a unprocessable entity for an invalid file",
+			service:  receiverServiceMock{Err: fmt.Errorf("%w: missing column \"doc\"", receiver.ErrInvalidImportFile)},
+			file:     "name\nFlea\n",
+			wantCode: http.StatusUnprocessableEntity,
+			wantData: `{"errors":"invalid import file: missing column \"doc\"","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post(route, NewReceiverHandler(tt.service).Import())
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			if len(tt.file) > 0 {
+				part, err := form.CreateFormFile(ImportFileField, "receivers.csv")
+				require.NoError(t, err)
+				_, err = part.Write([]byte(tt.file))
+				require.NoError(t, err)
+			}
+			require.NoError(t, form.Close())
+			req := httptest.NewRequest("POST", fmt.Sprint(host, route, tt.query), body)
+			req.Header.Add("Content-Type", form.FormDataContentType())
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, string(data))
+		})
+	}
+}
+
+// exportRepo only implements the export of the receiver repository, filtering its receivers by status. Err fails
+// the export once every receiver was written
+type exportRepo struct {
+	receiver.Repository
+	receivers []dtos.GetReceiverResponse
+	Err       error
+}
+
+func (e exportRepo) Export(query receiver.SearchQuery, filter receiver.Filter, each func(receiver *dtos.GetReceiverResponse) error) error {
+	for i := range e.receivers {
+		if !exportStatusMatches(filter, e.receivers[i].Status) {
+			continue
+		}
+		if err := each(&e.receivers[i]); err != nil {
+			return err
+		}
+	}
+	return e.Err
+}
+
+func exportStatusMatches(filter receiver.Filter, status string) bool {
+	if len(filter.Statuses) == 0 {
+		return true
+	}
+	for _, s := range filter.Statuses {
+		if s.String() == status {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_receiverHandler_Export(t *testing.T) {
+	const host = "http://localhost"
+	const route = "/api/v1/receiver/export"
+	const timeout = int(time.Hour * 1)
+	exporter := receiverServiceMock{ExportMock: func(req dtos.ExportReceiversRequest, filter receiver.Filter) (*receiver.Export, error) {
+		repo := exportRepo{receivers: []dtos.GetReceiverResponse{
+			{Name: "Flea", Document: "47155059080", Status: "draft"},
+			{Name: "Chad", Document: "11222333000181", Status: "valid"},
+		}}
+		return receiver.NewService(log.MockLogger{}, repo, nil, receiver.Config{}).ExportReceivers(req, filter)
+	}}
+	tests := []struct {
+		name            string
+		service         receiver.UseCase
+		query           string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantDisposition string
+		wantData        string
+	}{
+		{
+			name:            "Should export as csv without an Accept header",
+			service:         exporter,
+			query:           "?columns=name,doc,status",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantDisposition: `attachment; filename="receivers.csv"`,
+			wantData:        "name,doc,status\nFlea,47155059080,draft\nChad,11222333000181,valid\n",
+		},
+		{
+			name:            "Should negotiate the format with the Accept header",
+			service:         exporter,
+			query:           "?columns=name,doc&mask=true&status=draft",
+			accept:          "application/json;q=0.9, application/x-ndjson",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantDisposition: `attachment; filename="receivers.jsonl"`,
+			wantData:        `{"name":"Flea","doc":"***.550.590-**"}` + "\n",
+		},
+		{
+			name:            "Should prefer the format query param over the Accept header",
+			service:         exporter,
+			query:           "?format=jsonl&columns=name&status=draft",
+			accept:          "text/csv",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantDisposition: `attachment; filename="receivers.jsonl"`,
+			wantData:        `{"name":"Flea"}` + "\n",
+		},
+		{
+			name:            "Should export only the receivers matching the filters",
+			service:         exporter,
+			query:           "?columns=name,status&status=valid,blocked",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantDisposition: `attachment; filename="receivers.csv"`,
+			wantData:        "name,status\nChad,valid\n",
+		},
+		{
+			name:            "Should return a bad request for an unknown filter",
+			service:         exporter,
+			query:           "?columns=name&colour=blue",
+			wantCode:        http.StatusBadRequest,
+			wantContentType: fiber.MIMEApplicationJSON,
+			wantData:        `{"errors":"invalid filter: unknown field \"colour\"","status":false}`,
+		},
+		{
+			name:            "Should return not acceptable for an unsupported Accept header",
+			service:         exporter,
+			accept:          "application/pdf",
+			wantCode:        http.StatusNotAcceptable,
+			wantContentType: fiber.MIMEApplicationJSON,
+			wantData:        `{"errors":"receivers can only be exported as text/csv, application/x-ndjson, application/vnd.openxmlformats-officedocument.spreadsheetml.sheet","status":false}`,
+		},
+		{
+			name:            "Should return a unprocessable entity for an unknown column",
+			service:         exporter,
+			query:           "?columns=phone",
+			wantCode:        http.StatusUnprocessableEntity,
+			wantContentType: fiber.MIMEApplicationJSON,
+			wantData:        `{"errors":"invalid export column: unknown column \"phone\"","status":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get(route, NewReceiverHandler(tt.service).Export())
+			req := httptest.NewRequest("GET", fmt.Sprint(host, route, tt.query), nil)
+			if len(tt.accept) > 0 {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			resp, err := app.Test(req, timeout)
+			require.NoErrorf(t, err, "failed to make a test request")
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			require.Equal(t, tt.wantContentType, resp.Header.Get(fiber.HeaderContentType))
+			require.Equal(t, tt.wantDisposition, resp.Header.Get(fiber.HeaderContentDisposition))
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, string(data))
+		})
+	}
+}
+
+func Test_receiverHandler_Export_StreamError(t *testing.T) {
+	const route = "/api/v1/receiver/export"
+	service := receiverServiceMock{ExportMock: func(req dtos.ExportReceiversRequest, filter receiver.Filter) (*receiver.Export, error) {
+		repo := exportRepo{
+			receivers: []dtos.GetReceiverResponse{{Name: "Flea", Document: "47155059080", Status: "draft"}},
+			Err:       errors.New("connection reset by database"),
+		}
+		return receiver.NewService(log.MockLogger{}, repo, nil, receiver.Config{}).ExportReceivers(req, filter)
+	}}
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get(route, NewReceiverHandler(service).Export())
+	// app.Test runs on a fake connection, a real one is needed to see it closed
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+	// the connection may be closed before or after the headers were sent, the response is incomplete either way
+	resp, err := http.Get("http://" + ln.Addr().String() + route)
+	if err == nil {
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+	}
+	require.Error(t, err, "a failed export must not read as a complete response")
+}
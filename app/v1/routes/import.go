@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/lucasszmt/transfeera-challenge/app/v1/handler"
+)
+
+const (
+	importV1Route = "api/v1/imports"
+)
+
+func ImportRoutes(route *fiber.App, handler handler.ImportHandler) {
+	importRoutes := route.Group(importV1Route)
+	importRoutes.Post("/", handler.Submit())
+	importRoutes.Get("/:id", handler.Get())
+	importRoutes.Get("/:id/errors", handler.Errors())
+}
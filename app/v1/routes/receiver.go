@@ -9,12 +9,25 @@ const (
 	receiverV1Route = "api/v1/receiver"
 )
 
-func ReceiverRoutes(route *fiber.App, handler handler.ReceiverHandler) {
+// ReceiverRoutes registers the receiver endpoints, idempotent guards the creation of receivers against retries
+func ReceiverRoutes(route *fiber.App, handler handler.ReceiverHandler, idempotent fiber.Handler) {
 	receiverRoutes := route.Group(receiverV1Route)
-	receiverRoutes.Post("/", handler.Create())
+	receiverRoutes.Post("/", idempotent, handler.Create())
+	receiverRoutes.Post("/from-brcode", handler.CreateFromBRCode())
+	receiverRoutes.Post("/import", handler.Import())
 	receiverRoutes.Patch("/", handler.Update())
 	receiverRoutes.Get("/search", handler.Search())
+	receiverRoutes.Get("/export", handler.Export())
 	receiverRoutes.Get("/", handler.List())
 	receiverRoutes.Get("/:id", handler.Get())
+	receiverRoutes.Get("/:id/history", handler.History())
+	receiverRoutes.Put("/:id/status", handler.Transition())
+	receiverRoutes.Post("/:id/validate", handler.Validate())
 	receiverRoutes.Delete("/", handler.Delete())
+	receiverRoutes.Post("/restore", handler.Restore())
+	receiverRoutes.Get("/:id/pix-keys", handler.ListPixKeys())
+	receiverRoutes.Post("/:id/pix-keys", handler.AddPixKey())
+	receiverRoutes.Delete("/:id/pix-keys/:keyId", handler.RemovePixKey())
+	receiverRoutes.Put("/:id/pix-keys/:keyId/preferred", handler.SetPreferredPixKey())
+	receiverRoutes.Get("/:id/brcode", handler.BRCode())
 }
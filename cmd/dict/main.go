@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"github.com/lucasszmt/transfeera-challenge/infra/dict"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"net/http"
+	"os"
+)
+
+// Runs a local stand-in for the key directory, seeded with the entries of the json array in DICT_SEED_FILE
+func main() {
+	logger := log.PrettyLogger()
+	if err := godotenv.Load(); err != nil {
+		logger.Info("env file not found")
+	}
+
+	directory := dict.NewMemory()
+	if seed := os.Getenv("DICT_SEED_FILE"); len(seed) > 0 {
+		if err := load(directory, seed); err != nil {
+			panic(err)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("key directory listening on port %s", os.Getenv("DICT_PORT")))
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", os.Getenv("DICT_PORT")), dict.NewHandler(directory)); err != nil {
+		panic(err)
+	}
+}
+
+func load(directory *dict.Memory, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries []dict.Entry
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		directory.Register(e.Key, receiver.KeyOwner{Document: e.OwnerDocument, Name: e.OwnerName, ISPB: e.ISPB})
+	}
+	return nil
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"github.com/joho/godotenv"
 	"github.com/lucasszmt/transfeera-challenge/app"
+	"github.com/lucasszmt/transfeera-challenge/domain/idempotency"
 	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
 	"github.com/lucasszmt/transfeera-challenge/infra/db"
+	"github.com/lucasszmt/transfeera-challenge/infra/dict"
 	"github.com/lucasszmt/transfeera-challenge/infra/log"
 	"os"
+	"strconv"
+	"time"
 )
 
 func main() {
@@ -27,9 +32,68 @@ func main() {
 	// Init repositories
 	receiverRepo := db.NewReceiver(dbConn)
 
+	// Init key directory, the empty in memory directory, where every key lookup misses, is only used when asked for
+	var keyDirectory receiver.KeyDirectory
+	dictInMemory, _ := strconv.ParseBool(os.Getenv("DICT_IN_MEMORY"))
+	switch dictURL := os.Getenv("DICT_URL"); {
+	case len(dictURL) > 0:
+		keyDirectory = dict.NewClient(dictURL, 5*time.Second)
+	case dictInMemory:
+		logger.Warn("DICT_IN_MEMORY is set, receivers are validated against an empty key directory")
+		keyDirectory = dict.NewMemory()
+	default:
+		logger.Fatal("DICT_URL is required to validate receivers", errors.New("missing DICT_URL"))
+	}
+
 	// Init services
-	receiverService := receiver.NewService(&logger, receiverRepo)
+	allowThirdPartyPixKeys, _ := strconv.ParseBool(os.Getenv("ALLOW_THIRD_PARTY_PIX_KEYS"))
+	// an empty retention keeps the deleted receivers forever, an invalid one would silently do the same
+	var deletedRetention time.Duration
+	if retention := os.Getenv("DELETED_RECEIVERS_RETENTION"); len(retention) > 0 {
+		if deletedRetention, err = time.ParseDuration(retention); err != nil {
+			logger.Fatal("invalid DELETED_RECEIVERS_RETENTION, use a duration as 720h", err)
+		}
+	}
+	duplicateDocuments, err := receiver.ParseDuplicateDocumentPolicy(os.Getenv("DUPLICATE_DOCUMENT_POLICY"))
+	if err != nil {
+		logger.Fatal("invalid DUPLICATE_DOCUMENT_POLICY", err)
+	}
+	receiverService := receiver.NewService(&logger, receiverRepo, keyDirectory, receiver.Config{
+		AllowThirdPartyPixKeys: allowThirdPartyPixKeys,
+		BRCodeCity:             os.Getenv("BRCODE_CITY"),
+		DeletedRetention:       deletedRetention,
+		DuplicateDocuments:     duplicateDocuments,
+	})
+	go purgeDeletedReceivers(receiverService, time.Hour)
+
+	// Init idempotency keys, responses are replayed for a day unless configured otherwise
+	idempotencyTTL := 24 * time.Hour
+	if ttl := os.Getenv("IDEMPOTENCY_KEY_TTL"); len(ttl) > 0 {
+		if idempotencyTTL, err = time.ParseDuration(ttl); err != nil {
+			logger.Fatal("invalid IDEMPOTENCY_KEY_TTL, use a duration as 24h", err)
+		}
+	}
+	idempotencyGuard := idempotency.NewGuard(&logger, db.NewIdempotencyKeys(dbConn), idempotencyTTL)
+	go purgeIdempotencyKeys(idempotencyGuard, time.Hour)
 
-	server := app.NewServer(receiverService)
+	// Init import jobs, the worker resumes the jobs left running by a previous run
+	importer := receiver.NewImporter(&logger, receiverService, db.NewImportJobs(dbConn))
+	go importer.Run(5 * time.Second)
+
+	server := app.NewServer(receiverService, importer, idempotencyGuard)
 	server.Run()
 }
+
+// purgeDeletedReceivers periodically removes the receivers soft deleted longer than the retention period
+func purgeDeletedReceivers(receiverService *receiver.Service, interval time.Duration) {
+	for range time.Tick(interval) {
+		receiverService.PurgeDeleted()
+	}
+}
+
+// purgeIdempotencyKeys periodically removes the expired idempotency keys
+func purgeIdempotencyKeys(guard *idempotency.Guard, interval time.Duration) {
+	for range time.Tick(interval) {
+		guard.PurgeExpired()
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
@@ -8,8 +9,10 @@ import (
 	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
 	"github.com/lucasszmt/transfeera-challenge/domain/vo"
 	"github.com/lucasszmt/transfeera-challenge/infra/db"
+	"github.com/lucasszmt/transfeera-challenge/infra/dict"
 	"github.com/lucasszmt/transfeera-challenge/infra/log"
 	"os"
+	"strings"
 )
 
 func main() {
@@ -26,13 +29,13 @@ func main() {
 		os.Getenv("DB_PASSWORD"),
 		os.Getenv("DB_NAME")))
 
-	initialMigration(dbConn)
+	initialMigration(dbConn, &logger)
 
 	// Init repositories
 	receiverRepo := db.NewReceiver(dbConn)
 
 	// Init services
-	receiverService := receiver.NewService(&logger, receiverRepo)
+	receiverService := receiver.NewService(&logger, receiverRepo, dict.NewMemory(), receiver.Config{})
 	GenerateData(receiverService)
 }
 
@@ -46,34 +49,155 @@ func GenerateData(receiverService *receiver.Service) {
 		}
 
 		if gofakeit.RandomString([]string{"cpf", "cnpj"}) == "cpf" {
-			item.Doc = gofakeit.Regex(vo.CPFRegexp.String())
+			item.Doc = fakeDoc(vo.CPFRegexp.String())
 		} else {
-			item.Doc = gofakeit.Regex(vo.CNPJRegexp.String())
+			item.Doc = fakeDoc(vo.CNPJRegexp.String())
 		}
 
 		switch string(item.PixKeyType) {
-		case "cpf":
-			item.PixKey = gofakeit.Regex(vo.CPFRegexp.String())
-		case "cnpj":
-			item.PixKey = gofakeit.Regex(vo.CNPJRegexp.String())
+		case "cpf", "cnpj":
+			item.PixKey = item.Doc
+			if doc, err := vo.NewCpfCnpj(item.Doc); err == nil {
+				item.PixKeyType = vo.PixKeyType(doc.GetType())
+			}
 		case "random_key":
 			item.PixKey = gofakeit.Regex(vo.RandomKeyRegexp.String())
 		case "email":
 			item.PixKey = gofakeit.Regex(item.Email)
 		case "phone":
-			item.PixKey = gofakeit.Regex(vo.PhoneRegexp.String())
+			item.PixKey = fakePhone()
 		}
-		receiverService.CreateReceiver(item)
+		receiverService.CreateReceiver(dtos.Audit{Actor: "migration"}, item)
 	}
 }
 
-func initialMigration(db *sqlx.DB) {
+// fakeDoc generates documents matching the pattern until one of them has valid check digits
+func fakeDoc(pattern string) string {
+	for {
+		doc := gofakeit.Regex(pattern)
+		if _, err := vo.NewCpfCnpj(doc); err == nil {
+			return doc
+		}
+	}
+}
+
+// fakePhone generates mobile numbers until one of them has a known DDD
+func fakePhone() string {
+	for {
+		phone := gofakeit.Regex(vo.PhoneRegexp.String())
+		if _, err := vo.NewPixKey(vo.PhoneKey, phone); err == nil {
+			return phone
+		}
+	}
+}
+
+func initialMigration(db *sqlx.DB, logger log.Logger) {
 	tx := db.MustBegin()
 	tx.MustExec(CreateExtension)
 	tx.MustExec(CreateTablePixKeyType)
 	tx.MustExec(InsertPixKeyItems)
 	tx.MustExec(CreateReceiverTable)
+	// the steps on the single pix key of the receiver table only run until it is moved to receiver_pix_key
+	legacyPixKey := hasLegacyPixKey(tx)
+	if legacyPixKey {
+		tx.MustExec(AddThirdPartyPixKeyColumn)
+	}
+	tx.MustExec(AddBankAccountColumns)
+	if legacyPixKey {
+		tx.MustExec(NormalizePhonePixKeys)
+	}
+	tx.MustExec(LowercaseEmailDomains)
+	if legacyPixKey {
+		tx.MustExec(LowercaseEmailPixKeyDomains)
+	}
+	tx.MustExec(AddValidationColumns)
+	tx.MustExec(AddSoftDeleteColumns)
+	tx.MustExec(CreateDeletedAtIndex)
+	tx.MustExec(CreateDocumentIndex)
+	tx.MustExec(AddVersionColumn)
+	tx.MustExec(AddCreatedAtColumn)
+	tx.MustExec(SetCreatedAtUTCDefault)
+	tx.MustExec(CreateCreatedAtIndex)
+	tx.MustExec(CreateReceiverHistoryTable)
+	tx.MustExec(CreateReceiverHistoryIndex)
+	tx.MustExec(CreateIdempotencyKeyTable)
+	tx.MustExec(CreateIdempotencyKeyExpiresIndex)
+	tx.MustExec(CreateImportJobTable)
+	tx.MustExec(CreateImportJobStatusIndex)
+	tx.MustExec(CreateImportJobErrorTable)
+	tx.MustExec(CreateReceiverPixKeyTable)
+	tx.MustExec(CreatePreferredPixKeyIndex)
+	if legacyPixKey {
+		mustNotShareLegacyPixKeys(tx)
+		tx.MustExec(MoveLegacyPixKeys)
+		tx.MustExec(DropLegacyPixKeyColumns)
+	}
+	punycodeEmailDomains(tx, logger, QueryNonASCIIEmails, UpdateEmail, "email")
+	punycodeEmailDomains(tx, logger, QueryNonASCIIEmailPixKeys, UpdateEmailPixKey, "email pix key")
+	tx.MustExec(CreateUnaccentExtension)
+	tx.MustExec(CreateTrigramExtension)
+	tx.MustExec(CreateUnaccentFunction)
+	tx.MustExec(AddSearchVectorColumn)
+	tx.MustExec(CreateSearchVectorIndex)
+	tx.MustExec(CreateNameTrigramIndex)
+	tx.MustExec(CreateEmailTrigramIndex)
+	tx.MustExec(CreateDocumentTrigramIndex)
+	tx.MustExec(CreatePixKeyTrigramIndex)
 	if err := tx.Commit(); err != nil {
 		panic(err)
 	}
 }
+
+func hasLegacyPixKey(tx *sqlx.Tx) bool {
+	var exists bool
+	if err := tx.Get(&exists, QueryLegacyPixKeyColumn); err != nil {
+		panic(err)
+	}
+	return exists
+}
+
+// mustNotShareLegacyPixKeys stops the migration when receivers share a pix key, as only one of them could keep it.
+// Each of them must be given its own key, or have it removed, before the keys are moved
+func mustNotShareLegacyPixKeys(tx *sqlx.Tx) {
+	var shared []struct {
+		PixKey    string `db:"pix_key"`
+		Receivers string `db:"receivers"`
+	}
+	if err := tx.Select(&shared, QuerySharedLegacyPixKeys); err != nil {
+		panic(err)
+	}
+	if len(shared) == 0 {
+		return
+	}
+	msg := strings.Builder{}
+	msg.WriteString("pix keys shared by more than one receiver, give each receiver its own key before migrating:")
+	for _, key := range shared {
+		msg.WriteString(fmt.Sprintf("\n\t%s: %s", key.PixKey, key.Receivers))
+	}
+	panic(msg.String())
+}
+
+// punycodeEmailDomains converts the unicode domains of the stored emails to punycode, as vo.NewEmail does with
+// the new ones. The emails it can't convert, or whose converted form is already taken, are kept and reported
+func punycodeEmailDomains(tx *sqlx.Tx, logger log.Logger, query, update, kind string) {
+	var rows []struct {
+		Id    string `db:"id"`
+		Value string `db:"value"`
+	}
+	if err := tx.Select(&rows, query); err != nil {
+		panic(err)
+	}
+	for _, row := range rows {
+		email, err := vo.NewEmail(row.Value)
+		if err != nil {
+			logger.Error(fmt.Sprintf("%s %q of %s can't be converted to punycode, fix it by hand", kind, row.Value, row.Id), err)
+			continue
+		}
+		if email.GetEmail() == row.Value {
+			continue
+		}
+		if affected, _ := tx.MustExec(update, email.GetEmail(), row.Id).RowsAffected(); affected == 0 {
+			logger.Warn(fmt.Sprintf("%s %q of %s is already registered as %q, fix it by hand", kind, row.Value, row.Id, email.GetEmail()))
+		}
+	}
+}
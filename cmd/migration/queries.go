@@ -12,7 +12,8 @@ const (
 		   ('cnpj'),
 		   ('email'),
 		   ('phone'),
-		   ('random_key')`
+		   ('random_key')
+	ON CONFLICT (name) DO NOTHING`
 	CreateReceiverTable = `CREATE TABLE IF NOT EXISTS receiver
 	(
 		id         uuid PRIMARY KEY NOT NULL,
@@ -23,4 +24,181 @@ const (
 		pixKeyType uuid references pix_key_type (id),
 		status     int
 	)`
+	AddThirdPartyPixKeyColumn = `ALTER TABLE receiver
+		ADD COLUMN IF NOT EXISTS third_party_pix_key boolean NOT NULL DEFAULT false`
+	AddBankAccountColumns = `ALTER TABLE receiver
+		ADD COLUMN IF NOT EXISTS bank_code          varchar(8),
+		ADD COLUMN IF NOT EXISTS bank_branch        varchar(4),
+		ADD COLUMN IF NOT EXISTS bank_account       varchar(20),
+		ADD COLUMN IF NOT EXISTS bank_account_digit varchar(1),
+		ADD COLUMN IF NOT EXISTS bank_account_type  varchar(10)`
+	NormalizePhonePixKeys = `UPDATE receiver r
+		SET pixKey = '+55' || right(regexp_replace(r.pixKey, '\D', '', 'g'), 11)
+		FROM pix_key_type pkt
+		WHERE r.pixKeyType = pkt.id
+		  AND pkt.name = 'phone'
+		  AND r.pixKey NOT LIKE '+55%'`
+	LowercaseEmailDomains = `UPDATE receiver
+		SET email = left(email, length(email) - strpos(reverse(email), '@') + 1) ||
+		            lower(right(email, strpos(reverse(email), '@') - 1))
+		WHERE strpos(email, '@') > 0`
+	LowercaseEmailPixKeyDomains = `UPDATE receiver r
+		SET pixKey = left(r.pixKey, length(r.pixKey) - strpos(reverse(r.pixKey), '@') + 1) ||
+		             lower(right(r.pixKey, strpos(reverse(r.pixKey), '@') - 1))
+		FROM pix_key_type pkt
+		WHERE r.pixKeyType = pkt.id
+		  AND pkt.name = 'email'
+		  AND strpos(r.pixKey, '@') > 0`
+	AddValidationColumns = `ALTER TABLE receiver
+		ADD COLUMN IF NOT EXISTS validation_error text,
+		ADD COLUMN IF NOT EXISTS validated_at     timestamp`
+	AddSoftDeleteColumns = `ALTER TABLE receiver
+		ADD COLUMN IF NOT EXISTS deleted_at timestamp,
+		ADD COLUMN IF NOT EXISTS deleted_by varchar(255)`
+	CreateDeletedAtIndex = `CREATE INDEX IF NOT EXISTS receiver_deleted_at_idx
+		ON receiver (deleted_at) WHERE deleted_at IS NOT NULL`
+	CreateDocumentIndex = `CREATE INDEX IF NOT EXISTS receiver_document_idx
+		ON receiver (document) WHERE deleted_at IS NULL`
+	// AddVersionColumn holds the version checked by updates, it is incremented on every change of the receiver
+	AddVersionColumn = `ALTER TABLE receiver ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1`
+	// AddCreatedAtColumn orders the receiver pages, the receivers created before it share the same creation time
+	// and are ordered by id among themselves
+	AddCreatedAtColumn = `ALTER TABLE receiver ADD COLUMN IF NOT EXISTS created_at timestamp NOT NULL DEFAULT now()`
+	// SetCreatedAtUTCDefault stores the creation time in UTC whatever the time zone of the database, as the filters
+	// compare it to UTC times
+	SetCreatedAtUTCDefault = `ALTER TABLE receiver ALTER COLUMN created_at SET DEFAULT (now() AT TIME ZONE 'utc')`
+	CreateCreatedAtIndex   = `CREATE INDEX IF NOT EXISTS receiver_created_at_idx
+		ON receiver (created_at, id) WHERE deleted_at IS NULL`
+	// CreateReceiverHistoryTable keeps no foreign key to receiver, so the history outlives purged receivers
+	CreateReceiverHistoryTable = `CREATE TABLE IF NOT EXISTS receiver_history
+	(
+		id          uuid PRIMARY KEY NOT NULL,
+		seq         bigserial,
+		receiver_id uuid        NOT NULL,
+		action      varchar(20) NOT NULL,
+		before      jsonb,
+		after       jsonb,
+		actor       varchar(255),
+		request_id  varchar(100),
+		created_at  timestamp   NOT NULL DEFAULT now()
+	)`
+	CreateReceiverHistoryIndex = `CREATE INDEX IF NOT EXISTS receiver_history_receiver_idx
+		ON receiver_history (receiver_id, created_at)`
+	CreateIdempotencyKeyTable = `CREATE TABLE IF NOT EXISTS idempotency_key
+	(
+		key          varchar(255) PRIMARY KEY NOT NULL,
+		fingerprint  char(64)     NOT NULL,
+		status_code  int,
+		content_type varchar(100),
+		body         bytea,
+		created_at   timestamp    NOT NULL,
+		expires_at   timestamp    NOT NULL
+	)`
+	CreateIdempotencyKeyExpiresIndex = `CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx
+		ON idempotency_key (expires_at)`
+	CreateImportJobTable = `CREATE TABLE IF NOT EXISTS import_job
+	(
+		id             uuid PRIMARY KEY NOT NULL,
+		status         varchar(20)  NOT NULL DEFAULT 'queued',
+		dry_run        boolean      NOT NULL DEFAULT false,
+		all_or_nothing boolean      NOT NULL DEFAULT false,
+		file           bytea        NOT NULL,
+		actor          varchar(255),
+		request_id     varchar(100),
+		claim          uuid,
+		line           int          NOT NULL DEFAULT 0,
+		total_rows     int          NOT NULL DEFAULT 0,
+		processed_rows int          NOT NULL DEFAULT 0,
+		created_rows   int          NOT NULL DEFAULT 0,
+		failed_rows    int          NOT NULL DEFAULT 0,
+		error          text,
+		created_at     timestamp    NOT NULL DEFAULT now(),
+		started_at     timestamp,
+		heartbeat_at   timestamp,
+		finished_at    timestamp
+	)`
+	CreateImportJobStatusIndex = `CREATE INDEX IF NOT EXISTS import_job_status_idx
+		ON import_job (status, created_at) WHERE status IN ('queued', 'running')`
+	CreateImportJobErrorTable = `CREATE TABLE IF NOT EXISTS import_job_error
+	(
+		job_id uuid NOT NULL references import_job (id) ON DELETE CASCADE,
+		line   int  NOT NULL,
+		error  text NOT NULL,
+		PRIMARY KEY (job_id, line)
+	)`
+	CreateReceiverPixKeyTable = `CREATE TABLE IF NOT EXISTS receiver_pix_key
+	(
+		id           uuid PRIMARY KEY NOT NULL,
+		receiver_id  uuid         NOT NULL references receiver (id) ON DELETE CASCADE,
+		pix_key      varchar(140) NOT NULL UNIQUE,
+		pix_key_type uuid         NOT NULL references pix_key_type (id),
+		preferred    boolean      NOT NULL DEFAULT false,
+		third_party  boolean      NOT NULL DEFAULT false,
+		created_at   timestamp    NOT NULL DEFAULT now()
+	)`
+	CreatePreferredPixKeyIndex = `CREATE UNIQUE INDEX IF NOT EXISTS receiver_pix_key_preferred_idx
+		ON receiver_pix_key (receiver_id) WHERE preferred`
+	// QueryLegacyPixKeyColumn tells whether the receiver table still holds its single pix key, which is moved to
+	// receiver_pix_key and dropped once
+	QueryLegacyPixKeyColumn = `SELECT EXISTS(SELECT 1
+		FROM information_schema.columns
+		WHERE table_name = 'receiver'
+		  AND column_name = 'pixkey')`
+	// QuerySharedLegacyPixKeys lists the keys held by more than one receiver, they can't be moved as keys are unique
+	// in receiver_pix_key
+	QuerySharedLegacyPixKeys = `SELECT r.pixKey AS pix_key, string_agg(r.id::text, ', ' ORDER BY r.id) AS receivers
+		FROM receiver r
+		WHERE r.pixKey IS NOT NULL
+		  AND r.pixKeyType IS NOT NULL
+		GROUP BY r.pixKey
+		HAVING count(*) > 1
+		ORDER BY r.pixKey`
+	MoveLegacyPixKeys = `INSERT INTO receiver_pix_key (id, receiver_id, pix_key, pix_key_type, preferred, third_party)
+		SELECT uuid_generate_v4(), r.id, r.pixKey, r.pixKeyType, true, r.third_party_pix_key
+		FROM receiver r
+		WHERE r.pixKey IS NOT NULL
+		  AND r.pixKeyType IS NOT NULL`
+	DropLegacyPixKeyColumns = `ALTER TABLE receiver
+		DROP COLUMN pixKey,
+		DROP COLUMN pixKeyType,
+		DROP COLUMN third_party_pix_key`
+	// QueryNonASCIIEmails and QueryNonASCIIEmailPixKeys find the emails that may still hold a unicode domain, the
+	// ones stored before domains were converted to punycode
+	QueryNonASCIIEmails = `SELECT id, email AS value
+		FROM receiver
+		WHERE octet_length(email) <> char_length(email)`
+	QueryNonASCIIEmailPixKeys = `SELECT rpk.id, rpk.pix_key AS value
+		FROM receiver_pix_key rpk
+		         JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+		WHERE pkt.name = 'email'
+		  AND octet_length(rpk.pix_key) <> char_length(rpk.pix_key)`
+	UpdateEmail       = `UPDATE receiver SET email = $1 WHERE id = $2`
+	UpdateEmailPixKey = `UPDATE receiver_pix_key
+		SET pix_key = $1
+		WHERE id = $2
+		  AND NOT EXISTS(SELECT 1 FROM receiver_pix_key WHERE pix_key = $1)`
+	CreateUnaccentExtension = `CREATE EXTENSION IF NOT EXISTS unaccent`
+	CreateTrigramExtension  = `CREATE EXTENSION IF NOT EXISTS pg_trgm`
+	// CreateUnaccentFunction wraps unaccent with its dictionary fixed, since unaccent itself isn't immutable and
+	// couldn't be used by the indexes and the generated search column
+	CreateUnaccentFunction = `CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT AS
+	$func$
+		SELECT public.unaccent('public.unaccent', $1)
+	$func$`
+	// AddSearchVectorColumn keeps the words of the name and the email lowercased and without accents, the simple
+	// configuration is used since names don't have stems to be reduced to
+	AddSearchVectorColumn = `ALTER TABLE receiver ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('simple', f_unaccent(lower(coalesce(name, ''))) || ' ' ||
+		                                           f_unaccent(lower(coalesce(email, ''))))) STORED`
+	CreateSearchVectorIndex = `CREATE INDEX IF NOT EXISTS receiver_search_vector_idx
+		ON receiver USING gin (search_vector)`
+	CreateNameTrigramIndex = `CREATE INDEX IF NOT EXISTS receiver_name_trgm_idx
+		ON receiver USING gin (f_unaccent(lower(name)) gin_trgm_ops)`
+	CreateEmailTrigramIndex = `CREATE INDEX IF NOT EXISTS receiver_email_trgm_idx
+		ON receiver USING gin (lower(email) gin_trgm_ops)`
+	CreateDocumentTrigramIndex = `CREATE INDEX IF NOT EXISTS receiver_document_trgm_idx
+		ON receiver USING gin (document gin_trgm_ops)`
+	CreatePixKeyTrigramIndex = `CREATE INDEX IF NOT EXISTS receiver_pix_key_trgm_idx
+		ON receiver_pix_key USING gin (lower(pix_key) gin_trgm_ops)`
 )
@@ -0,0 +1,183 @@
+package brcode
+
+import (
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// EMV ids of the fields used by the BACEN static BR Code
+const (
+	idPayloadFormat      = "00"
+	idMerchantAccount    = "26"
+	idMerchantCategory   = "52"
+	idCurrency           = "53"
+	idAmount             = "54"
+	idCountryCode        = "58"
+	idMerchantName       = "59"
+	idMerchantCity       = "60"
+	idAdditionalData     = "62"
+	idCRC                = "63"
+	idMerchantAccountGUI = "00"
+	idMerchantAccountKey = "01"
+	idAdditionalTxID     = "05"
+)
+
+const (
+	PixGUI          = "br.gov.bcb.pix"
+	payloadFormat   = "01"
+	noCategory      = "0000"
+	brlCurrency     = "986"
+	countryCode     = "BR"
+	noTxID          = "***"
+	MaxNameLength   = 25
+	MaxCityLength   = 15
+	MaxTxIDLength   = 25
+	MaxAmountLength = 13
+)
+
+var (
+	AmountRegexp = regexp.MustCompile(`^[0-9]+(?:\.[0-9]{1,2})?$`)
+	TxIDRegexp   = regexp.MustCompile(`^[A-Za-z0-9]{1,25}$`)
+)
+
+// StaticPayload holds the data encoded in a static BR Code, Amount and TxID are optional
+type StaticPayload struct {
+	PixKey       string
+	MerchantName string
+	MerchantCity string
+	Amount       string
+	TxID         string
+}
+
+// Encode builds the "copia e cola" string of a static BR Code, following the EMV TLV layout from the
+// BACEN BR Code manual, and appends its CRC16 checksum. Name and city are stripped of accents and
+// truncated to the lengths allowed by the manual
+func Encode(p StaticPayload) (string, error) {
+	if len(p.PixKey) == 0 {
+		return "", ErrMissingPixKey
+	}
+	name := sanitize(p.MerchantName, MaxNameLength)
+	if len(name) == 0 {
+		return "", ErrMissingMerchantName
+	}
+	city := sanitize(p.MerchantCity, MaxCityLength)
+	if len(city) == 0 {
+		return "", ErrMissingMerchantCity
+	}
+	txID := noTxID
+	if len(p.TxID) > 0 {
+		if !TxIDRegexp.MatchString(p.TxID) {
+			return "", ErrInvalidTxID
+		}
+		txID = p.TxID
+	}
+
+	account, err := field(idMerchantAccountGUI, PixGUI)
+	if err != nil {
+		return "", err
+	}
+	key, err := field(idMerchantAccountKey, p.PixKey)
+	if err != nil {
+		return "", err
+	}
+	additional, err := field(idAdditionalTxID, txID)
+	if err != nil {
+		return "", err
+	}
+
+	fields := [][2]string{
+		{idPayloadFormat, payloadFormat},
+		{idMerchantAccount, account + key},
+		{idMerchantCategory, noCategory},
+		{idCurrency, brlCurrency},
+	}
+	if len(p.Amount) > 0 {
+		amount, err := FormatAmount(p.Amount)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, [2]string{idAmount, amount})
+	}
+	fields = append(fields,
+		[2]string{idCountryCode, countryCode},
+		[2]string{idMerchantName, name},
+		[2]string{idMerchantCity, city},
+		[2]string{idAdditionalData, additional},
+	)
+
+	var sb strings.Builder
+	for _, f := range fields {
+		encoded, err := field(f[0], f[1])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(encoded)
+	}
+	sb.WriteString(idCRC + "04")
+	return sb.String() + fmt.Sprintf("%04X", CRC16(sb.String())), nil
+}
+
+// FormatAmount validates a decimal amount and writes it with two decimal places, as the BR Code expects
+func FormatAmount(amount string) (string, error) {
+	amount = strings.TrimSpace(amount)
+	if !AmountRegexp.MatchString(amount) {
+		return "", ErrInvalidAmount
+	}
+	units, cents, _ := strings.Cut(amount, ".")
+	units = strings.TrimLeft(units, "0")
+	if len(units) == 0 {
+		units = "0"
+	}
+	cents = (cents + "00")[:2]
+	if units == "0" && cents == "00" {
+		return "", ErrInvalidAmount
+	}
+	formatted := units + "." + cents
+	if len(formatted) > MaxAmountLength {
+		return "", ErrInvalidAmount
+	}
+	return formatted, nil
+}
+
+// CRC16 calculates the CRC16-CCITT (polynomial 0x1021, initial value 0xFFFF) checksum of a payload
+func CRC16(payload string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(payload); i++ {
+		crc ^= uint16(payload[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// field writes a single TLV entry, lengths are two decimal digits so values are limited to 99 characters
+func field(id, value string) (string, error) {
+	if len(value) > 99 {
+		return "", fmt.Errorf("%w: field %s", ErrFieldTooLong, id)
+	}
+	return fmt.Sprintf("%s%02d%s", id, len(value), value), nil
+}
+
+// sanitize removes the accents and any non ASCII character of a name, collapses its whitespace and
+// truncates it to size
+func sanitize(value string, size int) string {
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(value) {
+		if r < unicode.MaxASCII && unicode.IsPrint(r) {
+			sb.WriteRune(r)
+		}
+	}
+	sanitized := strings.Join(strings.Fields(sb.String()), " ")
+	if len(sanitized) > size {
+		sanitized = strings.TrimSpace(sanitized[:size])
+	}
+	return sanitized
+}
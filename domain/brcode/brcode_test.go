@@ -0,0 +1,117 @@
+package brcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    uint16
+	}{
+		{"check value", "123456789", 0x29B1},
+		{"bacen manual example", "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***6304", 0x1D3D},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CRC16(tt.payload); got != tt.want {
+				t.Errorf("CRC16() = %04X, want %04X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload StaticPayload
+		want    string
+		wantErr error
+	}{
+		{
+			name: "bacen manual example",
+			payload: StaticPayload{
+				PixKey:       "123e4567-e12b-12d1-a456-426655440000",
+				MerchantName: "Fulano de Tal",
+				MerchantCity: "BRASILIA",
+			},
+			want: "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D",
+		},
+		{
+			name: "with amount and txid, accents removed",
+			payload: StaticPayload{
+				PixKey:       "+5511999999999",
+				MerchantName: "João  da Conceição",
+				MerchantCity: "São Paulo",
+				Amount:       "10.5",
+				TxID:         "PEDIDO123",
+			},
+			want: "00020126360014br.gov.bcb.pix0114+55119999999995204000053039865405" +
+				"10.505802BR5917Joao da Conceicao6009Sao Paulo62130509PEDIDO12363048E6B",
+		},
+		{
+			name:    "missing key",
+			payload: StaticPayload{MerchantName: "Fulano", MerchantCity: "BRASILIA"},
+			wantErr: ErrMissingPixKey,
+		},
+		{
+			name:    "missing city",
+			payload: StaticPayload{PixKey: "08412535952", MerchantName: "Fulano"},
+			wantErr: ErrMissingMerchantCity,
+		},
+		{
+			name:    "invalid txid",
+			payload: StaticPayload{PixKey: "08412535952", MerchantName: "Fulano", MerchantCity: "BRASILIA", TxID: "pedido-1"},
+			wantErr: ErrInvalidTxID,
+		},
+		{
+			name:    "invalid amount",
+			payload: StaticPayload{PixKey: "08412535952", MerchantName: "Fulano", MerchantCity: "BRASILIA", Amount: "10,50"},
+			wantErr: ErrInvalidAmount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Encode(tt.payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Encode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount  string
+		want    string
+		wantErr bool
+	}{
+		{"10", "10.00", false},
+		{"10.5", "10.50", false},
+		{"0010.25", "10.25", false},
+		{"0.01", "0.01", false},
+		{"0", "", true},
+		{"-1", "", true},
+		{"1.234", "", true},
+		{"12345678901.00", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			got, err := FormatAmount(tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FormatAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FormatAmount() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
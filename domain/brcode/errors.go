@@ -0,0 +1,21 @@
+package brcode
+
+import "errors"
+
+var (
+	ErrMissingPixKey       = errors.New("a pix key is required in a br code")
+	ErrMissingMerchantName = errors.New("a merchant name is required in a br code")
+	ErrMissingMerchantCity = errors.New("a merchant city is required in a br code")
+	ErrInvalidAmount       = errors.New("invalid amount provided, it should be a positive value with up to 2 decimals")
+	ErrInvalidTxID         = errors.New("invalid txid provided, it should have up to 25 letters or digits")
+	ErrFieldTooLong        = errors.New("br code field longer than 99 characters")
+	ErrMalformedPayload    = errors.New("malformed br code payload")
+	ErrDuplicatedField     = errors.New("duplicated br code field")
+	ErrMissingCRC          = errors.New("br code payload must end with the crc16 field")
+	ErrInvalidCRC          = errors.New("br code crc16 checksum does not match the payload")
+	ErrInvalidFormat       = errors.New("unsupported br code payload format indicator")
+	ErrNotPixBRCode        = errors.New("br code has no pix merchant account information")
+	ErrDynamicBRCode       = errors.New("dynamic br codes are not supported, the payload has no pix key")
+	ErrInvalidCurrency     = errors.New("br code currency must be 986 (BRL)")
+	ErrInvalidCountryCode  = errors.New("br code country code must be BR")
+)
@@ -0,0 +1,125 @@
+package brcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	crcFieldLength = 8
+	// pix accounts may be written in any of the merchant account information templates, 26 to 51
+	firstMerchantAccountID = 26
+	lastMerchantAccountID  = 51
+	idMerchantAccountURL   = "25"
+)
+
+// tlv is a decoded EMV field, pos is the offset of its id in the payload, used in the error messages
+type tlv struct {
+	value string
+	pos   int
+}
+
+// Parse decodes a static BR Code "copia e cola" string. The CRC16 is verified before anything else, so
+// tampered payloads are refused even when they are still well formed
+func Parse(payload string) (*StaticPayload, error) {
+	payload = strings.TrimSpace(payload)
+	if len(payload) < crcFieldLength || payload[len(payload)-crcFieldLength:len(payload)-4] != idCRC+"04" {
+		return nil, ErrMissingCRC
+	}
+	checksum, err := strconv.ParseUint(payload[len(payload)-4:], 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: position %d", ErrInvalidCRC, len(payload)-4)
+	}
+	if uint16(checksum) != CRC16(payload[:len(payload)-4]) {
+		return nil, ErrInvalidCRC
+	}
+	fields, err := decode(payload[:len(payload)-crcFieldLength], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := fields[idPayloadFormat]; !ok || f.value != payloadFormat {
+		return nil, ErrInvalidFormat
+	}
+	p := &StaticPayload{}
+	if p.PixKey, err = pixKey(fields); err != nil {
+		return nil, err
+	}
+	if f, ok := fields[idCurrency]; ok && f.value != brlCurrency {
+		return nil, fmt.Errorf("%w: position %d", ErrInvalidCurrency, f.pos)
+	}
+	if f, ok := fields[idCountryCode]; ok && f.value != countryCode {
+		return nil, fmt.Errorf("%w: position %d", ErrInvalidCountryCode, f.pos)
+	}
+	if f, ok := fields[idAmount]; ok {
+		if p.Amount, err = FormatAmount(f.value); err != nil {
+			return nil, fmt.Errorf("%w: position %d", err, f.pos)
+		}
+	}
+	if p.MerchantName = strings.TrimSpace(fields[idMerchantName].value); len(p.MerchantName) == 0 {
+		return nil, ErrMissingMerchantName
+	}
+	if p.MerchantCity = strings.TrimSpace(fields[idMerchantCity].value); len(p.MerchantCity) == 0 {
+		return nil, ErrMissingMerchantCity
+	}
+	if f, ok := fields[idAdditionalData]; ok {
+		additional, err := decode(f.value, f.pos+4)
+		if err != nil {
+			return nil, err
+		}
+		if txID, ok := additional[idAdditionalTxID]; ok && txID.value != noTxID {
+			p.TxID = txID.value
+		}
+	}
+	return p, nil
+}
+
+// pixKey looks for the merchant account template holding the pix GUI and returns its key
+func pixKey(fields map[string]tlv) (string, error) {
+	for id := firstMerchantAccountID; id <= lastMerchantAccountID; id++ {
+		f, ok := fields[strconv.Itoa(id)]
+		if !ok {
+			continue
+		}
+		account, err := decode(f.value, f.pos+4)
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(account[idMerchantAccountGUI].value, PixGUI) {
+			continue
+		}
+		if key, ok := account[idMerchantAccountKey]; ok && len(key.value) > 0 {
+			return key.value, nil
+		}
+		if _, ok := account[idMerchantAccountURL]; ok {
+			return "", ErrDynamicBRCode
+		}
+		return "", fmt.Errorf("%w: position %d", ErrMissingPixKey, f.pos)
+	}
+	return "", ErrNotPixBRCode
+}
+
+// decode splits data into its TLV fields, offset is the position of data inside the whole payload
+func decode(data string, offset int) (map[string]tlv, error) {
+	fields := make(map[string]tlv)
+	for pos := 0; pos < len(data); {
+		if len(data)-pos < 4 {
+			return nil, fmt.Errorf("%w: truncated field at position %d", ErrMalformedPayload, offset+pos)
+		}
+		id := data[pos : pos+2]
+		size, err := strconv.Atoi(data[pos+2 : pos+4])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("%w: invalid length of field %s at position %d", ErrMalformedPayload, id, offset+pos)
+		}
+		if pos+4+size > len(data) {
+			return nil, fmt.Errorf("%w: field %s at position %d overflows the payload", ErrMalformedPayload, id, offset+pos)
+		}
+		if _, ok := fields[id]; ok {
+			return nil, fmt.Errorf("%w: field %s at position %d", ErrDuplicatedField, id, offset+pos)
+		}
+		fields[id] = tlv{value: data[pos+4 : pos+4+size], pos: offset + pos}
+		pos += 4 + size
+	}
+	return fields, nil
+}
@@ -0,0 +1,142 @@
+package brcode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func withCRC(payload string) string {
+	payload += idCRC + "04"
+	return payload + fmt.Sprintf("%04X", CRC16(payload))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    *StaticPayload
+		wantErr error
+	}{
+		{
+			name:    "bacen manual example",
+			payload: "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D",
+			want: &StaticPayload{
+				PixKey:       "123e4567-e12b-12d1-a456-426655440000",
+				MerchantName: "Fulano de Tal",
+				MerchantCity: "BRASILIA",
+			},
+		},
+		{
+			name:    "amount, txid and lowercase checksum",
+			payload: "00020126360014br.gov.bcb.pix0114+5511999999999520400005303986540510.505802BR5917Joao da Conceicao6009Sao Paulo62130509PEDIDO12363048e6b",
+			want: &StaticPayload{
+				PixKey:       "+5511999999999",
+				MerchantName: "Joao da Conceicao",
+				MerchantCity: "Sao Paulo",
+				Amount:       "10.50",
+				TxID:         "PEDIDO123",
+			},
+		},
+		{
+			name:    "pix account in another merchant template and initiation method",
+			payload: withCRC("000201010211" + "26170013com.example.x" + "27360014BR.GOV.BCB.PIX0114+5511999999999" + "5802BR5906Fulano6008BRASILIA"),
+			want: &StaticPayload{
+				PixKey:       "+5511999999999",
+				MerchantName: "Fulano",
+				MerchantCity: "BRASILIA",
+			},
+		},
+		{
+			name:    "tampered payload",
+			payload: "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Giz6008BRASILIA62070503***63041D3D",
+			wantErr: ErrInvalidCRC,
+		},
+		{
+			name:    "missing crc",
+			payload: "00020126580014br.gov.bcb.pix",
+			wantErr: ErrMissingCRC,
+		},
+		{
+			name:    "field overflowing the payload",
+			payload: withCRC("000201265800"),
+			wantErr: ErrMalformedPayload,
+		},
+		{
+			name:    "invalid field length",
+			payload: withCRC("0002012a"),
+			wantErr: ErrMalformedPayload,
+		},
+		{
+			name:    "duplicated field",
+			payload: withCRC("000201000201"),
+			wantErr: ErrDuplicatedField,
+		},
+		{
+			name:    "unsupported format",
+			payload: withCRC("000202"),
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "not a pix br code",
+			payload: withCRC("000201" + "26170013com.example.x" + "5802BR5906Fulano6008BRASILIA"),
+			wantErr: ErrNotPixBRCode,
+		},
+		{
+			name:    "dynamic br code",
+			payload: withCRC("000201" + "26400014br.gov.bcb.pix2518pix.example.com/qr" + "5802BR5906Fulano6008BRASILIA"),
+			wantErr: ErrDynamicBRCode,
+		},
+		{
+			name:    "invalid amount",
+			payload: withCRC("000201" + "26360014br.gov.bcb.pix0114+5511999999999" + "54041,005802BR5906Fulano6008BRASILIA"),
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name:    "missing city",
+			payload: withCRC("000201" + "26360014br.gov.bcb.pix0114+5511999999999" + "5802BR5906Fulano"),
+			wantErr: ErrMissingMerchantCity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_ErrorPosition(t *testing.T) {
+	_, err := Parse(withCRC("000201265800"))
+	want := "malformed br code payload: field 26 at position 6 overflows the payload"
+	if err == nil || err.Error() != want {
+		t.Errorf("Parse() error = %v, want %v", err, want)
+	}
+}
+
+func TestParse_Encode(t *testing.T) {
+	payload := StaticPayload{
+		PixKey:       "lucasszmt@gmail.com",
+		MerchantName: "Lucas Szmt",
+		MerchantCity: "CURITIBA",
+		Amount:       "150.00",
+		TxID:         "ABC123",
+	}
+	code, err := Encode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, payload) {
+		t.Errorf("Parse() got = %+v, want %+v", *got, payload)
+	}
+}
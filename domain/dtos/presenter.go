@@ -1,20 +1,120 @@
 package dtos
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
 
 type GetReceiverResponse struct {
-	Id       uuid.UUID `db:"id"`
-	Name     string    `db:"name"`
-	Email    string    `db:"email"`
-	Document string    `db:"document"`
-	Pixkey   string    `db:"pixkey"`
-	PixType  string    `db:"pix_type"`
-	Status   string    `db:"status"`
+	Id               uuid.UUID `db:"id"`
+	Name             string    `db:"name"`
+	Email            string    `db:"email"`
+	Document         string    `db:"document"`
+	Pixkey           string    `db:"pixkey"`
+	PixType          string    `db:"pix_type"`
+	BankCode         string    `db:"bank_code"`
+	BankBranch       string    `db:"bank_branch"`
+	BankAccount      string    `db:"bank_account"`
+	BankAccountDigit string    `db:"bank_account_digit"`
+	BankAccountType  string    `db:"bank_account_type"`
+	ThirdPartyPixKey bool      `db:"third_party_pix_key"`
+	Status           string    `db:"status"`
+	ValidationError  string    `db:"validation_error"`
+	Version          int       `db:"version"`
+}
+
+// SearchReceiverResponse is a receiver found by a search, Rank is how well it matched and Highlights holds the
+// matched fields with the matching parts wrapped in <mark>
+type SearchReceiverResponse struct {
+	GetReceiverResponse
+	Rank       float64           `db:"rank"`
+	Highlights map[string]string `db:"-"`
 }
 
 type ListReceiversResponse struct {
-	Id       uuid.UUID `db:"id" json:"id,omitempty"`
-	Name     string    `db:"name" json:"name,omitempty"`
-	Document string    `db:"document" json:"document,omitempty"`
-	Status   string    `db:"status" json:"status,omitempty"`
+	Id        uuid.UUID `db:"id" json:"id,omitempty"`
+	Name      string    `db:"name" json:"name,omitempty"`
+	Document  string    `db:"document" json:"document,omitempty"`
+	Status    string    `db:"status" json:"status,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"-"`
+}
+
+// Pagination describes a page of receivers, the cursors are only set when there are receivers after or before
+// it, and Page only when the page was asked by number
+type Pagination struct {
+	PageSize   int    `json:"page_size"`
+	Page       int    `json:"page,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+type ListReceiversPage struct {
+	Receivers  []ListReceiversResponse
+	Pagination Pagination
+}
+
+type PixKeyResponse struct {
+	Id         uuid.UUID `db:"id" json:"id"`
+	PixKey     string    `db:"pix_key" json:"pix_key"`
+	PixType    string    `db:"pix_type" json:"pix_key_type"`
+	Preferred  bool      `db:"preferred" json:"preferred"`
+	ThirdParty bool      `db:"third_party" json:"third_party"`
+}
+
+type ValidationResponse struct {
+	Valid     bool   `json:"valid"`
+	Reason    string `json:"reason,omitempty"`
+	OwnerName string `json:"owner_name,omitempty"`
+	ISPB      string `json:"ispb,omitempty"`
+}
+
+type HistoryEntryResponse struct {
+	Id        uuid.UUID       `db:"id" json:"id"`
+	Action    string          `db:"action" json:"action"`
+	Before    json.RawMessage `db:"before" json:"before"`
+	After     json.RawMessage `db:"after" json:"after"`
+	Actor     string          `db:"actor" json:"actor"`
+	RequestID string          `db:"request_id" json:"request_id"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// ImportRowResult is the outcome of a line of an import file, Status is one of created, valid (on dry runs),
+// invalid or skipped (valid rows of an all or nothing import that wasn't created)
+type ImportRowResult struct {
+	Line   int        `json:"line"`
+	Status string     `json:"status"`
+	Id     *uuid.UUID `json:"id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun       bool              `json:"dry_run"`
+	AllOrNothing bool              `json:"all_or_nothing"`
+	Total        int               `json:"total"`
+	Created      int               `json:"created"`
+	Failed       int               `json:"failed"`
+	Rows         []ImportRowResult `json:"rows"`
+}
+
+// ImportJobResponse is the progress of an import job, Status is one of queued, running, completed or failed
+type ImportJobResponse struct {
+	Id           uuid.UUID  `db:"id" json:"id"`
+	Status       string     `db:"status" json:"status"`
+	DryRun       bool       `db:"dry_run" json:"dry_run"`
+	AllOrNothing bool       `db:"all_or_nothing" json:"all_or_nothing"`
+	Total        int        `db:"total_rows" json:"total"`
+	Processed    int        `db:"processed_rows" json:"processed"`
+	Created      int        `db:"created_rows" json:"created"`
+	Failed       int        `db:"failed_rows" json:"failed"`
+	Error        string     `db:"error" json:"error,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	StartedAt    *time.Time `db:"started_at" json:"started_at,omitempty"`
+	FinishedAt   *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+}
+
+type ImportJobError struct {
+	Line  int    `db:"line" json:"line"`
+	Error string `db:"error" json:"error"`
 }
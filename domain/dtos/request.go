@@ -6,28 +6,103 @@ import (
 )
 
 type CreateReceiverRequest struct {
-	Name       string        `json:"name,omitempty" validate:"required"`
-	Email      string        `json:"email,omitempty" validate:"max=250"`
-	Doc        string        `json:"doc,omitempty"`
-	PixKeyType vo.PixKeyType `json:"pix_key_type,omitempty" validate:"required"`
-	PixKey     string        `json:"pix_key,omitempty" validate:"required,max=140"`
+	Name        string              `json:"name,omitempty" validate:"required"`
+	Email       string              `json:"email,omitempty" validate:"max=250"`
+	Doc         string              `json:"doc,omitempty"`
+	PixKeyType  vo.PixKeyType       `json:"pix_key_type,omitempty" validate:"required_without=BankAccount"`
+	PixKey      string              `json:"pix_key,omitempty" validate:"required_without=BankAccount,max=140"`
+	BankAccount *BankAccountRequest `json:"bank_account,omitempty"`
+}
+
+// CreateFromBRCodeRequest creates a receiver out of a "pix copia e cola" string, Doc may be left empty
+// when the BR Code key is the receiver's own CPF or CNPJ
+type CreateFromBRCodeRequest struct {
+	BRCode string `json:"brcode" validate:"required"`
+	Email  string `json:"email,omitempty" validate:"max=250"`
+	Doc    string `json:"doc,omitempty"`
+}
+
+type BankAccountRequest struct {
+	BankCode    string         `json:"bank_code" validate:"required"`
+	Branch      string         `json:"branch" validate:"required"`
+	Number      string         `json:"account_number" validate:"required"`
+	Digit       string         `json:"account_digit" validate:"required"`
+	AccountType vo.AccountType `json:"account_type" validate:"required"`
+}
+
+// Audit identifies who made a change to a receiver and in which request, it is recorded in the receiver history
+type Audit struct {
+	Actor     string
+	RequestID string
 }
 
 type DeleReceiverRequest struct {
 	Ids []uuid.UUID `json:"ids" validate:"required"`
 }
 
+type RestoreReceiversRequest struct {
+	Ids []uuid.UUID `json:"ids" validate:"required"`
+}
+
 type UpdateReceiverRequest struct {
-	Id         string        `json:"id" validate:"required"`
-	Name       string        `json:"name,omitempty" validate:"required"`
-	Email      string        `json:"email,omitempty" validate:"max=250"`
-	Doc        string        `json:"doc,omitempty"`
-	PixKeyType vo.PixKeyType `json:"pix_key_type,omitempty" validate:"required"`
-	PixKey     string        `json:"pix_key,omitempty" validate:"required,max=140"`
-	Status     string        `json:"status" validate:"required"`
+	Id          string              `json:"id" validate:"required"`
+	Name        string              `json:"name,omitempty" validate:"required"`
+	Email       string              `json:"email,omitempty" validate:"max=250"`
+	Doc         string              `json:"doc,omitempty"`
+	PixKeyType  vo.PixKeyType       `json:"pix_key_type,omitempty" validate:"required_without=BankAccount"`
+	PixKey      string              `json:"pix_key,omitempty" validate:"required_without=BankAccount,max=140"`
+	BankAccount *BankAccountRequest `json:"bank_account,omitempty"`
+	Status      string              `json:"status" validate:"required"`
+	// Version is the version of the receiver the update was based on, the If-Match header takes precedence
+	Version int `json:"version,omitempty"`
+}
+
+type TransitionStatusRequest struct {
+	Status string `json:"status" validate:"required"`
 }
 
+type AddPixKeyRequest struct {
+	PixKeyType vo.PixKeyType `json:"pix_key_type" validate:"required"`
+	PixKey     string        `json:"pix_key" validate:"required,max=140"`
+	Preferred  bool          `json:"preferred"`
+}
+
+// BRCodeRequest holds the optional fields of a static BR Code, City falls back to the configured default
+type BRCodeRequest struct {
+	Amount string `query:"amount"`
+	TxID   string `query:"txid"`
+	City   string `query:"city"`
+}
+
+// ImportReceiversRequest holds the modes of a CSV import, DryRun only validates the rows and AllOrNothing
+// creates the receivers only when every row is valid, all of them in the same transaction
+type ImportReceiversRequest struct {
+	DryRun       bool `query:"dry_run"`
+	AllOrNothing bool `query:"all_or_nothing"`
+}
+
+// SearchRequest searches the receivers by the Query text, or reads it with the search query language when Syntax
+// is "query"
 type SearchRequest struct {
-	Query string `params:"query" validate:"required"`
-	Limit int
+	Query  string `params:"query" validate:"required"`
+	Limit  int
+	Syntax string `query:"syntax"`
+}
+
+// ListReceiversRequest pages through the receivers with the Cursor of a previous page, or by the Page number, kept
+// for the clients written before cursors. Total counts every receiver along with the page
+type ListReceiversRequest struct {
+	Page     int    `query:"page"`
+	PageSize int    `query:"page_size"`
+	Cursor   string `query:"cursor"`
+	Total    bool   `query:"total"`
+}
+
+// ExportReceiversRequest filters the receivers as SearchRequest does, an empty Query exports all of them. Columns
+// is a comma separated list of the exported columns and Mask hides most of the documents and pix keys
+type ExportReceiversRequest struct {
+	Query   string `query:"query"`
+	Format  string `query:"format"`
+	Columns string `query:"columns"`
+	Mask    bool   `query:"mask"`
 }
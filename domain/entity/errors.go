@@ -0,0 +1,11 @@
+package entity
+
+import "errors"
+
+var (
+	ErrPixKeyNotOwned          = errors.New("cpf or cnpj pix key does not belong to the receiver document")
+	ErrNoPaymentDestination    = errors.New("a pix key or a bank account is required")
+	ErrInvalidStatus           = errors.New("invalid receiver status provided")
+	ErrInvalidStatusTransition = errors.New("receiver status transition not allowed")
+	ErrReceiverNotEditable     = errors.New("receiver can't be updated in its current status")
+)
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+)
+
+// ReceiverPixKey is one of the pix keys registered to a receiver, the preferred one is used to pay it
+type ReceiverPixKey struct {
+	id         uuid.UUID
+	receiverID uuid.UUID
+	key        *vo.PixKey
+	preferred  bool
+	thirdParty bool
+}
+
+// NewReceiverPixKey validates a new pix key of the receiver holding the document doc, following the same
+// ownership rule applied by NewReceiver
+func NewReceiverPixKey(receiverID uuid.UUID, doc string, keyType vo.PixKeyType, value string, preferred bool,
+	allowThirdPartyPixKey bool) (*ReceiverPixKey, error) {
+	holder, err := vo.NewCpfCnpj(doc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := vo.NewPixKey(keyType, value)
+	if err != nil {
+		return nil, err
+	}
+	thirdParty, err := pixKeyOwnership(holder, key, allowThirdPartyPixKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiverPixKey{
+		id:         uuid.New(),
+		receiverID: receiverID,
+		key:        key,
+		preferred:  preferred,
+		thirdParty: thirdParty,
+	}, nil
+}
+
+func (k *ReceiverPixKey) Id() uuid.UUID {
+	return k.id
+}
+
+func (k *ReceiverPixKey) ReceiverID() uuid.UUID {
+	return k.receiverID
+}
+
+func (k *ReceiverPixKey) Key() *vo.PixKey {
+	return k.key
+}
+
+func (k *ReceiverPixKey) Preferred() bool {
+	return k.preferred
+}
+
+func (k *ReceiverPixKey) ThirdParty() bool {
+	return k.thirdParty
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"testing"
+)
+
+func TestNewReceiverPixKey(t *testing.T) {
+	type args struct {
+		doc        string
+		keyType    vo.PixKeyType
+		value      string
+		allowThird bool
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantErr        error
+		wantThirdParty bool
+	}{
+		{
+			"Should create an email key",
+			args{"471.550.590-80", vo.EmailKey, "jhoncena@gmail.com", false},
+			nil,
+			false,
+		},
+		{
+			"Should create the receiver's own cpf key",
+			args{"471.550.590-80", vo.CPFKey, "47155059080", false},
+			nil,
+			false,
+		},
+		{
+			"Should return an err for a cpf key of another document",
+			args{"471.550.590-80", vo.CPFKey, "419.267.660-59", false},
+			ErrPixKeyNotOwned,
+			false,
+		},
+		{
+			"Should flag a cpf key of another document when allowed",
+			args{"471.550.590-80", vo.CPFKey, "419.267.660-59", true},
+			nil,
+			true,
+		},
+		{
+			"Should return an err for an invalid key",
+			args{"471.550.590-80", vo.RandomKey, "not-a-uuid", false},
+			vo.ErrInvalidRandomKey,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewReceiverPixKey(uuid.New(), tt.args.doc, tt.args.keyType, tt.args.value, true, tt.args.allowThird)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewReceiverPixKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.ThirdParty() != tt.wantThirdParty {
+				t.Errorf("ThirdParty() = %v, want %v", got.ThirdParty(), tt.wantThirdParty)
+			}
+		})
+	}
+}
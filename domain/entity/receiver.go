@@ -4,35 +4,28 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/lucasszmt/transfeera-challenge/domain/vo"
-	"strings"
-)
-
-type UserStatus int
-
-const (
-	Draft UserStatus = iota
-	Valid
 )
 
 type Receiver struct {
-	id     uuid.UUID
-	name   vo.Name
-	email  vo.EmailAddress
-	doc    *vo.CpfCnpj
-	pixKey *vo.PixKey
-	status UserStatus
+	id               uuid.UUID
+	name             vo.Name
+	email            vo.EmailAddress
+	doc              *vo.CpfCnpj
+	pixKey           *vo.PixKey
+	bankAccount      *vo.BankAccount
+	thirdPartyPixKey bool
+	status           UserStatus
 }
 
+// NewReceiver creates a draft receiver, paid through a pix key, a bank account or both, so the pix key may be
+// left empty when a bank account is provided. CPF and CNPJ pix keys must belong to the receiver's own document
+// unless allowThirdPartyPixKey is set, in which case the receiver is flagged as holding a third party key
 func NewReceiver(name string, emailAddress string, doc string, pixKeyType vo.PixKeyType,
-	pixKey string) (*Receiver, error) {
+	pixKey string, bankAccount *vo.BankAccount, allowThirdPartyPixKey bool) (*Receiver, error) {
 	r := new(Receiver)
 	var err error
 
 	r.id = uuid.New()
-	r.name, err = vo.NewName(name)
-	if err != nil {
-		return nil, err
-	}
 
 	if len(emailAddress) > 0 {
 		r.email, err = vo.NewEmail(emailAddress)
@@ -44,10 +37,13 @@ func NewReceiver(name string, emailAddress string, doc string, pixKeyType vo.Pix
 	if err != nil {
 		return nil, err
 	}
-	r.pixKey, err = vo.NewPixKey(pixKeyType, pixKey)
+	r.name, err = vo.NewName(name, r.doc.GetType())
 	if err != nil {
 		return nil, err
 	}
+	if err = r.setPaymentDestination(pixKeyType, pixKey, bankAccount, allowThirdPartyPixKey); err != nil {
+		return nil, err
+	}
 	r.status = Draft
 	return r, nil
 }
@@ -55,7 +51,7 @@ func NewReceiver(name string, emailAddress string, doc string, pixKeyType vo.Pix
 // TODO extracts this lot of constructore attributes to structs
 
 func NewUpdatebleReceiver(id string, name string, emailAddress string, doc string, pixKeyType vo.PixKeyType,
-	pixKey string, status string) (*Receiver, error) {
+	pixKey string, bankAccount *vo.BankAccount, status string, allowThirdPartyPixKey bool) (*Receiver, error) {
 	r := new(Receiver)
 	var err error
 
@@ -63,10 +59,6 @@ func NewUpdatebleReceiver(id string, name string, emailAddress string, doc strin
 	if err != nil {
 		return nil, fmt.Errorf("invalid id provided %w", err)
 	}
-	r.name, err = vo.NewName(name)
-	if err != nil {
-		return nil, err
-	}
 
 	if len(emailAddress) > 0 {
 		r.email, err = vo.NewEmail(emailAddress)
@@ -78,15 +70,15 @@ func NewUpdatebleReceiver(id string, name string, emailAddress string, doc strin
 	if err != nil {
 		return nil, err
 	}
-	r.pixKey, err = vo.NewPixKey(pixKeyType, pixKey)
+	r.name, err = vo.NewName(name, r.doc.GetType())
 	if err != nil {
 		return nil, err
 	}
-	if strings.ToLower(status) == "draft" {
-		r.status = Draft
+	if err = r.setPaymentDestination(pixKeyType, pixKey, bankAccount, allowThirdPartyPixKey); err != nil {
+		return nil, err
 	}
-	if strings.ToLower(status) == "valid" {
-		r.status = Valid
+	if r.status, err = ParseUserStatus(status); err != nil {
+		return nil, err
 	}
 	return r, nil
 }
@@ -100,7 +92,7 @@ func (r *Receiver) Name() string {
 }
 
 func (r *Receiver) SetName(name string) error {
-	n, err := vo.NewName(name)
+	n, err := vo.NewName(name, r.doc.GetType())
 	if err != nil {
 		return err
 	}
@@ -133,15 +125,70 @@ func (r *Receiver) PixKey() *vo.PixKey {
 	return r.pixKey
 }
 
-func (r *Receiver) SetPixKey(keyType vo.PixKeyType, value string) error {
+// SetPixKey replaces the pix key, which must follow the ownership rule as in NewReceiver, a refused key
+// leaves the receiver unchanged
+func (r *Receiver) SetPixKey(keyType vo.PixKeyType, value string, allowThirdParty bool) error {
 	pixKey, err := vo.NewPixKey(keyType, value)
 	if err != nil {
 		return err
 	}
+	previous, previousThirdParty := r.pixKey, r.thirdPartyPixKey
 	r.pixKey = pixKey
+	if err = r.checkPixKeyOwnership(allowThirdParty); err != nil {
+		r.pixKey, r.thirdPartyPixKey = previous, previousThirdParty
+		return err
+	}
+	return nil
+}
+
+func (r *Receiver) BankAccount() *vo.BankAccount {
+	return r.bankAccount
+}
+
+func (r *Receiver) SetBankAccount(account *vo.BankAccount) error {
+	if account == nil && r.pixKey == nil {
+		return ErrNoPaymentDestination
+	}
+	r.bankAccount = account
 	return nil
 }
 
+// ThirdPartyPixKey tells whether the receiver holds a CPF or CNPJ pix key of another document
+func (r *Receiver) ThirdPartyPixKey() bool {
+	return r.thirdPartyPixKey
+}
+
+// checkPixKeyOwnership makes sure the receiver's pix key follows the ownership rule of pixKeyOwnership
+func (r *Receiver) checkPixKeyOwnership(allowThirdParty bool) error {
+	r.thirdPartyPixKey = false
+	if r.pixKey == nil {
+		return nil
+	}
+	thirdParty, err := pixKeyOwnership(r.doc, r.pixKey, allowThirdParty)
+	if err != nil {
+		return err
+	}
+	r.thirdPartyPixKey = thirdParty
+	return nil
+}
+
+// pixKeyOwnership makes sure CPF and CNPJ pix keys are the holder's own document, the other key types can't be
+// matched against it and are always accepted. It tells whether the key belongs to a third party, which is only
+// accepted when allowThirdParty is set
+func pixKeyOwnership(doc *vo.CpfCnpj, key *vo.PixKey, allowThirdParty bool) (bool, error) {
+	keyType := vo.PixKeyType(key.KeyType())
+	if keyType != vo.CPFKey && keyType != vo.CNPJKey {
+		return false, nil
+	}
+	if key.Value() == doc.GetValue() {
+		return false, nil
+	}
+	if !allowThirdParty {
+		return false, ErrPixKeyNotOwned
+	}
+	return true, nil
+}
+
 func (r *Receiver) Status() UserStatus {
 	return r.status
 }
@@ -149,3 +196,29 @@ func (r *Receiver) Status() UserStatus {
 func (r *Receiver) SetStatus(status UserStatus) {
 	r.status = status
 }
+
+// TransitionTo moves the receiver to another status, following the transitions allowed by CheckTransition
+func (r *Receiver) TransitionTo(status UserStatus) error {
+	if err := CheckTransition(r.status, status); err != nil {
+		return err
+	}
+	r.status = status
+	return nil
+}
+
+// setPaymentDestination sets the pix key and the bank account of the receiver, at least one of them is required
+func (r *Receiver) setPaymentDestination(pixKeyType vo.PixKeyType, pixKey string, bankAccount *vo.BankAccount,
+	allowThirdPartyPixKey bool) error {
+	if len(pixKeyType) > 0 || len(pixKey) > 0 {
+		key, err := vo.NewPixKey(pixKeyType, pixKey)
+		if err != nil {
+			return err
+		}
+		r.pixKey = key
+	}
+	if r.pixKey == nil && bankAccount == nil {
+		return ErrNoPaymentDestination
+	}
+	r.bankAccount = bankAccount
+	return r.checkPixKeyOwnership(allowThirdPartyPixKey)
+}
@@ -13,6 +13,8 @@ func TestNewReceiver(t *testing.T) {
 		doc          string
 		pixKeyType   vo.PixKeyType
 		pixKey       string
+		bankAccount  *vo.BankAccount
+		allowThird   bool
 	}
 	tests := []struct {
 		name    string
@@ -80,10 +82,70 @@ func TestNewReceiver(t *testing.T) {
 			vo.ErrInvalidRandomKey,
 			true,
 		},
+		{
+			"Should return an err for a cpf pix key of another document",
+			args{
+				name:         "Jhon Cena",
+				emailAddress: "jhoncena@gmail.com",
+				doc:          "471.550.590-80",
+				pixKeyType:   vo.CPFKey,
+				pixKey:       "419.267.660-59",
+			},
+			ErrPixKeyNotOwned,
+			true,
+		},
+		{
+			"Should create a receiver with a third party pix key when allowed",
+			args{
+				name:         "Jhon Cena",
+				emailAddress: "jhoncena@gmail.com",
+				doc:          "471.550.590-80",
+				pixKeyType:   vo.CPFKey,
+				pixKey:       "419.267.660-59",
+				allowThird:   true,
+			},
+			nil,
+			false,
+		},
+		{
+			"Should create a receiver paid only by bank account",
+			args{
+				name:         "Jhon Cena",
+				emailAddress: "jhoncena@gmail.com",
+				doc:          "471.550.590-80",
+				bankAccount:  mustBankAccount(t),
+			},
+			nil,
+			false,
+		},
+		{
+			"Should create a receiver with both a pix key and a bank account",
+			args{
+				name:         "Jhon Cena",
+				emailAddress: "jhoncena@gmail.com",
+				doc:          "471.550.590-80",
+				pixKeyType:   vo.CPFKey,
+				pixKey:       "471.550.590-80",
+				bankAccount:  mustBankAccount(t),
+			},
+			nil,
+			false,
+		},
+		{
+			"Should return an err for a receiver without payment destination",
+			args{
+				name:         "Jhon Cena",
+				emailAddress: "jhoncena@gmail.com",
+				doc:          "471.550.590-80",
+			},
+			ErrNoPaymentDestination,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewReceiver(tt.args.name, tt.args.emailAddress, tt.args.doc, tt.args.pixKeyType, tt.args.pixKey)
+			_, err := NewReceiver(tt.args.name, tt.args.emailAddress, tt.args.doc, tt.args.pixKeyType, tt.args.pixKey,
+				tt.args.bankAccount, tt.args.allowThird)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewReceiver() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -94,3 +156,65 @@ func TestNewReceiver(t *testing.T) {
 		})
 	}
 }
+
+func TestReceiver_ThirdPartyPixKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		pixKeyType vo.PixKeyType
+		pixKey     string
+		want       bool
+	}{
+		{"Should not flag the receiver's own cpf key", vo.CPFKey, "471.550.590-80", false},
+		{"Should flag a cpf key of another document", vo.CPFKey, "419.267.660-59", true},
+		{"Should not flag an email key", vo.EmailKey, "jhoncena@gmail.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReceiver("Jhon Cena", "jhoncena@gmail.com", "471.550.590-80", tt.pixKeyType, tt.pixKey, nil, true)
+			if err != nil {
+				t.Fatalf("NewReceiver() error = %v", err)
+			}
+			if got := r.ThirdPartyPixKey(); got != tt.want {
+				t.Errorf("ThirdPartyPixKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceiver_SetPixKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		pixKey         string
+		allowThird     bool
+		wantThirdParty bool
+		wantErr        error
+	}{
+		{"Should flag a cpf key of another document when allowed", "419.267.660-59", true, true, nil},
+		{"Should refuse a cpf key of another document when not allowed", "419.267.660-59", false, false, ErrPixKeyNotOwned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReceiver("Jhon Cena", "jhoncena@gmail.com", "471.550.590-80", vo.EmailKey, "jhoncena@gmail.com", nil, false)
+			if err != nil {
+				t.Fatalf("NewReceiver() error = %v", err)
+			}
+			if err = r.SetPixKey(vo.CPFKey, tt.pixKey, tt.allowThird); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetPixKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := r.ThirdPartyPixKey(); got != tt.wantThirdParty {
+				t.Errorf("ThirdPartyPixKey() = %v, want %v", got, tt.wantThirdParty)
+			}
+			if err != nil && r.PixKey().KeyType() != string(vo.EmailKey) {
+				t.Errorf("SetPixKey() replaced the key with a refused one: %v", r.PixKey().Value())
+			}
+		})
+	}
+}
+
+func mustBankAccount(t *testing.T) *vo.BankAccount {
+	account, err := vo.NewBankAccount("341", "0123", "12345", "6", vo.CheckingAccount)
+	if err != nil {
+		t.Fatalf("NewBankAccount() error = %v", err)
+	}
+	return account
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+type UserStatus int
+
+// the numeric values are the ones stored in the receiver status column
+const (
+	Draft UserStatus = iota
+	Valid
+	Blocked
+	Archived
+)
+
+var statusNames = map[UserStatus]string{
+	Draft:    "draft",
+	Valid:    "valid",
+	Blocked:  "blocked",
+	Archived: "archived",
+}
+
+// statusTransitions lists the statuses each status may move to. Archived receivers can only go back to
+// draft, so they are validated again before being paid
+var statusTransitions = map[UserStatus][]UserStatus{
+	Draft:    {Valid, Blocked, Archived},
+	Valid:    {Blocked, Archived},
+	Blocked:  {Draft, Valid, Archived},
+	Archived: {Draft},
+}
+
+// ParseUserStatus reads a status by its name, the same one used in the JSON and returned by the SQL queries
+func ParseUserStatus(status string) (UserStatus, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	for s, name := range statusNames {
+		if name == status {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+}
+
+func (s UserStatus) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+func (s UserStatus) CanTransitionTo(to UserStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition returns an ErrInvalidStatusTransition when a receiver can't move from one status to the other
+func CheckTransition(from, to UserStatus) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, from, to)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseUserStatus(t *testing.T) {
+	tests := []struct {
+		status  string
+		want    UserStatus
+		wantErr error
+	}{
+		{"draft", Draft, nil},
+		{"Valid", Valid, nil},
+		{" blocked ", Blocked, nil},
+		{"archived", Archived, nil},
+		{"active", 0, ErrInvalidStatus},
+		{"", 0, ErrInvalidStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			got, err := ParseUserStatus(tt.status)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseUserStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseUserStatus() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from    UserStatus
+		to      UserStatus
+		wantErr bool
+	}{
+		{Draft, Valid, false},
+		{Draft, Blocked, false},
+		{Draft, Archived, false},
+		{Valid, Blocked, false},
+		{Valid, Archived, false},
+		{Valid, Draft, true},
+		{Blocked, Valid, false},
+		{Blocked, Draft, false},
+		{Archived, Draft, false},
+		{Archived, Valid, true},
+		{Archived, Blocked, true},
+		{Draft, Draft, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"->"+tt.to.String(), func(t *testing.T) {
+			err := CheckTransition(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckTransition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidStatusTransition) {
+				t.Errorf("CheckTransition() error = %v, want ErrInvalidStatusTransition", err)
+			}
+		})
+	}
+}
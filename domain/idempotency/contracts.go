@@ -0,0 +1,26 @@
+package idempotency
+
+import "time"
+
+// Response is the outcome of a request, replayed to the retries sent with the same idempotency key
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Record is an idempotency key already claimed by a request, Response is nil while that request is running
+type Record struct {
+	Fingerprint string
+	Response    *Response
+}
+
+type Store interface {
+	// Reserve claims the key for the request, in a single statement so concurrent requests can't both claim it.
+	// Keys expired before now, or left running since staleBefore, are claimed again. It returns nil when the key
+	// was claimed and the record holding the key otherwise
+	Reserve(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error)
+	Complete(key, fingerprint string, resp Response) error
+	Release(key, fingerprint string) error
+	Purge(expiredBefore time.Time) (int64, error)
+}
@@ -0,0 +1,9 @@
+package idempotency
+
+import "errors"
+
+var (
+	ErrInvalidKey          = errors.New("idempotency key must have between 1 and 255 characters")
+	ErrFingerprintMismatch = errors.New("idempotency key was already used with a different request")
+	ErrRequestInProgress   = errors.New("a request with the same idempotency key is still in progress")
+)
@@ -0,0 +1,91 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"time"
+)
+
+const (
+	MaxKeyLength = 255
+	// InFlightTimeout is how long a key stays locked by a request that never completed, after that it can be
+	// claimed again, so a crash in the middle of a request doesn't lock the key until it expires
+	InFlightTimeout = time.Minute
+)
+
+// Guard makes requests idempotent, the first request sent with a key runs and its response is kept for TTL,
+// replayed to the requests sent afterwards with the same key and the same fingerprint
+type Guard struct {
+	log   log.Logger
+	store Store
+	ttl   time.Duration
+}
+
+func NewGuard(log log.Logger, store Store, ttl time.Duration) *Guard {
+	return &Guard{log: log, store: store, ttl: ttl}
+}
+
+// Begin claims the key for a request, it returns the response to replay when the key was already used by
+// the same request, or nil when the request should run and then be completed or released
+func (g *Guard) Begin(key, fingerprint string) (*Response, error) {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return nil, ErrInvalidKey
+	}
+	now := time.Now()
+	record, err := g.store.Reserve(key, fingerprint, now, now.Add(g.ttl), now.Add(-InFlightTimeout))
+	if err != nil {
+		g.log.Error(fmt.Sprintf("error reserving the idempotency key %s", key), err)
+		return nil, err
+	}
+	switch {
+	case record == nil:
+		return nil, nil
+	case record.Fingerprint != fingerprint:
+		return nil, ErrFingerprintMismatch
+	case record.Response == nil:
+		return nil, ErrRequestInProgress
+	default:
+		return record.Response, nil
+	}
+}
+
+// Complete stores the response of the request that claimed the key
+func (g *Guard) Complete(key, fingerprint string, resp Response) error {
+	if err := g.store.Complete(key, fingerprint, resp); err != nil {
+		g.log.Error(fmt.Sprintf("error storing the response of the idempotency key %s", key), err)
+		return err
+	}
+	return nil
+}
+
+// Release frees the key of a request that failed, so it can be retried with the same key
+func (g *Guard) Release(key, fingerprint string) error {
+	if err := g.store.Release(key, fingerprint); err != nil {
+		g.log.Error(fmt.Sprintf("error releasing the idempotency key %s", key), err)
+		return err
+	}
+	return nil
+}
+
+// PurgeExpired removes the keys whose responses are no longer replayed
+func (g *Guard) PurgeExpired() (int64, error) {
+	purged, err := g.store.Purge(time.Now())
+	if err != nil {
+		g.log.Error("error purging expired idempotency keys", err)
+		return 0, err
+	}
+	return purged, nil
+}
+
+// Fingerprint hashes the parts identifying a request, each part is length prefixed so moving bytes from one
+// part to the next changes the fingerprint
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
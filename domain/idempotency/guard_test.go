@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"errors"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type storeMock struct {
+	Err         error
+	ReserveMock func(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error)
+}
+
+func (s storeMock) Reserve(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error) {
+	switch {
+	case s.ReserveMock != nil:
+		return s.ReserveMock(key, fingerprint, now, expiresAt, staleBefore)
+	default:
+		return nil, s.Err
+	}
+}
+
+func (s storeMock) Complete(key, fingerprint string, resp Response) error {
+	return s.Err
+}
+
+func (s storeMock) Release(key, fingerprint string) error {
+	return s.Err
+}
+
+func (s storeMock) Purge(expiredBefore time.Time) (int64, error) {
+	return 0, s.Err
+}
+
+func TestGuard_Begin(t *testing.T) {
+	stored := &Response{StatusCode: 201, ContentType: "application/json", Body: []byte(`{"status":true}`)}
+	tests := []struct {
+		name        string
+		key         string
+		store       Store
+		want        *Response
+		ExpectedErr error
+	}{
+		{
+			name: "Should run the request when the key is claimed",
+			key:  "a2f1c1d0",
+			store: storeMock{ReserveMock: func(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error) {
+				if expiresAt.Sub(now) != time.Hour || now.Sub(staleBefore) != InFlightTimeout {
+					return nil, errors.New("unexpected expiration")
+				}
+				return nil, nil
+			}},
+		},
+		{
+			name: "Should replay the response of the same request",
+			key:  "a2f1c1d0",
+			store: storeMock{ReserveMock: func(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error) {
+				return &Record{Fingerprint: "fingerprint", Response: stored}, nil
+			}},
+			want: stored,
+		},
+		{
+			name: "Should refuse a key used with another request",
+			key:  "a2f1c1d0",
+			store: storeMock{ReserveMock: func(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error) {
+				return &Record{Fingerprint: "other fingerprint", Response: stored}, nil
+			}},
+			ExpectedErr: ErrFingerprintMismatch,
+		},
+		{
+			name: "Should refuse a key held by a running request",
+			key:  "a2f1c1d0",
+			store: storeMock{ReserveMock: func(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*Record, error) {
+				return &Record{Fingerprint: "fingerprint"}, nil
+			}},
+			ExpectedErr: ErrRequestInProgress,
+		},
+		{
+			name:        "Should refuse a key longer than allowed",
+			key:         strings.Repeat("k", MaxKeyLength+1),
+			store:       storeMock{},
+			ExpectedErr: ErrInvalidKey,
+		},
+		{
+			name:        "Should return the store errors",
+			key:         "a2f1c1d0",
+			store:       storeMock{Err: errors.New("connection refused")},
+			ExpectedErr: errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGuard(log.MockLogger{}, tt.store, time.Hour)
+			got, err := g.Begin(tt.key, "fingerprint")
+			if (err != nil) != (tt.ExpectedErr != nil) || err != nil && err.Error() != tt.ExpectedErr.Error() {
+				t.Fatalf("Begin() error = %v, expectedErr %v", err, tt.ExpectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Begin() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     [][]byte
+		b     [][]byte
+		equal bool
+	}{
+		{"Should match the same request", [][]byte{[]byte("POST"), []byte(`{"name":"a"}`)}, [][]byte{[]byte("POST"), []byte(`{"name":"a"}`)}, true},
+		{"Should not match another body", [][]byte{[]byte("POST"), []byte(`{"name":"a"}`)}, [][]byte{[]byte("POST"), []byte(`{"name":"b"}`)}, false},
+		{"Should not match bytes moved between parts", [][]byte{[]byte("POST/"), []byte("a")}, [][]byte{[]byte("POST"), []byte("/a")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fingerprint(tt.a...) == Fingerprint(tt.b...); got != tt.equal {
+				t.Errorf("Fingerprint() equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
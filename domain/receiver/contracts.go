@@ -4,19 +4,46 @@ import (
 	"github.com/google/uuid"
 	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
 	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"io"
+	"time"
 )
 
+// Writer stores receivers, Create and UpdateDraft return a DuplicateReceiverError when the receiver pix key, or its
+// document when uniqueDocument is set, is already held by another receiver
 type Writer interface {
-	Create(receiver *entity.Receiver) (*entity.Receiver, error)
-	UpdateDraft(receiver *entity.Receiver) (*entity.Receiver, error)
-	UpdateValid(id uuid.UUID, email string) error
-	Delete(id ...uuid.UUID) error
+	Create(audit dtos.Audit, receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error)
+	// CreateBatch stores every receiver or none of them, returning a BatchError with the one that failed
+	CreateBatch(audit dtos.Audit, receivers []*entity.Receiver, uniqueDocument bool) error
+	UpdateDraft(audit dtos.Audit, receiver *entity.Receiver, version int, uniqueDocument bool) (*entity.Receiver, error)
+	UpdateValid(audit dtos.Audit, id uuid.UUID, email string, version int) error
+	UpdateStatus(audit dtos.Audit, id uuid.UUID, from, to entity.UserStatus) error
+	SaveValidation(audit dtos.Audit, id uuid.UUID, reason string) error
+	Delete(audit dtos.Audit, id ...uuid.UUID) error
+	// Restore brings the deleted receivers back, when uniqueDocument is set it fails with a DuplicateReceiverError
+	// if a restored receiver shares its document with another one not deleted
+	Restore(audit dtos.Audit, uniqueDocument bool, id ...uuid.UUID) (int64, error)
+	// Purge removes the receivers deleted longer than the retention, measured by the clock of the repository
+	Purge(audit dtos.Audit, retention time.Duration) (int64, error)
+	AddPixKey(audit dtos.Audit, key *entity.ReceiverPixKey) error
+	RemovePixKey(audit dtos.Audit, receiverID, keyID uuid.UUID) error
+	SetPreferredPixKey(audit dtos.Audit, receiverID, keyID uuid.UUID) error
 }
 
 type Reader interface {
 	GetByID(id uuid.UUID) (*dtos.GetReceiverResponse, error)
-	Get(query string, limit int) ([]dtos.GetReceiverResponse, error)
-	List(page int) ([]dtos.ListReceiversResponse, error)
+	// Search reads the receivers matching the query, the most relevant first
+	Search(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error)
+	// List reads limit receivers matching the filter skipping offset, in the same order ListFrom uses
+	List(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error)
+	// ListFrom reads limit receivers matching the filter after the cursor, or before it when backward, always in
+	// the order of the filter sort
+	ListFrom(filter Filter, cursor Cursor, limit int) ([]dtos.ListReceiversResponse, error)
+	Count(filter Filter) (int, error)
+	ListPixKeys(receiverID uuid.UUID) ([]dtos.PixKeyResponse, error)
+	History(id uuid.UUID) ([]dtos.HistoryEntryResponse, error)
+	// Export calls each for every receiver matching the query and the filter, the query is ignored when empty, as
+	// they are read from the database
+	Export(query SearchQuery, filter Filter, each func(receiver *dtos.GetReceiverResponse) error) error
 }
 
 type Repository interface {
@@ -24,11 +51,50 @@ type Repository interface {
 	Reader
 }
 
+type ImportJobRepository interface {
+	CreateImportJob(job *ImportJob) error
+	GetImportJob(id uuid.UUID) (*dtos.ImportJobResponse, error)
+	ImportJobErrors(id uuid.UUID) ([]dtos.ImportJobError, error)
+	// ClaimImportJob takes the oldest queued job, or a running one without progress since staleBefore, for the
+	// claim. It returns nil when there is no job to run
+	ClaimImportJob(claim uuid.UUID, staleBefore time.Time) (*ImportJob, error)
+	// SaveImportChunk creates the receivers of the valid rows when create is set, setting Err on the rows it
+	// couldn't create, and records the errors of the rows and the job progress, all in the same transaction
+	SaveImportChunk(job *ImportJob, rows []ImportChunkRow, create bool, uniqueDocument bool) error
+	// FinishImportJob completes the job creating the receivers in the same transaction, it returns a BatchError
+	// with the receiver that failed, in which case nothing is stored
+	FinishImportJob(job *ImportJob, receivers []*entity.Receiver, uniqueDocument bool) error
+	// SaveImportError records the error of a row already processed, counting it as failed
+	SaveImportError(job *ImportJob, line int, err error) error
+	FailImportJob(job *ImportJob, reason string) error
+}
+
+// UseCase receives the dtos.Audit of the request in every method changing receivers, so the change is
+// recorded in the receiver history along with who made it
 type UseCase interface {
-	CreateReceiver(request dtos.CreateReceiverRequest) (*entity.Receiver, error)
-	SearchReceivers(request dtos.SearchRequest) ([]dtos.GetReceiverResponse, error)
-	UpdateReceiver(req dtos.UpdateReceiverRequest) error
-	ListReceivers(page int) ([]dtos.ListReceiversResponse, error)
+	CreateReceiver(audit dtos.Audit, request dtos.CreateReceiverRequest) (*entity.Receiver, error)
+	CreateReceiverFromBRCode(audit dtos.Audit, request dtos.CreateFromBRCodeRequest) (*entity.Receiver, error)
+	ImportReceivers(audit dtos.Audit, file io.Reader, req dtos.ImportReceiversRequest) (*dtos.ImportReport, error)
+	SearchReceivers(request dtos.SearchRequest, filter Filter) ([]dtos.SearchReceiverResponse, error)
+	UpdateReceiver(audit dtos.Audit, req dtos.UpdateReceiverRequest) error
+	TransitionReceiver(audit dtos.Audit, id string, req dtos.TransitionStatusRequest) error
+	ValidateReceiver(audit dtos.Audit, id string) (*dtos.ValidationResponse, error)
+	ListReceivers(req dtos.ListReceiversRequest, filter Filter) (*dtos.ListReceiversPage, error)
 	GetReceiver(id string) (*dtos.GetReceiverResponse, error)
-	DeleteReceivers(ids dtos.DeleReceiverRequest) error
+	ReceiverHistory(id string) ([]dtos.HistoryEntryResponse, error)
+	DeleteReceivers(audit dtos.Audit, ids dtos.DeleReceiverRequest) error
+	RestoreReceivers(audit dtos.Audit, req dtos.RestoreReceiversRequest) error
+	ListPixKeys(id string) ([]dtos.PixKeyResponse, error)
+	AddPixKey(audit dtos.Audit, id string, req dtos.AddPixKeyRequest) (*entity.ReceiverPixKey, error)
+	RemovePixKey(audit dtos.Audit, id string, keyID string) error
+	SetPreferredPixKey(audit dtos.Audit, id string, keyID string) error
+	GenerateBRCode(id string, req dtos.BRCodeRequest) (string, error)
+	ExportReceivers(req dtos.ExportReceiversRequest, filter Filter) (*Export, error)
+}
+
+// ImportUseCase queues CSV imports as background jobs and tracks their progress
+type ImportUseCase interface {
+	SubmitImport(audit dtos.Audit, file []byte, req dtos.ImportReceiversRequest) (*dtos.ImportJobResponse, error)
+	ImportJob(id string) (*dtos.ImportJobResponse, error)
+	ImportJobErrors(id string) ([]dtos.ImportJobError, error)
 }
@@ -0,0 +1,82 @@
+package receiver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"time"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// Cursor points at the receiver a page starts after, or ends before when Backward is set. Receivers are ordered by
+// the Sort field, and then by id, so new receivers don't shift the pages already seen. The cursor holds the values
+// of the receiver for every sort field, only the one of its Sort is compared
+type Cursor struct {
+	Sort      Sort
+	CreatedAt time.Time
+	Name      string
+	Status    entity.UserStatus
+	Id        uuid.UUID
+	Backward  bool
+}
+
+type encodedCursor struct {
+	Sort      string            `json:"o,omitempty"`
+	CreatedAt time.Time         `json:"t"`
+	Name      string            `json:"n,omitempty"`
+	Status    entity.UserStatus `json:"s,omitempty"`
+	Id        uuid.UUID         `json:"i"`
+	Backward  bool              `json:"b,omitempty"`
+}
+
+// cursorAt points a cursor at a receiver of a page sorted by sort
+func cursorAt(r dtos.ListReceiversResponse, sort Sort, backward bool) Cursor {
+	status, _ := entity.ParseUserStatus(r.Status)
+	return Cursor{Sort: sort, CreatedAt: r.CreatedAt, Name: r.Name, Status: status, Id: r.Id, Backward: backward}
+}
+
+// Encode writes the cursor as an opaque url safe string
+func (c Cursor) Encode() string {
+	encoded, _ := json.Marshal(encodedCursor{
+		Sort:      c.Sort.String(),
+		CreatedAt: c.CreatedAt,
+		Name:      c.Name,
+		Status:    c.Status,
+		Id:        c.Id,
+		Backward:  c.Backward,
+	})
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor reads a cursor written by Encode, the cursors written before the receivers could be sorted are
+// sorted by their creation time
+func DecodeCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	decoded := encodedCursor{}
+	if err = json.Unmarshal(raw, &decoded); err != nil || decoded.Id == uuid.Nil || decoded.CreatedAt.IsZero() {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c := Cursor{
+		Sort:      Sort{Field: SortCreatedAt},
+		CreatedAt: decoded.CreatedAt,
+		Name:      decoded.Name,
+		Status:    decoded.Status,
+		Id:        decoded.Id,
+		Backward:  decoded.Backward,
+	}
+	if len(decoded.Sort) > 0 {
+		if c.Sort, err = ParseSort(decoded.Sort); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
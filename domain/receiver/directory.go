@@ -0,0 +1,15 @@
+package receiver
+
+// KeyOwner is the holder of a pix key as registered in the key directory
+type KeyOwner struct {
+	Document string
+	Name     string
+	// ISPB identifies the bank or payment institution holding the account the key points to
+	ISPB string
+}
+
+// KeyDirectory resolves pix keys to their owners, as the BACEN DICT does. Lookup returns
+// ErrKeyNotInDirectory for keys that aren't registered
+type KeyDirectory interface {
+	Lookup(key string) (*KeyOwner, error)
+}
@@ -1,7 +1,58 @@
 package receiver
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+)
 
 var (
-	ErrReceiverNotFound = errors.New("receiver not found")
+	ErrReceiverNotFound        = errors.New("receiver not found")
+	ErrPixKeyNotFound          = errors.New("pix key not found")
+	ErrPixKeyAlreadyRegistered = errors.New("pix key already registered")
+	ErrReceiverWithoutPixKey   = errors.New("receiver has no pix key")
+	ErrStatusMismatch          = errors.New("receiver status can only be changed through the status endpoint")
+	ErrStatusChanged           = errors.New("receiver status was changed by another request")
+	ErrKeyNotInDirectory       = errors.New("pix key not found in the key directory")
+	ErrDocumentMismatch        = errors.New("pix key owner document does not match the receiver document")
+	ErrVersionRequired         = errors.New("receiver version is required, send it in the If-Match header or the version field")
+	ErrReceiverAlreadyExists   = errors.New("receiver already exists")
+	ErrInvalidImportFile       = errors.New("invalid import file")
+	ErrInvalidImportRow        = errors.New("invalid import row")
+	ErrVersionMismatch         = errors.New("receiver was changed by another request, reload it and retry")
+	ErrInvalidCursor           = errors.New("invalid cursor, use one returned by the previous page")
+	ErrInvalidFilter           = errors.New("invalid filter")
+	ErrInvalidQuery            = errors.New("invalid search query")
+	ErrInvalidExportFormat     = errors.New("invalid export format")
+	ErrInvalidExportColumn     = errors.New("invalid export column")
+	ErrImportJobNotFound       = errors.New("import job not found")
+	ErrImportJobReclaimed      = errors.New("import job was taken over by another worker")
 )
+
+// DuplicateReceiverError is ErrReceiverAlreadyExists along with the receiver already holding the duplicated data
+type DuplicateReceiverError struct {
+	ExistingID uuid.UUID
+	Field      string
+}
+
+func (e *DuplicateReceiverError) Error() string {
+	return fmt.Sprintf("%s with the same %s: %s", ErrReceiverAlreadyExists, e.Field, e.ExistingID)
+}
+
+func (e *DuplicateReceiverError) Unwrap() error {
+	return ErrReceiverAlreadyExists
+}
+
+// BatchError tells which receiver made a batch fail, in which case none of the batch receivers is stored
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("receiver %d of the batch: %s", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
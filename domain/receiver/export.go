@@ -0,0 +1,266 @@
+package receiver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// export formats
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+	ExportXLSX  = "xlsx"
+)
+
+// ExportFormats lists the export formats in order of preference, along with their media types in ExportMediaTypes
+var ExportFormats = []string{ExportCSV, ExportJSONL, ExportXLSX}
+
+var ExportMediaTypes = map[string]string{
+	ExportCSV:   "text/csv",
+	ExportJSONL: "application/x-ndjson",
+	ExportXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type exportColumn struct {
+	name  string
+	value func(r *dtos.GetReceiverResponse, mask bool) string
+}
+
+// exportColumns are the columns exported by default, in order. They are named as the import columns, so an exported
+// file can be imported back
+var exportColumns = []exportColumn{
+	{"id", func(r *dtos.GetReceiverResponse, mask bool) string { return r.Id.String() }},
+	{"name", func(r *dtos.GetReceiverResponse, mask bool) string { return r.Name }},
+	{"email", func(r *dtos.GetReceiverResponse, mask bool) string { return r.Email }},
+	{"doc", func(r *dtos.GetReceiverResponse, mask bool) string {
+		if mask {
+			return maskDocument(r.Document)
+		}
+		return r.Document
+	}},
+	{"pix_key_type", func(r *dtos.GetReceiverResponse, mask bool) string { return r.PixType }},
+	{"pix_key", func(r *dtos.GetReceiverResponse, mask bool) string {
+		if mask {
+			return maskPixKey(vo.PixKeyType(r.PixType), r.Pixkey)
+		}
+		return r.Pixkey
+	}},
+	{"bank_code", func(r *dtos.GetReceiverResponse, mask bool) string { return r.BankCode }},
+	{"branch", func(r *dtos.GetReceiverResponse, mask bool) string { return r.BankBranch }},
+	{"account_number", func(r *dtos.GetReceiverResponse, mask bool) string { return r.BankAccount }},
+	{"account_digit", func(r *dtos.GetReceiverResponse, mask bool) string { return r.BankAccountDigit }},
+	{"account_type", func(r *dtos.GetReceiverResponse, mask bool) string { return r.BankAccountType }},
+	{"status", func(r *dtos.GetReceiverResponse, mask bool) string { return r.Status }},
+}
+
+// exportWriter writes the rows of an export, the header is written when it's created
+type exportWriter interface {
+	Write(values []string) error
+	Close() error
+}
+
+// Export is a prepared export of receivers, written by Stream straight from the repository rows, so the whole export
+// is never held in memory
+type Export struct {
+	log     log.Logger
+	repo    Reader
+	format  string
+	query   SearchQuery
+	filter  Filter
+	columns []exportColumn
+	mask    bool
+}
+
+// ExportReceivers checks the export format and columns, nothing is read until the export is streamed. The receivers
+// are narrowed by the filter as in SearchReceivers
+func (s *Service) ExportReceivers(req dtos.ExportReceiversRequest, filter Filter) (*Export, error) {
+	export := &Export{
+		log:    s.log,
+		repo:   s.repo,
+		format: strings.ToLower(req.Format),
+		query:  NewSearchQuery(req.Query),
+		filter: filter,
+		mask:   req.Mask,
+	}
+	if len(export.format) == 0 {
+		export.format = ExportCSV
+	}
+	if _, ok := ExportMediaTypes[export.format]; !ok {
+		return nil, fmt.Errorf("%w: %q, use one of %s", ErrInvalidExportFormat, req.Format, strings.Join(ExportFormats, ", "))
+	}
+	if len(strings.TrimSpace(req.Columns)) == 0 {
+		export.columns = exportColumns
+		return export, nil
+	}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(req.Columns, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		column, ok := findExportColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidExportColumn, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicated column %q", ErrInvalidExportColumn, name)
+		}
+		seen[name] = true
+		export.columns = append(export.columns, column)
+	}
+	return export, nil
+}
+
+func findExportColumn(name string) (exportColumn, bool) {
+	for _, column := range exportColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return exportColumn{}, false
+}
+
+func (e *Export) ContentType() string {
+	if e.format == ExportCSV {
+		return ExportMediaTypes[e.format] + "; charset=utf-8"
+	}
+	return ExportMediaTypes[e.format]
+}
+
+func (e *Export) FileName() string {
+	return "receivers." + e.format
+}
+
+// Stream writes the export to w as the receivers are read
+func (e *Export) Stream(w io.Writer) error {
+	header := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		header[i] = column.name
+	}
+	var out exportWriter
+	var err error
+	switch e.format {
+	case ExportJSONL:
+		out = &jsonlExport{w: w, columns: header}
+	case ExportXLSX:
+		out, err = newXLSXExport(w, header)
+	default:
+		out, err = newCSVExport(w, header)
+	}
+	if err != nil {
+		e.log.Error("error starting the receivers export", err)
+		return err
+	}
+	values := make([]string, len(e.columns))
+	err = e.repo.Export(e.query, e.filter, func(r *dtos.GetReceiverResponse) error {
+		for i, column := range e.columns {
+			values[i] = column.value(r, e.mask)
+		}
+		return out.Write(values)
+	})
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		e.log.Error("error exporting the receivers", err)
+	}
+	return err
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer, header []string) (*csvExport, error) {
+	export := &csvExport{w: csv.NewWriter(w)}
+	return export, export.Write(header)
+}
+
+func (c *csvExport) Write(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvExport) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlExport writes a json object per line, keeping the keys in the order of the columns
+type jsonlExport struct {
+	w       io.Writer
+	columns []string
+}
+
+func (j *jsonlExport) Write(values []string) error {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		key, _ := json.Marshal(j.columns[i])
+		encoded, _ := json.Marshal(value)
+		sb.Write(key)
+		sb.WriteByte(':')
+		sb.Write(encoded)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(j.w, sb.String())
+	return err
+}
+
+func (j *jsonlExport) Close() error {
+	return nil
+}
+
+// maskDocument hides the first and last digits of a CPF or CNPJ, as in ***.456.789-**, other values only keep
+// their last 4 characters
+func maskDocument(doc string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || unicode.IsUpper(r) {
+			return r
+		}
+		return -1
+	}, doc)
+	switch len(digits) {
+	case 11:
+		return "***." + digits[3:6] + "." + digits[6:9] + "-**"
+	case 14:
+		return "**." + digits[2:5] + "." + digits[5:8] + "/" + digits[8:12] + "-**"
+	}
+	return maskMiddle(doc, 0, 4)
+}
+
+// maskPixKey hides most of a pix key, keeping enough of it to be told apart: the email domain, the last digits of a
+// phone number and the first characters of a random key
+func maskPixKey(keyType vo.PixKeyType, key string) string {
+	switch keyType {
+	case vo.CPFKey, vo.CNPJKey:
+		return maskDocument(key)
+	case vo.EmailKey:
+		if at := strings.LastIndexByte(key, '@'); at > 0 {
+			return maskMiddle(key[:at], 1, 0) + key[at:]
+		}
+	case vo.PhoneKey:
+		return maskMiddle(key, 3, 4)
+	case vo.RandomKey:
+		return maskMiddle(key, 8, 0)
+	}
+	return maskMiddle(key, 0, 4)
+}
+
+// maskMiddle replaces the letters and digits of value with *, but the ones among its first keepStart and last
+// keepEnd characters
+func maskMiddle(value string, keepStart, keepEnd int) string {
+	runes := []rune(value)
+	for i, r := range runes {
+		if i >= keepStart && i < len(runes)-keepEnd && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
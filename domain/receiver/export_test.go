@@ -0,0 +1,196 @@
+package receiver
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestService_ExportReceivers(t *testing.T) {
+	receivers := []dtos.GetReceiverResponse{
+		{
+			Id:       uuid.MustParse("9260c278-031f-4d2e-976e-b093dd0452fc"),
+			Name:     "Anthony Kieds",
+			Email:    "anthony@chilipeppers.com",
+			Document: "47155059080",
+			Pixkey:   "rhcp@chilipeppers.com",
+			PixType:  "email",
+			Status:   "valid",
+		},
+		{
+			Id:               uuid.MustParse("0f45db07-245f-47e1-8b0e-3b9a7905f082"),
+			Name:             "Flea, \"the bassist\"",
+			Document:         "11222333000181",
+			BankCode:         "341",
+			BankBranch:       "0123",
+			BankAccount:      "12345",
+			BankAccountDigit: "6",
+			BankAccountType:  "checking",
+			Status:           "draft",
+		},
+	}
+	repo := receiverRepoMock{ExportMock: func(query SearchQuery, filter Filter, each func(receiver *dtos.GetReceiverResponse) error) error {
+		if query.Text != "chili" {
+			return errors.New("unexpected query")
+		}
+		for i := range receivers {
+			if err := each(&receivers[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+	tests := []struct {
+		name            string
+		req             dtos.ExportReceiversRequest
+		want            string
+		wantContentType string
+		ExpectedErr     error
+	}{
+		{
+			name: "Should export every column as csv by default",
+			req:  dtos.ExportReceiversRequest{Query: "chili"},
+			want: "id,name,email,doc,pix_key_type,pix_key,bank_code,branch,account_number,account_digit,account_type,status\n" +
+				"9260c278-031f-4d2e-976e-b093dd0452fc,Anthony Kieds,anthony@chilipeppers.com,47155059080,email,rhcp@chilipeppers.com,,,,,,valid\n" +
+				"0f45db07-245f-47e1-8b0e-3b9a7905f082,\"Flea, \"\"the bassist\"\"\",,11222333000181,,,341,0123,12345,6,checking,draft\n",
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name: "Should export the chosen columns in their order as json lines",
+			req:  dtos.ExportReceiversRequest{Query: "chili", Format: "JSONL", Columns: "status, name"},
+			want: `{"status":"valid","name":"Anthony Kieds"}` + "\n" +
+				`{"status":"draft","name":"Flea, \"the bassist\""}` + "\n",
+			wantContentType: "application/x-ndjson",
+		},
+		{
+			name: "Should mask documents and pix keys",
+			req:  dtos.ExportReceiversRequest{Query: "chili", Columns: "doc,pix_key", Mask: true},
+			want: "doc,pix_key\n" +
+				"***.550.590-**,r***@chilipeppers.com\n" +
+				"**.222.333/0001-**,\n",
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:        "Should refuse an unknown column",
+			req:         dtos.ExportReceiversRequest{Columns: "name,phone"},
+			ExpectedErr: ErrInvalidExportColumn,
+		},
+		{
+			name:        "Should refuse a duplicated column",
+			req:         dtos.ExportReceiversRequest{Columns: "name,name"},
+			ExpectedErr: ErrInvalidExportColumn,
+		},
+		{
+			name:        "Should refuse an unknown format",
+			req:         dtos.ExportReceiversRequest{Format: "pdf"},
+			ExpectedErr: ErrInvalidExportFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{log: log.MockLogger{}, repo: repo}
+			export, err := s.ExportReceivers(tt.req, Filter{})
+			if !errors.Is(err, tt.ExpectedErr) {
+				t.Fatalf("ExportReceivers() error = %v, expectedErr %v", err, tt.ExpectedErr)
+			}
+			if err != nil {
+				return
+			}
+			if export.ContentType() != tt.wantContentType {
+				t.Errorf("ContentType() = %s, want %s", export.ContentType(), tt.wantContentType)
+			}
+			var buf bytes.Buffer
+			if err = export.Stream(&buf); err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Stream() got = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestExport_Stream_XLSX(t *testing.T) {
+	repo := receiverRepoMock{ExportMock: func(query SearchQuery, filter Filter, each func(receiver *dtos.GetReceiverResponse) error) error {
+		return each(&dtos.GetReceiverResponse{Name: "Chad <Smith> & Co", Document: "01234567890"})
+	}}
+	s := &Service{log: log.MockLogger{}, repo: repo}
+	export, err := s.ExportReceivers(dtos.ExportReceiversRequest{Format: ExportXLSX, Columns: "name,doc"}, Filter{})
+	if err != nil {
+		t.Fatalf("ExportReceivers() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err = export.Stream(&buf); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	workbook, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Stream() wrote an invalid zip: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range workbook.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", f.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll(%s) error = %v", f.Name, err)
+		}
+		parts[f.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Stream() workbook has no %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Chad &lt;Smith&gt; &amp; Co</t></is></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">01234567890</t></is></c></row>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("Stream() sheet = %s, want it to contain %s", sheet, want)
+		}
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("Stream() sheet is not closed: %s", sheet)
+	}
+}
+
+func Test_maskPixKey(t *testing.T) {
+	tests := []struct {
+		keyType vo.PixKeyType
+		key     string
+		want    string
+	}{
+		{vo.CPFKey, "471.550.590-80", "***.550.590-**"},
+		{vo.CNPJKey, "11222333000181", "**.222.333/0001-**"},
+		{vo.EmailKey, "rhcp@chilipeppers.com", "r***@chilipeppers.com"},
+		{vo.PhoneKey, "+5511987654321", "+55*******4321"},
+		{vo.RandomKey, "123e4567-e89b-12d3-a456-426614174000", "123e4567-****-****-****-************"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.keyType), func(t *testing.T) {
+			if got := maskPixKey(tt.keyType, tt.key); got != tt.want {
+				t.Errorf("maskPixKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_xlsxColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
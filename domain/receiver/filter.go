@@ -0,0 +1,185 @@
+package receiver
+
+import (
+	"fmt"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortField is a field the receivers can be sorted by
+type SortField string
+
+const (
+	SortCreatedAt SortField = "created_at"
+	SortName      SortField = "name"
+	SortStatus    SortField = "status"
+)
+
+// Sort orders the receivers by Field, ties are broken by id in the same direction. The zero value keeps the
+// default order, the creation time for lists and the relevance for searches
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ParseSort reads a sort field, prefixed by "-" for the descending order, as in "-created_at"
+func ParseSort(value string) (Sort, error) {
+	s := Sort{}
+	if strings.HasPrefix(value, "-") {
+		s.Desc = true
+		value = value[1:]
+	}
+	switch field := SortField(value); field {
+	case SortCreatedAt, SortName, SortStatus:
+		s.Field = field
+	default:
+		return Sort{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidFilter, value)
+	}
+	return s, nil
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + string(s.Field)
+	}
+	return string(s.Field)
+}
+
+// Filter narrows the receivers listed or searched, every field set must match. Statuses and PixKeyTypes match any
+// of their values, CreatedFrom and CreatedTo bound the creation time including both ends, and HasEmail asks for
+// receivers with or without an email. Condition holds a search query, as read by ParseQuery. The zero value
+// matches every receiver
+type Filter struct {
+	Statuses    []entity.UserStatus
+	PixKeyTypes []vo.PixKeyType
+	DocType     vo.DocType
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	HasEmail    *bool
+	Condition   *Condition
+	Sort        Sort
+}
+
+// QueryField is a field of a search query condition
+type QueryField string
+
+const (
+	// FieldAny matches the name, the email, the document or the pix key
+	FieldAny        QueryField = ""
+	FieldName       QueryField = "name"
+	FieldEmail      QueryField = "email"
+	FieldDocument   QueryField = "doc"
+	FieldStatus     QueryField = "status"
+	FieldPixKeyType QueryField = "pix"
+	FieldPixKey     QueryField = "key"
+)
+
+// Condition is either all of And, any of Or, or a term matching Value on Field, negated when Not is set. Text fields
+// contain the Value, ignoring case and accents, while the document, status and pix key type are equal to it. Prefix
+// matches the values starting with Value instead
+type Condition struct {
+	And    []Condition
+	Or     []Condition
+	Not    bool
+	Field  QueryField
+	Value  string
+	Prefix bool
+}
+
+// filterParams reads each of the query parameters accepted by NewFilter into the filter
+var filterParams = map[string]func(f *Filter, value string) error{
+	"status": func(f *Filter, value string) error {
+		status, err := entity.ParseUserStatus(value)
+		if err != nil {
+			return err
+		}
+		f.Statuses = append(f.Statuses, status)
+		return nil
+	},
+	"pix_key_type": func(f *Filter, value string) error {
+		switch keyType := vo.PixKeyType(strings.ToLower(value)); keyType {
+		case vo.CPFKey, vo.CNPJKey, vo.EmailKey, vo.PhoneKey, vo.RandomKey:
+			f.PixKeyTypes = append(f.PixKeyTypes, keyType)
+			return nil
+		}
+		return fmt.Errorf("unknown pix key type %q", value)
+	},
+	"doc_type": func(f *Filter, value string) error {
+		switch docType := vo.DocType(strings.ToLower(value)); docType {
+		case vo.CPF, vo.CNPJ:
+			f.DocType = docType
+			return nil
+		}
+		return fmt.Errorf("unknown document type %q", value)
+	},
+	"created_from": func(f *Filter, value string) (err error) {
+		f.CreatedFrom, err = parseFilterTime(value, false)
+		return err
+	},
+	"created_to": func(f *Filter, value string) (err error) {
+		f.CreatedTo, err = parseFilterTime(value, true)
+		return err
+	},
+	"has_email": func(f *Filter, value string) error {
+		hasEmail, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		f.HasEmail = &hasEmail
+		return nil
+	},
+	"sort": func(f *Filter, value string) (err error) {
+		f.Sort, err = ParseSort(value)
+		return err
+	},
+}
+
+// NewFilter reads the filter from the query parameters, lists are either comma separated or repeated, as in
+// status=draft,valid, and sort takes a field prefixed by "-" for the descending order. Unknown parameters are
+// refused, so a misspelled filter doesn't silently list every receiver
+func NewFilter(params url.Values) (Filter, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	f := Filter{}
+	for _, key := range keys {
+		read, ok := filterParams[key]
+		if !ok {
+			return Filter{}, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, key)
+		}
+		for _, values := range params[key] {
+			for _, value := range strings.Split(values, ",") {
+				if err := read(&f, strings.TrimSpace(value)); err != nil {
+					return Filter{}, fmt.Errorf("%w: %s: %s", ErrInvalidFilter, key, err)
+				}
+			}
+		}
+	}
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && f.CreatedFrom.After(f.CreatedTo) {
+		return Filter{}, fmt.Errorf("%w: created_from is after created_to", ErrInvalidFilter)
+	}
+	return f, nil
+}
+
+// parseFilterTime reads a RFC 3339 time or a date, which ends the range at the end of the day when endOfDay is set.
+// Times are converted to UTC, as the creation times are stored in UTC without their offset
+func parseFilterTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date or a RFC 3339 time, got %q", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+	return t, nil
+}
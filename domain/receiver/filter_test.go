@@ -0,0 +1,118 @@
+package receiver
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewFilter(t *testing.T) {
+	withEmail, withoutEmail := true, false
+	tests := []struct {
+		name        string
+		params      string
+		want        Filter
+		ExpectedErr error
+	}{
+		{
+			name:   "Should match every receiver without params",
+			params: "",
+			want:   Filter{},
+		},
+		{
+			name:   "Should read comma separated and repeated lists",
+			params: "status=draft,valid&status=BLOCKED&pix_key_type=email&pix_key_type=random_key",
+			want: Filter{
+				Statuses:    []entity.UserStatus{entity.Draft, entity.Valid, entity.Blocked},
+				PixKeyTypes: []vo.PixKeyType{vo.EmailKey, vo.RandomKey},
+			},
+		},
+		{
+			name:   "Should read the document type, the email presence and a descending sort",
+			params: "doc_type=cnpj&has_email=false&sort=-name",
+			want:   Filter{DocType: vo.CNPJ, HasEmail: &withoutEmail, Sort: Sort{Field: SortName, Desc: true}},
+		},
+		{
+			name:   "Should end a creation range given by date at the end of the day",
+			params: "created_from=2023-02-01&created_to=2023-02-28&has_email=true",
+			want: Filter{
+				CreatedFrom: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+				CreatedTo:   time.Date(2023, 2, 28, 23, 59, 59, 999999000, time.UTC),
+				HasEmail:    &withEmail,
+			},
+		},
+		{
+			name:   "Should read a creation range given by time",
+			params: "created_from=2023-02-01T10:00:00Z",
+			want:   Filter{CreatedFrom: time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "Should convert a creation range given with an offset to UTC",
+			params: "created_from=2024-01-01T00:00:00-03:00",
+			want:   Filter{CreatedFrom: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:        "Should refuse an unknown field",
+			params:      "status=draft&colour=blue",
+			ExpectedErr: ErrInvalidFilter,
+		},
+		{
+			name:        "Should refuse an unknown status",
+			params:      "status=paid",
+			ExpectedErr: ErrInvalidFilter,
+		},
+		{
+			name:        "Should refuse an unknown sort field",
+			params:      "sort=document",
+			ExpectedErr: ErrInvalidFilter,
+		},
+		{
+			name:        "Should refuse an invalid date",
+			params:      "created_to=yesterday",
+			ExpectedErr: ErrInvalidFilter,
+		},
+		{
+			name:        "Should refuse a range ending before its start",
+			params:      "created_from=2023-03-01&created_to=2023-02-01",
+			ExpectedErr: ErrInvalidFilter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewFilter(params)
+			if !errors.Is(err, tt.ExpectedErr) {
+				t.Fatalf("NewFilter() error = %v, expectedErr %v", err, tt.ExpectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewFilter() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	c := Cursor{
+		Sort:      Sort{Field: SortStatus, Desc: true},
+		CreatedAt: time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC),
+		Status:    entity.Blocked,
+		Id:        uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
+		Backward:  true,
+	}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil || got != c {
+		t.Errorf("DecodeCursor() = %+v, %v, want %+v", got, err, c)
+	}
+	legacy := "eyJ0IjoiMjAyMy0wMi0wMVQxMDowMDowMFoiLCJpIjoiNjI0YjI5MTMtZWNmMy00NDQ1LTliNjgtNTg4ZTQxMDM4NTkzIn0"
+	if got, err = DecodeCursor(legacy); err != nil || got.Sort != (Sort{Field: SortCreatedAt}) {
+		t.Errorf("DecodeCursor() = %+v, %v, want a cursor sorted by creation", got, err)
+	}
+}
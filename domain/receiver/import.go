@@ -0,0 +1,262 @@
+package receiver
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"github.com/lucasszmt/transfeera-challenge/utils"
+	"io"
+	"strings"
+)
+
+// importColumns maps the CSV header to the dtos.CreateReceiverRequest fields, using the same names as its json tags
+var importColumns = map[string]func(req *dtos.CreateReceiverRequest, value string){
+	"name":  func(req *dtos.CreateReceiverRequest, value string) { req.Name = value },
+	"email": func(req *dtos.CreateReceiverRequest, value string) { req.Email = value },
+	"doc":   func(req *dtos.CreateReceiverRequest, value string) { req.Doc = value },
+	"pix_key_type": func(req *dtos.CreateReceiverRequest, value string) {
+		req.PixKeyType = vo.PixKeyType(strings.ToLower(value))
+	},
+	"pix_key":        func(req *dtos.CreateReceiverRequest, value string) { req.PixKey = value },
+	"bank_code":      func(req *dtos.CreateReceiverRequest, value string) { bankAccount(req).BankCode = value },
+	"branch":         func(req *dtos.CreateReceiverRequest, value string) { bankAccount(req).Branch = value },
+	"account_number": func(req *dtos.CreateReceiverRequest, value string) { bankAccount(req).Number = value },
+	"account_digit":  func(req *dtos.CreateReceiverRequest, value string) { bankAccount(req).Digit = value },
+	"account_type": func(req *dtos.CreateReceiverRequest, value string) {
+		bankAccount(req).AccountType = vo.AccountType(strings.ToLower(value))
+	},
+}
+
+var requiredImportColumns = []string{"name", "doc"}
+
+// statuses of the rows of an import report
+const (
+	ImportCreated = "created"
+	ImportValid   = "valid"
+	ImportInvalid = "invalid"
+	ImportSkipped = "skipped"
+)
+
+// ImportRow is a receiver read from a line of an import file, Err holds what made the line unreadable
+type ImportRow struct {
+	Line    int
+	Request dtos.CreateReceiverRequest
+	Err     error
+}
+
+// CSVImport reads receivers out of a CSV file whose header names the dtos.CreateReceiverRequest fields, the bank
+// account fields included, in any order. Both comma and semicolon separated files are accepted, as spreadsheets
+// exported with the brazilian locale use the latter
+type CSVImport struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func NewCSVImport(r io.Reader) (*CSVImport, error) {
+	buffered := bufio.NewReader(r)
+	firstLine, err := buffered.Peek(buffered.Size())
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if i := strings.IndexByte(string(firstLine), '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if strings.Count(string(firstLine), ";") > strings.Count(string(firstLine), ",") {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImportFile)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+	}
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if _, ok := importColumns[column]; !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImportFile, column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: duplicated column %q", ErrInvalidImportFile, column)
+		}
+		seen[column] = true
+		columns[i] = column
+	}
+	for _, column := range requiredImportColumns {
+		if !seen[column] {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidImportFile, column)
+		}
+	}
+	return &CSVImport{reader: reader, columns: columns}, nil
+}
+
+// Next returns the receiver of the next line of the file, or io.EOF after the last one. Malformed lines are
+// returned with their Err set, so the reading can go on
+func (i *CSVImport) Next() (*ImportRow, error) {
+	record, err := i.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &ImportRow{Line: parseErr.StartLine, Err: fmt.Errorf("%w: %s", ErrInvalidImportRow, parseErr.Err)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	line, _ := i.reader.FieldPos(0)
+	row := &ImportRow{Line: line}
+	if len(record) != len(i.columns) {
+		row.Err = fmt.Errorf("%w: expected %d fields but got %d", ErrInvalidImportRow, len(i.columns), len(record))
+		return row, nil
+	}
+	for j, value := range record {
+		importColumns[i.columns[j]](&row.Request, strings.TrimSpace(value))
+	}
+	if account := row.Request.BankAccount; account != nil && *account == (dtos.BankAccountRequest{}) {
+		row.Request.BankAccount = nil
+	}
+	return row, nil
+}
+
+func bankAccount(req *dtos.CreateReceiverRequest) *dtos.BankAccountRequest {
+	if req.BankAccount == nil {
+		req.BankAccount = &dtos.BankAccountRequest{}
+	}
+	return req.BankAccount
+}
+
+// ImportReceivers creates the receivers of a CSV file and reports the outcome of every row. Rows are validated as
+// CreateReceiver does, and rows repeating the pix key, or the document under the reject policy, of a previous row
+// are refused. Each row is stored on its own unless the import is all or nothing, and nothing is stored on dry runs
+func (s *Service) ImportReceivers(audit dtos.Audit, file io.Reader, req dtos.ImportReceiversRequest) (*dtos.ImportReport, error) {
+	rows, err := NewCSVImport(file)
+	if err != nil {
+		return nil, err
+	}
+	report := &dtos.ImportReport{DryRun: req.DryRun, AllOrNothing: req.AllOrNothing, Rows: []dtos.ImportRowResult{}}
+	repeated := newRepeatedRows(s.uniqueDocument())
+	var batch []*entity.Receiver
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+		}
+		result := dtos.ImportRowResult{Line: row.Line}
+		rcv, err := s.importRow(row, repeated)
+		switch {
+		case err != nil:
+			result.Status, result.Error = ImportInvalid, err.Error()
+		case req.DryRun:
+			result.Status = ImportValid
+		case req.AllOrNothing:
+			result.Status = ImportSkipped
+			batch = append(batch, rcv)
+		default:
+			s.storeImportRow(audit, rcv, &result)
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	if req.AllOrNothing && !req.DryRun && len(batch) > 0 && len(batch) == len(report.Rows) {
+		if err = s.storeImportBatch(audit, batch, report.Rows); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range report.Rows {
+		switch row.Status {
+		case ImportCreated:
+			report.Created++
+		case ImportInvalid:
+			report.Failed++
+		}
+	}
+	report.Total = len(report.Rows)
+	return report, nil
+}
+
+func (s *Service) importRow(row *ImportRow, repeated *repeatedRows) (*entity.Receiver, error) {
+	if row.Err != nil {
+		return nil, row.Err
+	}
+	if err := utils.ValidateStruct(row.Request); err != nil {
+		return nil, err
+	}
+	rcv, err := s.newReceiver(row.Request)
+	if err != nil {
+		return nil, err
+	}
+	return rcv, repeated.check(row.Line, rcv)
+}
+
+func (s *Service) storeImportRow(audit dtos.Audit, rcv *entity.Receiver, result *dtos.ImportRowResult) {
+	if _, err := s.repo.Create(audit, rcv, s.uniqueDocument()); err != nil {
+		s.log.Error(fmt.Sprintf("error importing the receiver of the line %d", result.Line), err)
+		result.Status, result.Error = ImportInvalid, err.Error()
+		return
+	}
+	id := rcv.Id()
+	result.Status, result.Id = ImportCreated, &id
+}
+
+// storeImportBatch creates the receivers of an all or nothing import, results holds a row for each of them. When
+// one of them fails its row is the only one reported as invalid, the others stay skipped
+func (s *Service) storeImportBatch(audit dtos.Audit, batch []*entity.Receiver, results []dtos.ImportRowResult) error {
+	err := s.repo.CreateBatch(audit, batch, s.uniqueDocument())
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		results[batchErr.Index].Status, results[batchErr.Index].Error = ImportInvalid, batchErr.Err.Error()
+		return nil
+	}
+	if err != nil {
+		s.log.Error("error importing receivers", err)
+		return err
+	}
+	for i, rcv := range batch {
+		id := rcv.Id()
+		results[i].Status, results[i].Id = ImportCreated, &id
+	}
+	return nil
+}
+
+// repeatedRows remembers the pix keys, and documents when they must be unique, of the rows already read
+type repeatedRows struct {
+	pixKeys   map[string]int
+	documents map[string]int
+}
+
+func newRepeatedRows(uniqueDocument bool) *repeatedRows {
+	r := &repeatedRows{pixKeys: map[string]int{}}
+	if uniqueDocument {
+		r.documents = map[string]int{}
+	}
+	return r
+}
+
+func (r *repeatedRows) check(line int, rcv *entity.Receiver) error {
+	if key := rcv.PixKey(); key != nil {
+		if previous, ok := r.pixKeys[key.Value()]; ok {
+			return fmt.Errorf("%w: pix key repeated from line %d", ErrReceiverAlreadyExists, previous)
+		}
+		r.pixKeys[key.Value()] = line
+	}
+	if r.documents != nil {
+		if previous, ok := r.documents[rcv.Doc()]; ok {
+			return fmt.Errorf("%w: document repeated from line %d", ErrReceiverAlreadyExists, previous)
+		}
+		r.documents[rcv.Doc()] = line
+	}
+	return nil
+}
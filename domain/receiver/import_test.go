@@ -0,0 +1,220 @@
+package receiver
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCSVImport(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		want        []ImportRow
+		ExpectedErr error
+	}{
+		{
+			name: "Should read the rows of a comma separated file",
+			file: "name,doc,pix_key_type,pix_key\nAnthony Kieds,471.550.590-80,CPF,471.550.590-80\n",
+			want: []ImportRow{{Line: 2, Request: dtos.CreateReceiverRequest{
+				Name:       "Anthony Kieds",
+				Doc:        "471.550.590-80",
+				PixKeyType: vo.CPFKey,
+				PixKey:     "471.550.590-80",
+			}}},
+		},
+		{
+			name: "Should read a semicolon separated file with a byte order mark and a bank account",
+			file: "\ufeffName; doc; bank_code; branch; account_number; account_digit; account_type\n" +
+				"Chad Smith; 419.267.660-59; 341; 0123; 12345; 6; checking\n\n" +
+				"Flea; 471.550.590-80; ; ; ; ;\n",
+			want: []ImportRow{
+				{Line: 2, Request: dtos.CreateReceiverRequest{
+					Name: "Chad Smith",
+					Doc:  "419.267.660-59",
+					BankAccount: &dtos.BankAccountRequest{
+						BankCode:    "341",
+						Branch:      "0123",
+						Number:      "12345",
+						Digit:       "6",
+						AccountType: vo.CheckingAccount,
+					},
+				}},
+				{Line: 4, Request: dtos.CreateReceiverRequest{Name: "Flea", Doc: "471.550.590-80"}},
+			},
+		},
+		{
+			name: "Should report a line with missing fields and go on",
+			file: "name,doc\nFlea\nChad Smith,419.267.660-59\n",
+			want: []ImportRow{
+				{Line: 2, Err: ErrInvalidImportRow},
+				{Line: 3, Request: dtos.CreateReceiverRequest{Name: "Chad Smith", Doc: "419.267.660-59"}},
+			},
+		},
+		{
+			name:        "Should refuse an unknown column",
+			file:        "name,doc,phone\n",
+			ExpectedErr: ErrInvalidImportFile,
+		},
+		{
+			name:        "Should refuse a file without the document column",
+			file:        "name,email\n",
+			ExpectedErr: ErrInvalidImportFile,
+		},
+		{
+			name:        "Should refuse an empty file",
+			file:        "",
+			ExpectedErr: ErrInvalidImportFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := NewCSVImport(strings.NewReader(tt.file))
+			if !errors.Is(err, tt.ExpectedErr) {
+				t.Fatalf("NewCSVImport() error = %v, expectedErr %v", err, tt.ExpectedErr)
+			}
+			if err != nil {
+				return
+			}
+			var got []ImportRow
+			for {
+				row, err := rows.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				if !errors.Is(row.Err, tt.want[len(got)].Err) {
+					t.Errorf("Next() row error = %v, want %v", row.Err, tt.want[len(got)].Err)
+				}
+				row.Err = tt.want[len(got)].Err
+				got = append(got, *row)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_ImportReceivers(t *testing.T) {
+	const file = "name,doc,pix_key_type,pix_key\n" +
+		"Anthony Kieds,471.550.590-80,cpf,471.550.590-80\n" +
+		"Flea,471550580,cpf,471.550.590-80\n" +
+		"Chad Smith,419.267.660-59,cpf,419.267.660-59\n"
+	created := func(receivers []*entity.Receiver) error { return nil }
+	statuses := func(report *dtos.ImportReport) []string {
+		var got []string
+		for _, row := range report.Rows {
+			got = append(got, row.Status)
+		}
+		return got
+	}
+	tests := []struct {
+		name        string
+		file        string
+		req         dtos.ImportReceiversRequest
+		repo        receiverRepoMock
+		want        []string
+		wantCreated int
+		wantFailed  int
+	}{
+		{
+			name: "Should create the valid rows and report the invalid ones",
+			file: file,
+			repo: receiverRepoMock{CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
+				return receiver, nil
+			}},
+			want:        []string{ImportCreated, ImportInvalid, ImportCreated},
+			wantCreated: 2,
+			wantFailed:  1,
+		},
+		{
+			name: "Should report the rows refused by the repo",
+			file: file,
+			repo: receiverRepoMock{CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
+				if receiver.Doc() == "41926766059" {
+					return nil, &DuplicateReceiverError{ExistingID: uuid.New(), Field: "pix key"}
+				}
+				return receiver, nil
+			}},
+			want:        []string{ImportCreated, ImportInvalid, ImportInvalid},
+			wantCreated: 1,
+			wantFailed:  2,
+		},
+		{
+			name:       "Should only validate the rows on a dry run",
+			file:       file,
+			req:        dtos.ImportReceiversRequest{DryRun: true},
+			repo:       receiverRepoMock{Err: errors.New("dry runs must not store receivers")},
+			want:       []string{ImportValid, ImportInvalid, ImportValid},
+			wantFailed: 1,
+		},
+		{
+			name:       "Should not create any row of an all or nothing import with an invalid row",
+			file:       file,
+			req:        dtos.ImportReceiversRequest{AllOrNothing: true},
+			repo:       receiverRepoMock{Err: errors.New("invalid imports must not store receivers")},
+			want:       []string{ImportSkipped, ImportInvalid, ImportSkipped},
+			wantFailed: 1,
+		},
+		{
+			name:        "Should create every row of a valid all or nothing import",
+			file:        "name,doc,pix_key_type,pix_key\nAnthony Kieds,471.550.590-80,cpf,471.550.590-80\n",
+			req:         dtos.ImportReceiversRequest{AllOrNothing: true},
+			repo:        receiverRepoMock{CreateBatchMock: created},
+			want:        []string{ImportCreated},
+			wantCreated: 1,
+		},
+		{
+			name: "Should report the row that made an all or nothing import fail",
+			file: "name,doc,pix_key_type,pix_key\n" +
+				"Anthony Kieds,471.550.590-80,cpf,471.550.590-80\n" +
+				"Chad Smith,419.267.660-59,cpf,419.267.660-59\n",
+			req: dtos.ImportReceiversRequest{AllOrNothing: true},
+			repo: receiverRepoMock{CreateBatchMock: func(receivers []*entity.Receiver) error {
+				return &BatchError{Index: 1, Err: ErrReceiverAlreadyExists}
+			}},
+			want:       []string{ImportSkipped, ImportInvalid},
+			wantFailed: 1,
+		},
+		{
+			name: "Should refuse a pix key repeated in the file",
+			file: "name,doc,pix_key_type,pix_key\n" +
+				"Anthony Kieds,471.550.590-80,email,rhcp@chilipeppers.com\n" +
+				"Chad Smith,419.267.660-59,email,rhcp@chilipeppers.com\n",
+			req:        dtos.ImportReceiversRequest{DryRun: true},
+			want:       []string{ImportValid, ImportInvalid},
+			wantFailed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{log: log.MockLogger{}, repo: tt.repo}
+			report, err := s.ImportReceivers(dtos.Audit{}, strings.NewReader(tt.file), tt.req)
+			if err != nil {
+				t.Fatalf("ImportReceivers() error = %v", err)
+			}
+			if got := statuses(report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ImportReceivers() statuses = %v, want %v", got, tt.want)
+			}
+			if report.Total != len(tt.want) || report.Created != tt.wantCreated || report.Failed != tt.wantFailed {
+				t.Errorf("ImportReceivers() total = %d, created = %d, failed = %d, want %d, %d, %d",
+					report.Total, report.Created, report.Failed, len(tt.want), tt.wantCreated, tt.wantFailed)
+			}
+			for _, row := range report.Rows {
+				if (row.Status == ImportCreated) != (row.Id != nil) {
+					t.Errorf("ImportReceivers() line %d is %s with id %v", row.Line, row.Status, row.Id)
+				}
+			}
+		})
+	}
+}
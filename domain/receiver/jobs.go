@@ -0,0 +1,237 @@
+package receiver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"io"
+	"time"
+)
+
+// statuses of an import job
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+const (
+	// ImportChunkSize is how many rows are stored at once, along with the progress of the job
+	ImportChunkSize = 500
+	// StaleImportJob is how long a running job goes without progress before it's taken as abandoned, by a server
+	// that stopped in the middle of it, and resumed
+	StaleImportJob = 2 * time.Minute
+)
+
+// ImportJob is an import running in the background, Line is the last line of the file already processed, the
+// job resumes from the next one. Claim identifies the worker running the job, every change of the job checks it
+type ImportJob struct {
+	Id           uuid.UUID
+	Claim        uuid.UUID
+	DryRun       bool
+	AllOrNothing bool
+	File         []byte
+	Audit        dtos.Audit
+	Line         int
+	Total        int
+	Processed    int
+	Created      int
+	Failed       int
+}
+
+// ImportChunkRow is a row of an import job, Receiver is set for the valid rows and Err for the invalid ones
+type ImportChunkRow struct {
+	Line     int
+	Receiver *entity.Receiver
+	Err      error
+}
+
+// Importer runs CSV imports as background jobs, so large files don't have to be imported within a request.
+// Jobs and their progress are stored, a job stopped in the middle, by a restart of the server, is resumed from
+// the last stored chunk
+type Importer struct {
+	log     log.Logger
+	service *Service
+	jobs    ImportJobRepository
+	wake    chan struct{}
+}
+
+func NewImporter(log log.Logger, service *Service, jobs ImportJobRepository) *Importer {
+	return &Importer{log: log, service: service, jobs: jobs, wake: make(chan struct{}, 1)}
+}
+
+// SubmitImport checks the file header and queues the import, the rows are validated by the job
+func (i *Importer) SubmitImport(audit dtos.Audit, file []byte, req dtos.ImportReceiversRequest) (*dtos.ImportJobResponse, error) {
+	rows, err := NewCSVImport(bytes.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for {
+		if _, err = rows.Next(); errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+		}
+		total++
+	}
+	job := &ImportJob{
+		Id:           uuid.New(),
+		DryRun:       req.DryRun,
+		AllOrNothing: req.AllOrNothing,
+		File:         file,
+		Audit:        audit,
+		Total:        total,
+	}
+	if err = i.jobs.CreateImportJob(job); err != nil {
+		i.log.Error("error creating an import job", err)
+		return nil, err
+	}
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+	return i.jobs.GetImportJob(job.Id)
+}
+
+func (i *Importer) ImportJob(id string) (*dtos.ImportJobResponse, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	return i.jobs.GetImportJob(parsedID)
+}
+
+// ImportJobErrors returns the invalid rows of the job processed so far
+func (i *Importer) ImportJobErrors(id string) ([]dtos.ImportJobError, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	if _, err = i.jobs.GetImportJob(parsedID); err != nil {
+		return nil, err
+	}
+	return i.jobs.ImportJobErrors(parsedID)
+}
+
+// Run processes the import jobs one after the other, looking for new ones every interval or as soon as one
+// is submitted. It never returns, so it's meant to run in its own goroutine
+func (i *Importer) Run(interval time.Duration) {
+	for {
+		for i.RunNext() {
+		}
+		select {
+		case <-i.wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunNext claims a job and runs it, reporting whether there was one
+func (i *Importer) RunNext() bool {
+	job, err := i.jobs.ClaimImportJob(uuid.New(), time.Now().Add(-StaleImportJob))
+	if err != nil {
+		i.log.Error("error claiming an import job", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+	if job.Line > 0 {
+		i.log.Info(fmt.Sprintf("resuming the import job %s after the line %d", job.Id, job.Line))
+	}
+	if err = i.run(job); err != nil {
+		i.log.Error(fmt.Sprintf("error running the import job %s", job.Id), err)
+		if errors.Is(err, ErrImportJobReclaimed) {
+			return true
+		}
+		if err = i.jobs.FailImportJob(job, err.Error()); err != nil {
+			i.log.Error(fmt.Sprintf("error failing the import job %s", job.Id), err)
+		}
+	}
+	return true
+}
+
+// run validates the rows of the job in chunks, creating their receivers along unless the job is a dry run or all
+// or nothing, in which case they are only created at the end when every row is valid. The rows before the job
+// line are read again, without being stored, so rows repeating previous ones are still detected
+func (i *Importer) run(job *ImportJob) error {
+	rows, err := NewCSVImport(bytes.NewReader(job.File))
+	if err != nil {
+		return err
+	}
+	create := !job.DryRun && !job.AllOrNothing
+	repeated := newRepeatedRows(i.service.uniqueDocument())
+	chunk := make([]ImportChunkRow, 0, ImportChunkSize)
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rcv, err := i.service.importRow(row, repeated)
+		if row.Line <= job.Line {
+			continue
+		}
+		chunk = append(chunk, ImportChunkRow{Line: row.Line, Receiver: rcv, Err: err})
+		if len(chunk) == ImportChunkSize {
+			if err = i.jobs.SaveImportChunk(job, chunk, create, i.service.uniqueDocument()); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if len(chunk) > 0 {
+		if err = i.jobs.SaveImportChunk(job, chunk, create, i.service.uniqueDocument()); err != nil {
+			return err
+		}
+	}
+	if !job.AllOrNothing || job.DryRun || job.Failed > 0 {
+		return i.jobs.FinishImportJob(job, nil, false)
+	}
+	return i.finishAllOrNothing(job)
+}
+
+// finishAllOrNothing creates every receiver of a job whose rows are all valid, when one of them can't be created
+// its row is recorded as the only error of the job
+func (i *Importer) finishAllOrNothing(job *ImportJob) error {
+	rows, err := NewCSVImport(bytes.NewReader(job.File))
+	if err != nil {
+		return err
+	}
+	repeated := newRepeatedRows(i.service.uniqueDocument())
+	var receivers []*entity.Receiver
+	var lines []int
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rcv, err := i.service.importRow(row, repeated)
+		if err != nil {
+			return fmt.Errorf("line %d is no longer valid: %w", row.Line, err)
+		}
+		receivers = append(receivers, rcv)
+		lines = append(lines, row.Line)
+	}
+	err = i.jobs.FinishImportJob(job, receivers, i.service.uniqueDocument())
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		return err
+	}
+	if err = i.jobs.SaveImportError(job, lines[batchErr.Index], batchErr.Err); err != nil {
+		return err
+	}
+	return i.jobs.FinishImportJob(job, nil, false)
+}
@@ -0,0 +1,229 @@
+package receiver
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// importJobsMock runs a single job, keeping what the importer stored for it
+type importJobsMock struct {
+	job       *ImportJob
+	createErr func(rcv *entity.Receiver) error
+	finishErr error
+	status    string
+	created   []int
+	errors    map[int]string
+	finished  []*entity.Receiver
+}
+
+func (m *importJobsMock) CreateImportJob(job *ImportJob) error {
+	m.job, m.status = job, JobQueued
+	return nil
+}
+
+func (m *importJobsMock) GetImportJob(id uuid.UUID) (*dtos.ImportJobResponse, error) {
+	if m.job == nil || m.job.Id != id {
+		return nil, ErrImportJobNotFound
+	}
+	return &dtos.ImportJobResponse{Id: id, Status: m.status, Total: m.job.Total}, nil
+}
+
+func (m *importJobsMock) ImportJobErrors(id uuid.UUID) ([]dtos.ImportJobError, error) {
+	return nil, nil
+}
+
+func (m *importJobsMock) ClaimImportJob(claim uuid.UUID, staleBefore time.Time) (*ImportJob, error) {
+	if m.job == nil || m.status != JobQueued {
+		return nil, nil
+	}
+	m.job.Claim, m.status = claim, JobRunning
+	return m.job, nil
+}
+
+func (m *importJobsMock) SaveImportChunk(job *ImportJob, rows []ImportChunkRow, create bool, uniqueDocument bool) error {
+	for _, row := range rows {
+		if row.Err == nil && create && m.createErr != nil {
+			row.Err = m.createErr(row.Receiver)
+		}
+		switch {
+		case row.Err != nil:
+			m.errors[row.Line] = row.Err.Error()
+			job.Failed++
+		case create:
+			m.created = append(m.created, row.Line)
+			job.Created++
+		}
+		job.Line = row.Line
+		job.Processed++
+	}
+	return nil
+}
+
+func (m *importJobsMock) FinishImportJob(job *ImportJob, receivers []*entity.Receiver, uniqueDocument bool) error {
+	if len(receivers) > 0 && m.finishErr != nil {
+		return m.finishErr
+	}
+	m.finished = receivers
+	job.Created += len(receivers)
+	m.status = JobCompleted
+	return nil
+}
+
+func (m *importJobsMock) SaveImportError(job *ImportJob, line int, err error) error {
+	m.errors[line] = err.Error()
+	job.Failed++
+	return nil
+}
+
+func (m *importJobsMock) FailImportJob(job *ImportJob, reason string) error {
+	m.status = JobFailed
+	return nil
+}
+
+func TestImporter_RunNext(t *testing.T) {
+	const file = "name,doc,pix_key_type,pix_key\n" +
+		"Anthony Kieds,471.550.590-80,cpf,471.550.590-80\n" +
+		"Flea,471550580,cpf,471.550.590-80\n" +
+		"Chad Smith,419.267.660-59,email,rhcp@chilipeppers.com\n" +
+		"John Frusciante,936.396.920-04,email,rhcp@chilipeppers.com\n"
+	tests := []struct {
+		name        string
+		req         dtos.ImportReceiversRequest
+		resumeAfter int
+		jobs        *importJobsMock
+		wantStatus  string
+		wantCreated []int
+		wantErrors  []int
+	}{
+		{
+			name:        "Should create the valid rows and record the invalid ones",
+			jobs:        &importJobsMock{},
+			wantStatus:  JobCompleted,
+			wantCreated: []int{2, 4},
+			wantErrors:  []int{3, 5},
+		},
+		{
+			name: "Should record the rows refused by the repo",
+			jobs: &importJobsMock{createErr: func(rcv *entity.Receiver) error {
+				if rcv.Doc() == "41926766059" {
+					return ErrReceiverAlreadyExists
+				}
+				return nil
+			}},
+			wantStatus:  JobCompleted,
+			wantCreated: []int{2},
+			wantErrors:  []int{3, 4, 5},
+		},
+		{
+			name:        "Should resume after the last processed line, still refusing rows repeating previous ones",
+			resumeAfter: 4,
+			jobs:        &importJobsMock{},
+			wantStatus:  JobCompleted,
+			wantErrors:  []int{5},
+		},
+		{
+			name:       "Should not create any row on a dry run",
+			req:        dtos.ImportReceiversRequest{DryRun: true},
+			jobs:       &importJobsMock{createErr: func(rcv *entity.Receiver) error { return errors.New("unexpected create") }},
+			wantStatus: JobCompleted,
+			wantErrors: []int{3, 5},
+		},
+		{
+			name:       "Should not create any row of an all or nothing import with an invalid row",
+			req:        dtos.ImportReceiversRequest{AllOrNothing: true},
+			jobs:       &importJobsMock{},
+			wantStatus: JobCompleted,
+			wantErrors: []int{3, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.jobs.errors = map[int]string{}
+			importer := NewImporter(log.MockLogger{}, &Service{log: log.MockLogger{}}, tt.jobs)
+			job, err := importer.SubmitImport(dtos.Audit{Actor: "anthony"}, []byte(file), tt.req)
+			if err != nil {
+				t.Fatalf("SubmitImport() error = %v", err)
+			}
+			if job.Total != 4 {
+				t.Errorf("SubmitImport() total = %d, want 4", job.Total)
+			}
+			if tt.resumeAfter > 0 {
+				tt.jobs.job.Line, tt.jobs.job.Processed = tt.resumeAfter, tt.resumeAfter-1
+			}
+			if !importer.RunNext() {
+				t.Fatalf("RunNext() found no job")
+			}
+			if importer.RunNext() {
+				t.Errorf("RunNext() ran a finished job again")
+			}
+			if tt.jobs.status != tt.wantStatus {
+				t.Errorf("RunNext() status = %s, want %s", tt.jobs.status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(tt.jobs.created, tt.wantCreated) {
+				t.Errorf("RunNext() created lines = %v, want %v", tt.jobs.created, tt.wantCreated)
+			}
+			var gotErrors []int
+			for line := 2; line <= 5; line++ {
+				if _, ok := tt.jobs.errors[line]; ok {
+					gotErrors = append(gotErrors, line)
+				}
+			}
+			if !reflect.DeepEqual(gotErrors, tt.wantErrors) {
+				t.Errorf("RunNext() invalid lines = %v, want %v", gotErrors, tt.wantErrors)
+			}
+			if tt.jobs.job.Processed != 4 {
+				t.Errorf("RunNext() processed = %d, want 4", tt.jobs.job.Processed)
+			}
+		})
+	}
+}
+
+func TestImporter_RunNext_AllOrNothing(t *testing.T) {
+	const file = "name,doc,pix_key_type,pix_key\n" +
+		"Anthony Kieds,471.550.590-80,cpf,471.550.590-80\n" +
+		"Chad Smith,419.267.660-59,cpf,419.267.660-59\n"
+	tests := []struct {
+		name         string
+		jobs         *importJobsMock
+		wantFinished int
+		wantErrors   map[int]string
+	}{
+		{
+			name:         "Should create every row of a valid import when finishing it",
+			jobs:         &importJobsMock{},
+			wantFinished: 2,
+			wantErrors:   map[int]string{},
+		},
+		{
+			name:       "Should record the row that made the import fail",
+			jobs:       &importJobsMock{finishErr: &BatchError{Index: 1, Err: ErrReceiverAlreadyExists}},
+			wantErrors: map[int]string{3: ErrReceiverAlreadyExists.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.jobs.errors = map[int]string{}
+			importer := NewImporter(log.MockLogger{}, &Service{log: log.MockLogger{}}, tt.jobs)
+			_, err := importer.SubmitImport(dtos.Audit{}, []byte(file), dtos.ImportReceiversRequest{AllOrNothing: true})
+			if err != nil {
+				t.Fatalf("SubmitImport() error = %v", err)
+			}
+			importer.RunNext()
+			if tt.jobs.status != JobCompleted {
+				t.Errorf("RunNext() status = %s, want %s", tt.jobs.status, JobCompleted)
+			}
+			if len(tt.jobs.finished) != tt.wantFinished {
+				t.Errorf("RunNext() finished with %d receivers, want %d", len(tt.jobs.finished), tt.wantFinished)
+			}
+			if !reflect.DeepEqual(tt.jobs.errors, tt.wantErrors) {
+				t.Errorf("RunNext() errors = %v, want %v", tt.jobs.errors, tt.wantErrors)
+			}
+		})
+	}
+}
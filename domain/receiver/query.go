@@ -0,0 +1,323 @@
+package receiver
+
+import (
+	"fmt"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// the syntaxes of a search, the text is searched as typed and the query is read by ParseQuery
+const (
+	SyntaxText  = "text"
+	SyntaxQuery = "query"
+)
+
+// documentQueryRegexp matches a document once its mask is removed, alphanumeric CNPJs included
+var documentQueryRegexp = regexp.MustCompile(`^[0-9A-Z]+$`)
+
+// QuerySyntaxError points at the problem of a search query, Position counts the characters of the query from 1
+type QuerySyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", ErrInvalidQuery, e.Position, e.Message)
+}
+
+func (e *QuerySyntaxError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+type queryTokenKind int
+
+const (
+	tokenEnd queryTokenKind = iota
+	tokenTerm
+	tokenOr
+	tokenAnd
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// queryToken is an operator, a parenthesis or a term of a query, along with the positions of its parts
+type queryToken struct {
+	kind     queryTokenKind
+	pos      int
+	field    string
+	value    string
+	valuePos int
+	quoted   bool
+}
+
+// ParseQuery reads a search query into a condition. Terms are a value, optionally qualified by a field, as in
+// status:draft, pix:email, key:rhcp@chilipeppers.com, name:"maria silva", email:gmail or doc:084.125.359-52, and
+// unqualified values match the name, email, document or pix key. Values are matched ignoring case and accents, text
+// fields match when they contain the value and a trailing * matches the values starting with it, as in doc:123*.
+// Terms are all required unless joined by OR, and are negated by - or NOT, parentheses group them
+func ParseQuery(query string) (*Condition, error) {
+	tokens, err := lexQuery([]rune(query))
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == tokenEnd {
+		return nil, &QuerySyntaxError{Position: 1, Message: "empty query"}
+	}
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, &QuerySyntaxError{Position: t.pos, Message: "unexpected )"}
+	}
+	return &c, nil
+}
+
+func lexQuery(query []rune) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+	for i < len(query) {
+		r := query[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen, pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose, pos: i + 1})
+			i++
+		case r == '-':
+			if i+1 == len(query) || unicode.IsSpace(query[i+1]) || query[i+1] == ')' {
+				return nil, &QuerySyntaxError{Position: i + 1, Message: "nothing to negate after -"}
+			}
+			tokens = append(tokens, queryToken{kind: tokenNot, pos: i + 1})
+			i++
+		case r == '"':
+			phrase, next, err := lexPhrase(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokenTerm, pos: i + 1, value: phrase, valuePos: i + 2, quoted: true})
+			i = next
+		default:
+			start := i
+			for i < len(query) && !unicode.IsSpace(query[i]) && query[i] != '(' && query[i] != ')' && query[i] != '"' {
+				i++
+			}
+			word := string(query[start:i])
+			switch word {
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr, pos: start + 1})
+				continue
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd, pos: start + 1})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot, pos: start + 1})
+				continue
+			}
+			t := queryToken{kind: tokenTerm, pos: start + 1, value: word, valuePos: start + 1}
+			if colon := strings.IndexRune(word, ':'); colon >= 0 {
+				if colon == 0 {
+					return nil, &QuerySyntaxError{Position: start + 1, Message: "missing field before :"}
+				}
+				t.field = word[:colon]
+				t.value = word[colon+1:]
+				t.valuePos = start + len([]rune(word[:colon])) + 2
+				if len(t.value) == 0 {
+					if i == len(query) || query[i] != '"' {
+						return nil, &QuerySyntaxError{Position: t.valuePos, Message: fmt.Sprintf("missing value for %s", t.field)}
+					}
+					phrase, next, err := lexPhrase(query, i)
+					if err != nil {
+						return nil, err
+					}
+					t.value, t.quoted = phrase, true
+					t.valuePos = i + 2
+					i = next
+				}
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return append(tokens, queryToken{kind: tokenEnd, pos: len(query) + 1}), nil
+}
+
+// lexPhrase reads the phrase quoted at start, returning where the query goes on after its closing quote
+func lexPhrase(query []rune, start int) (string, int, error) {
+	for end := start + 1; end < len(query); end++ {
+		if query[end] != '"' {
+			continue
+		}
+		phrase := strings.TrimSpace(string(query[start+1 : end]))
+		if len(phrase) == 0 {
+			return "", 0, &QuerySyntaxError{Position: start + 1, Message: "empty phrase"}
+		}
+		return phrase, end + 1, nil
+	}
+	return "", 0, &QuerySyntaxError{Position: start + 1, Message: "unterminated quote"}
+}
+
+// queryParser reads the tokens by precedence, NOT binds the closest, then AND and then OR
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) advance() queryToken {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+func (p *queryParser) or() (Condition, error) {
+	first, err := p.and()
+	if err != nil {
+		return Condition{}, err
+	}
+	if p.peek().kind != tokenOr {
+		return first, nil
+	}
+	c := Condition{Or: []Condition{first}}
+	for p.peek().kind == tokenOr {
+		p.advance()
+		next, err := p.and()
+		if err != nil {
+			return Condition{}, err
+		}
+		c.Or = append(c.Or, next)
+	}
+	return c, nil
+}
+
+func (p *queryParser) and() (Condition, error) {
+	var conditions []Condition
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tokenAnd:
+			if len(conditions) == 0 {
+				return Condition{}, &QuerySyntaxError{Position: t.pos, Message: "missing condition before AND"}
+			}
+			p.advance()
+			if next := p.peek(); next.kind != tokenTerm && next.kind != tokenNot && next.kind != tokenOpen {
+				return Condition{}, &QuerySyntaxError{Position: next.pos, Message: "missing condition after AND"}
+			}
+			continue
+		case tokenOr, tokenClose, tokenEnd:
+			if len(conditions) == 0 {
+				return Condition{}, &QuerySyntaxError{Position: t.pos, Message: "missing condition"}
+			}
+			if len(conditions) == 1 {
+				return conditions[0], nil
+			}
+			return Condition{And: conditions}, nil
+		}
+		c, err := p.unary()
+		if err != nil {
+			return Condition{}, err
+		}
+		conditions = append(conditions, c)
+	}
+}
+
+func (p *queryParser) unary() (Condition, error) {
+	if p.peek().kind != tokenNot {
+		return p.primary()
+	}
+	p.advance()
+	c, err := p.unary()
+	if err != nil {
+		return Condition{}, err
+	}
+	c.Not = !c.Not
+	return c, nil
+}
+
+func (p *queryParser) primary() (Condition, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenOpen:
+		c, err := p.or()
+		if err != nil {
+			return Condition{}, err
+		}
+		if p.advance().kind != tokenClose {
+			return Condition{}, &QuerySyntaxError{Position: t.pos, Message: "missing ) for this ("}
+		}
+		return c, nil
+	case tokenTerm:
+		return queryTerm(t)
+	case tokenClose:
+		return Condition{}, &QuerySyntaxError{Position: t.pos, Message: "unexpected )"}
+	}
+	return Condition{}, &QuerySyntaxError{Position: t.pos, Message: "missing condition"}
+}
+
+// queryTerm checks the field and the value of a term, values of the fields with a fixed set of values are
+// written as the filters take them
+func queryTerm(t queryToken) (Condition, error) {
+	c := Condition{Field: QueryField(strings.ToLower(t.field)), Value: t.value}
+	if !t.quoted {
+		if star := strings.IndexRune(c.Value, '*'); star >= 0 {
+			if star != len(c.Value)-1 {
+				return Condition{}, &QuerySyntaxError{
+					Position: t.valuePos + len([]rune(c.Value[:star])),
+					Message:  "wildcards are only allowed at the end of a value",
+				}
+			}
+			if star == 0 {
+				return Condition{}, &QuerySyntaxError{Position: t.valuePos, Message: "missing prefix before *"}
+			}
+			c.Value, c.Prefix = c.Value[:star], true
+		}
+	}
+	invalid := func(message string) (Condition, error) {
+		return Condition{}, &QuerySyntaxError{Position: t.valuePos, Message: message}
+	}
+	switch c.Field {
+	case FieldAny, FieldName, FieldEmail, FieldPixKey:
+	case FieldStatus:
+		if c.Prefix {
+			return invalid("status doesn't accept wildcards")
+		}
+		status, err := entity.ParseUserStatus(c.Value)
+		if err != nil {
+			return invalid(fmt.Sprintf("unknown status %q", c.Value))
+		}
+		c.Value = status.String()
+	case FieldPixKeyType:
+		if c.Prefix {
+			return invalid("pix doesn't accept wildcards")
+		}
+		switch keyType := vo.PixKeyType(strings.ToLower(c.Value)); keyType {
+		case vo.CPFKey, vo.CNPJKey, vo.EmailKey, vo.PhoneKey, vo.RandomKey:
+			c.Value = string(keyType)
+		default:
+			return invalid(fmt.Sprintf("unknown pix key type %q", c.Value))
+		}
+	case FieldDocument:
+		c.Value = strings.ToUpper(strings.NewReplacer(".", "", "-", "", "/", "", " ", "").Replace(c.Value))
+		if !documentQueryRegexp.MatchString(c.Value) {
+			return invalid("documents only have digits and letters")
+		}
+	default:
+		return Condition{}, &QuerySyntaxError{
+			Position: t.pos,
+			Message:  fmt.Sprintf("unknown field %q, use doc, email, key, name, pix or status", t.field),
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,125 @@
+package receiver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		want         *Condition
+		wantPosition int
+	}{
+		{
+			name:  "Should require every term of the query",
+			query: `status:draft pix:EMAIL name:"maria silva" -doc:123.4*`,
+			want: &Condition{And: []Condition{
+				{Field: FieldStatus, Value: "draft"},
+				{Field: FieldPixKeyType, Value: "email"},
+				{Field: FieldName, Value: "maria silva"},
+				{Field: FieldDocument, Value: "1234", Prefix: true, Not: true},
+			}},
+		},
+		{
+			name:  "Should bind AND closer than OR",
+			query: `status:valid email:gmail OR status:blocked AND NOT key:rhcp*`,
+			want: &Condition{Or: []Condition{
+				{And: []Condition{{Field: FieldStatus, Value: "valid"}, {Field: FieldEmail, Value: "gmail"}}},
+				{And: []Condition{{Field: FieldStatus, Value: "blocked"}, {Field: FieldPixKey, Value: "rhcp", Prefix: true, Not: true}}},
+			}},
+		},
+		{
+			name:  "Should group and negate with parentheses",
+			query: `-(status:draft OR status:archived) "chili peppers" flea`,
+			want: &Condition{And: []Condition{
+				{Or: []Condition{{Field: FieldStatus, Value: "draft"}, {Field: FieldStatus, Value: "archived"}}, Not: true},
+				{Value: "chili peppers"},
+				{Value: "flea"},
+			}},
+		},
+		{
+			name:  "Should keep a single term and the hyphens inside values",
+			query: ` doc:084.125.359-52 `,
+			want:  &Condition{Field: FieldDocument, Value: "08412535952"},
+		},
+		{
+			name:         "Should point at an unknown field",
+			query:        `status:draft phone:1234`,
+			wantPosition: 14,
+		},
+		{
+			name:         "Should point at an unknown status",
+			query:        `status:paid`,
+			wantPosition: 8,
+		},
+		{
+			name:         "Should point at the opening quote left unterminated",
+			query:        `name:"maria silva`,
+			wantPosition: 6,
+		},
+		{
+			name:         "Should point at a wildcard out of the end of a value",
+			query:        `name:ma*ria`,
+			wantPosition: 8,
+		},
+		{
+			name:         "Should refuse wildcards on statuses",
+			query:        `status:dra*`,
+			wantPosition: 8,
+		},
+		{
+			name:         "Should point at a missing value",
+			query:        `flea name: chad`,
+			wantPosition: 11,
+		},
+		{
+			name:         "Should point at a dangling OR",
+			query:        `flea OR`,
+			wantPosition: 8,
+		},
+		{
+			name:         "Should point at an unclosed parenthesis",
+			query:        `(flea OR chad`,
+			wantPosition: 1,
+		},
+		{
+			name:         "Should point at an unexpected parenthesis",
+			query:        `flea) chad`,
+			wantPosition: 5,
+		},
+		{
+			name:         "Should point at a dangling negation",
+			query:        `flea -`,
+			wantPosition: 6,
+		},
+		{
+			name:         "Should refuse an empty query",
+			query:        `   `,
+			wantPosition: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.query)
+			if tt.wantPosition > 0 {
+				var syntaxErr *QuerySyntaxError
+				if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrInvalidQuery) {
+					t.Fatalf("ParseQuery() error = %v, want a syntax error", err)
+				}
+				if syntaxErr.Position != tt.wantPosition {
+					t.Errorf("ParseQuery() error = %v, want it at position %d", err, tt.wantPosition)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package receiver
+
+import (
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"golang.org/x/text/unicode/norm"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// documentSearchRegexp matches searches made only of digits and the characters of a document mask, as in
+// "084.125.359-52" or "11.222.333/0001-81"
+var documentSearchRegexp = regexp.MustCompile(`^\d[\d./ -]*$`)
+
+// SearchQuery is a receiver search prepared for the repository. Text is the search as typed, Terms its words
+// lowercased and without accents, and Document the digits of a search typed as a document, since documents are
+// stored without their masks
+type SearchQuery struct {
+	Text     string
+	Terms    []string
+	Document string
+}
+
+func NewSearchQuery(text string) SearchQuery {
+	text = strings.TrimSpace(text)
+	query := SearchQuery{
+		Text: text,
+		Terms: strings.FieldsFunc(fold(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}),
+	}
+	if documentSearchRegexp.MatchString(text) {
+		query.Document = strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, text)
+	}
+	return query
+}
+
+// TSQuery writes the terms as prefixes for to_tsquery, so "joão sil" becomes "joao:* & sil:*" and matches while
+// the name is still being typed
+func (q SearchQuery) TSQuery() string {
+	prefixes := make([]string, len(q.Terms))
+	for i, term := range q.Terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " & ")
+}
+
+// highlights marks where the search matched the receiver, by the name of the matched field. Fields found only
+// through trigram similarity, as a misspelled name, have nothing to be marked and are left out
+func (q SearchQuery) highlights(r *dtos.GetReceiverResponse) map[string]string {
+	found := map[string]string{}
+	for field, value := range map[string]string{
+		"Name":     r.Name,
+		"Email":    r.Email,
+		"Document": r.Document,
+		"Pixkey":   r.Pixkey,
+	} {
+		if marked, ok := highlight(value, q.Terms, q.Document); ok {
+			found[field] = marked
+		}
+	}
+	return found
+}
+
+// highlight wraps the parts of value matching the search in <mark>, comparing them lowercased and without accents,
+// so "joao" marks "João". Terms only match the start of words, as the prefix search does, while the digits of a
+// document match anywhere. The rest of value is html escaped, ok is false when nothing matched
+func highlight(value string, terms []string, digits string) (string, bool) {
+	runes := []rune(value)
+	var folded []rune
+	var owner []int
+	for i, r := range runes {
+		for _, f := range fold(string(r)) {
+			folded = append(folded, f)
+			owner = append(owner, i)
+		}
+	}
+	marked := make([]bool, len(runes))
+	ok := false
+	mark := func(term string, wordStart bool) {
+		t := []rune(term)
+		if len(t) == 0 {
+			return
+		}
+		for start := 0; start+len(t) <= len(folded); start++ {
+			if wordStart && start > 0 && (unicode.IsLetter(folded[start-1]) || unicode.IsDigit(folded[start-1])) {
+				continue
+			}
+			if string(folded[start:start+len(t)]) != term {
+				continue
+			}
+			for k := start; k < start+len(t); k++ {
+				marked[owner[k]] = true
+			}
+			ok = true
+		}
+	}
+	for _, term := range terms {
+		mark(term, true)
+	}
+	mark(digits, false)
+	if !ok {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(string(runes[i:j])) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(string(runes[i:j])))
+		}
+		i = j
+	}
+	return b.String(), true
+}
+
+// fold lowercases s and strips its accents, as f_unaccent(lower()) does on the database
+func fold(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, norm.NFD.String(s))
+}
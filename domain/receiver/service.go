@@ -1,29 +1,69 @@
 package receiver
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/brcode"
 	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
 	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
 	"github.com/lucasszmt/transfeera-challenge/infra/log"
+	"strings"
+	"time"
 )
 
+// DuplicateDocumentPolicy tells whether more than one receiver may share the same CPF or CNPJ
+type DuplicateDocumentPolicy string
+
+const (
+	AllowDuplicateDocuments  DuplicateDocumentPolicy = "allow"
+	RejectDuplicateDocuments DuplicateDocumentPolicy = "reject"
+)
+
+// ParseDuplicateDocumentPolicy reads a policy ignoring case, an empty value allows duplicates while any value other
+// than allow or reject is refused, so a misspelled policy doesn't silently allow them
+func ParseDuplicateDocumentPolicy(value string) (DuplicateDocumentPolicy, error) {
+	switch policy := DuplicateDocumentPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return AllowDuplicateDocuments, nil
+	case AllowDuplicateDocuments, RejectDuplicateDocuments:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown duplicate document policy %q, use allow or reject", value)
+}
+
+// Config holds the tenant wide rules applied by the receiver Service
+type Config struct {
+	// AllowThirdPartyPixKeys turns off the rule that CPF and CNPJ pix keys must be the receiver's own document
+	AllowThirdPartyPixKeys bool
+	// BRCodeCity is the merchant city written in BR Codes when the request doesn't provide one
+	BRCodeCity string
+	// DeletedRetention is how long soft deleted receivers are kept before being purged, zero disables the purge
+	DeletedRetention time.Duration
+	// DuplicateDocuments rejects, when set to RejectDuplicateDocuments, receivers with the document of another
+	// receiver not deleted, AllowDuplicateDocuments or the zero value allow them
+	DuplicateDocuments DuplicateDocumentPolicy
+}
+
 type Service struct {
-	log  log.Logger
-	repo Repository
+	log       log.Logger
+	repo      Repository
+	directory KeyDirectory
+	cfg       Config
 }
 
-func NewService(log log.Logger, repo Repository) *Service {
-	return &Service{log: log, repo: repo}
+func NewService(log log.Logger, repo Repository, directory KeyDirectory, cfg Config) *Service {
+	return &Service{log: log, repo: repo, directory: directory, cfg: cfg}
 }
 
-func (s *Service) CreateReceiver(r dtos.CreateReceiverRequest) (*entity.Receiver, error) {
-	rcv, err := entity.NewReceiver(r.Name, r.Email, r.Doc, r.PixKeyType, r.PixKey)
+func (s *Service) CreateReceiver(audit dtos.Audit, r dtos.CreateReceiverRequest) (*entity.Receiver, error) {
+	rcv, err := s.newReceiver(r)
 	if err != nil {
 		s.log.Error("error creating the a receiver", err)
 		return nil, err
 	}
-	rcv, err = s.repo.Create(rcv)
+	rcv, err = s.repo.Create(audit, rcv, s.uniqueDocument())
 	if err != nil {
 		s.log.Error("error creating the a receiver", err)
 		return nil, err
@@ -31,41 +71,149 @@ func (s *Service) CreateReceiver(r dtos.CreateReceiverRequest) (*entity.Receiver
 	return rcv, nil
 }
 
-func (s *Service) UpdateReceiver(req dtos.UpdateReceiverRequest) error {
-	if req.Status == "draft" {
-		rcvr, err := entity.NewUpdatebleReceiver(
-			req.Id, req.Name, req.Email, req.Doc, req.PixKeyType, req.PixKey, req.Status)
-		//TODO make repo verification  if user is indeed draft or active
+// CreateReceiverFromBRCode parses a static BR Code and creates a receiver with its key and merchant name
+func (s *Service) CreateReceiverFromBRCode(audit dtos.Audit, r dtos.CreateFromBRCodeRequest) (*entity.Receiver, error) {
+	payload, err := brcode.Parse(r.BRCode)
+	if err != nil {
+		s.log.Error("error parsing the br code of a receiver", err)
+		return nil, err
+	}
+	keyType, key, err := vo.DetectPixKeyType(payload.PixKey)
+	if err != nil {
+		return nil, err
+	}
+	doc := r.Doc
+	if len(doc) == 0 && (keyType == vo.CPFKey || keyType == vo.CNPJKey) {
+		doc = key
+	}
+	return s.CreateReceiver(audit, dtos.CreateReceiverRequest{
+		Name:       payload.MerchantName,
+		Email:      r.Email,
+		Doc:        doc,
+		PixKeyType: keyType,
+		PixKey:     key,
+	})
+}
+
+// UpdateReceiver updates a receiver according to its stored status, draft receivers may have all their data
+// changed while valid ones only accept a new email. The status itself is only changed by TransitionReceiver.
+// The request must carry the version of the receiver it was based on, the update is refused when the
+// receiver has been changed since then
+func (s *Service) UpdateReceiver(audit dtos.Audit, req dtos.UpdateReceiverRequest) error {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return fmt.Errorf("invalid id provided %w", err)
+	}
+	if req.Version <= 0 {
+		return ErrVersionRequired
+	}
+	requested, err := entity.ParseUserStatus(req.Status)
+	if err != nil {
+		return err
+	}
+	rcv, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if rcv.Version != req.Version {
+		return ErrVersionMismatch
+	}
+	current, err := entity.ParseUserStatus(rcv.Status)
+	if err != nil {
+		return err
+	}
+	if requested != current {
+		return fmt.Errorf("%w: receiver is %s", ErrStatusMismatch, current)
+	}
+	switch current {
+	case entity.Draft:
+		account, err := newBankAccount(req.BankAccount)
 		if err != nil {
 			s.log.Error("invalid user information provided for update", err)
 			return err
 		}
+		rcvr, err := entity.NewUpdatebleReceiver(req.Id, req.Name, req.Email, req.Doc, req.PixKeyType, req.PixKey,
+			account, req.Status, s.cfg.AllowThirdPartyPixKeys)
+		if err != nil {
+			s.log.Error("invalid user information provided for update", err)
+			return err
+		}
+		s.warnThirdPartyPixKey(rcvr)
 
-		_, err = s.repo.UpdateDraft(rcvr)
+		_, err = s.repo.UpdateDraft(audit, rcvr, req.Version, s.uniqueDocument())
 		if err != nil {
 			return err
 		}
 		return nil
+	case entity.Valid:
+		var email vo.EmailAddress
+		if len(req.Email) > 0 {
+			if email, err = vo.NewEmail(req.Email); err != nil {
+				return err
+			}
+		}
+		return s.repo.UpdateValid(audit, id, email.GetEmail(), req.Version)
+	default:
+		return fmt.Errorf("%w: receiver is %s", entity.ErrReceiverNotEditable, current)
 	}
-	id, err := uuid.Parse(req.Id)
+}
+
+// TransitionReceiver moves a receiver to another status, refusing the transitions not allowed by the entity
+func (s *Service) TransitionReceiver(audit dtos.Audit, id string, req dtos.TransitionStatusRequest) error {
+	parsedID, err := uuid.Parse(id)
 	if err != nil {
-		return fmt.Errorf("invalid id provided %w", err)
+		return fmt.Errorf("invalid id provided: %w", err)
+	}
+	target, err := entity.ParseUserStatus(req.Status)
+	if err != nil {
+		return err
 	}
-	return s.repo.UpdateValid(id, req.Email)
+	current, err := s.currentStatus(parsedID)
+	if err != nil {
+		return err
+	}
+	if err = entity.CheckTransition(current, target); err != nil {
+		return err
+	}
+	if err = s.repo.UpdateStatus(audit, parsedID, current, target); err != nil {
+		s.log.Error(fmt.Sprintf("error moving the receiver %s from %s to %s", id, current, target), err)
+		return err
+	}
+	return nil
 }
 
-func (s *Service) SearchReceivers(request dtos.SearchRequest) ([]dtos.GetReceiverResponse, error) {
+// SearchReceivers ranks the receivers by how well they match the search text. A query written with the search
+// query language only filters the receivers, sorted by name unless the filter sorts them otherwise
+func (s *Service) SearchReceivers(request dtos.SearchRequest, filter Filter) ([]dtos.SearchReceiverResponse, error) {
 	if request.Limit <= 0 {
 		request.Limit = 10
 	}
 	if request.Limit > 100 {
 		request.Limit = 100
 	}
-	receivers, err := s.repo.Get(request.Query, request.Limit)
+	query := NewSearchQuery(request.Query)
+	switch request.Syntax {
+	case "", SyntaxText:
+	case SyntaxQuery:
+		condition, err := ParseQuery(request.Query)
+		if err != nil {
+			return nil, err
+		}
+		filter.Condition, query = condition, SearchQuery{}
+		if filter.Sort.Field == "" {
+			filter.Sort = Sort{Field: SortName}
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown syntax %q, use %s or %s", ErrInvalidQuery, request.Syntax, SyntaxText, SyntaxQuery)
+	}
+	receivers, err := s.repo.Search(query, filter, request.Limit)
 	if err != nil {
 		s.log.Error(fmt.Sprintf("error finding the receiver with the following query: %s", request.Query), err)
 		return nil, err
 	}
+	for i := range receivers {
+		receivers[i].Highlights = query.highlights(&receivers[i].GetReceiverResponse)
+	}
 	return receivers, nil
 }
 
@@ -83,15 +231,348 @@ func (s *Service) GetReceiver(id string) (*dtos.GetReceiverResponse, error) {
 	return resp, nil
 }
 
-func (s *Service) ListReceivers(page int) ([]dtos.ListReceiversResponse, error) {
-	list, err := s.repo.List(page)
+// ReceiverHistory returns every recorded change of the receiver, deleted receivers included
+func (s *Service) ReceiverHistory(id string) ([]dtos.HistoryEntryResponse, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	history, err := s.repo.History(parsedID)
 	if err != nil {
-		s.log.Error("error while listing receivers", err)
+		s.log.Error(fmt.Sprintf("error reading the history of the receiver %s", id), err)
 		return nil, err
 	}
-	return list, nil
+	if len(history) == 0 {
+		return nil, ErrReceiverNotFound
+	}
+	return history, nil
+}
+
+// ListReceivers reads a page of the receivers matching the filter, one more receiver than the page size is read to
+// tell whether there is a next page. A page asked by number is read with an offset, in the same order as the
+// cursors. Receivers are sorted by their creation time unless the filter sorts them otherwise, and a cursor only
+// pages through the sort it was created with
+func (s *Service) ListReceivers(req dtos.ListReceiversRequest, filter Filter) (*dtos.ListReceiversPage, error) {
+	size := req.PageSize
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	if size > MaxPageSize {
+		size = MaxPageSize
+	}
+	if filter.Sort.Field == "" {
+		filter.Sort = Sort{Field: SortCreatedAt}
+	}
+	page := &dtos.ListReceiversPage{Pagination: dtos.Pagination{PageSize: size}}
+	var list []dtos.ListReceiversResponse
+	var hasNext, hasPrev bool
+	if len(req.Cursor) > 0 {
+		cursor, err := DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != filter.Sort {
+			return nil, ErrInvalidCursor
+		}
+		if list, err = s.repo.ListFrom(filter, cursor, size+1); err != nil {
+			s.log.Error("error while listing receivers", err)
+			return nil, err
+		}
+		more := len(list) > size
+		if cursor.Backward {
+			if more {
+				list = list[1:]
+			}
+			hasNext, hasPrev = true, more
+		} else {
+			if more {
+				list = list[:size]
+			}
+			hasNext, hasPrev = more, true
+		}
+	} else {
+		number := req.Page
+		if number < 1 {
+			number = 1
+		}
+		var err error
+		if list, err = s.repo.List(filter, (number-1)*size, size+1); err != nil {
+			s.log.Error("error while listing receivers", err)
+			return nil, err
+		}
+		if len(list) > size {
+			list = list[:size]
+			hasNext = true
+		}
+		hasPrev = number > 1
+		if req.Page > 0 {
+			page.Pagination.Page = number
+		}
+	}
+	if len(list) > 0 {
+		first, last := list[0], list[len(list)-1]
+		if hasNext {
+			page.Pagination.NextCursor = cursorAt(last, filter.Sort, false).Encode()
+		}
+		if hasPrev {
+			page.Pagination.PrevCursor = cursorAt(first, filter.Sort, true).Encode()
+		}
+	}
+	page.Receivers = list
+	if req.Total {
+		total, err := s.repo.Count(filter)
+		if err != nil {
+			s.log.Error("error while counting receivers", err)
+			return nil, err
+		}
+		page.Pagination.Total = &total
+	}
+	return page, nil
+}
+
+func (s *Service) DeleteReceivers(audit dtos.Audit, req dtos.DeleReceiverRequest) error {
+	return s.repo.Delete(audit, req.Ids...)
+}
+
+// RestoreReceivers brings soft deleted receivers back, it fails when none of them could be restored, or when
+// duplicate documents are rejected and one of them shares its document with a receiver not deleted
+func (s *Service) RestoreReceivers(audit dtos.Audit, req dtos.RestoreReceiversRequest) error {
+	restored, err := s.repo.Restore(audit, s.uniqueDocument(), req.Ids...)
+	if err != nil {
+		s.log.Error(fmt.Sprintf("error restoring the receivers %v", req.Ids), err)
+		return err
+	}
+	if restored == 0 {
+		return ErrReceiverNotFound
+	}
+	return nil
 }
 
-func (s *Service) DeleteReceivers(req dtos.DeleReceiverRequest) error {
-	return s.repo.Delete(req.Ids...)
+// purgeActor is recorded in the history of the receivers removed by PurgeDeleted, as no request asks for it
+const purgeActor = "retention"
+
+// PurgeDeleted permanently removes the receivers soft deleted longer than the configured retention
+func (s *Service) PurgeDeleted() (int64, error) {
+	if s.cfg.DeletedRetention <= 0 {
+		return 0, nil
+	}
+	purged, err := s.repo.Purge(dtos.Audit{Actor: purgeActor}, s.cfg.DeletedRetention)
+	if err != nil {
+		s.log.Error("error purging deleted receivers", err)
+		return 0, err
+	}
+	if purged > 0 {
+		s.log.Info(fmt.Sprintf("%d deleted receivers purged", purged))
+	}
+	return purged, nil
+}
+
+func (s *Service) ListPixKeys(id string) ([]dtos.PixKeyResponse, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	if _, err = s.repo.GetByID(parsedID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPixKeys(parsedID)
+}
+
+func (s *Service) AddPixKey(audit dtos.Audit, id string, req dtos.AddPixKeyRequest) (*entity.ReceiverPixKey, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	rcv, err := s.repo.GetByID(parsedID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := entity.NewReceiverPixKey(parsedID, rcv.Document, req.PixKeyType, req.PixKey, req.Preferred,
+		s.cfg.AllowThirdPartyPixKeys)
+	if err != nil {
+		s.log.Error(fmt.Sprintf("invalid pix key provided for the receiver %s", id), err)
+		return nil, err
+	}
+	if key.ThirdParty() {
+		s.log.Warn(fmt.Sprintf("receiver %s registered with a third party %s pix key", id, key.Key().KeyType()))
+	}
+	if err = s.repo.AddPixKey(audit, key); err != nil {
+		s.log.Error(fmt.Sprintf("error adding a pix key to the receiver %s", id), err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// RemovePixKey removes one of the receiver's pix keys, the last one can only be removed from receivers
+// that can still be paid through a bank account
+func (s *Service) RemovePixKey(audit dtos.Audit, id string, keyID string) error {
+	parsedID, parsedKeyID, err := parsePixKeyIDs(id, keyID)
+	if err != nil {
+		return err
+	}
+	rcv, err := s.repo.GetByID(parsedID)
+	if err != nil {
+		return err
+	}
+	keys, err := s.repo.ListPixKeys(parsedID)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 1 && keys[0].Id == parsedKeyID && len(rcv.BankCode) == 0 {
+		return entity.ErrNoPaymentDestination
+	}
+	if err = s.repo.RemovePixKey(audit, parsedID, parsedKeyID); err != nil {
+		s.log.Error(fmt.Sprintf("error removing the pix key %s of the receiver %s", keyID, id), err)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) SetPreferredPixKey(audit dtos.Audit, id string, keyID string) error {
+	parsedID, parsedKeyID, err := parsePixKeyIDs(id, keyID)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.SetPreferredPixKey(audit, parsedID, parsedKeyID); err != nil {
+		s.log.Error(fmt.Sprintf("error setting the preferred pix key of the receiver %s", id), err)
+		return err
+	}
+	return nil
+}
+
+// GenerateBRCode builds the static BR Code of the receiver's preferred pix key
+func (s *Service) GenerateBRCode(id string, req dtos.BRCodeRequest) (string, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid id provided: %w", err)
+	}
+	rcv, err := s.repo.GetByID(parsedID)
+	if err != nil {
+		return "", err
+	}
+	if len(rcv.Pixkey) == 0 {
+		return "", ErrReceiverWithoutPixKey
+	}
+	city := req.City
+	if len(city) == 0 {
+		city = s.cfg.BRCodeCity
+	}
+	code, err := brcode.Encode(brcode.StaticPayload{
+		PixKey:       rcv.Pixkey,
+		MerchantName: rcv.Name,
+		MerchantCity: city,
+		Amount:       req.Amount,
+		TxID:         req.TxID,
+	})
+	if err != nil {
+		s.log.Error(fmt.Sprintf("error generating the br code of the receiver %s", id), err)
+		return "", err
+	}
+	return code, nil
+}
+
+// ValidateReceiver looks the receiver's preferred pix key up in the key directory and compares its owner
+// document with the receiver's. A match moves the receiver from draft to valid, a mismatch is recorded on
+// the receiver, which stays in draft, and returned as the validation reason
+func (s *Service) ValidateReceiver(audit dtos.Audit, id string) (*dtos.ValidationResponse, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	rcv, err := s.repo.GetByID(parsedID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := entity.ParseUserStatus(rcv.Status)
+	if err != nil {
+		return nil, err
+	}
+	if current != entity.Draft {
+		return nil, fmt.Errorf("%w: only draft receivers can be validated, receiver is %s",
+			entity.ErrInvalidStatusTransition, current)
+	}
+	if len(rcv.Pixkey) == 0 {
+		return nil, ErrReceiverWithoutPixKey
+	}
+
+	resp := &dtos.ValidationResponse{}
+	owner, err := s.directory.Lookup(rcv.Pixkey)
+	switch {
+	case errors.Is(err, ErrKeyNotInDirectory):
+		resp.Reason = err.Error()
+	case err != nil:
+		s.log.Error(fmt.Sprintf("error looking up the pix key of the receiver %s", id), err)
+		return nil, err
+	default:
+		resp.OwnerName, resp.ISPB = owner.Name, owner.ISPB
+		if !sameDocument(owner.Document, rcv.Document) {
+			resp.Reason = ErrDocumentMismatch.Error()
+		}
+	}
+	resp.Valid = len(resp.Reason) == 0
+	if err = s.repo.SaveValidation(audit, parsedID, resp.Reason); err != nil {
+		s.log.Error(fmt.Sprintf("error saving the validation of the receiver %s", id), err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *Service) newReceiver(r dtos.CreateReceiverRequest) (*entity.Receiver, error) {
+	account, err := newBankAccount(r.BankAccount)
+	if err != nil {
+		return nil, err
+	}
+	rcv, err := entity.NewReceiver(r.Name, r.Email, r.Doc, r.PixKeyType, r.PixKey, account, s.cfg.AllowThirdPartyPixKeys)
+	if err != nil {
+		return nil, err
+	}
+	s.warnThirdPartyPixKey(rcv)
+	return rcv, nil
+}
+
+func (s *Service) currentStatus(id uuid.UUID) (entity.UserStatus, error) {
+	rcv, err := s.repo.GetByID(id)
+	if err != nil {
+		return 0, err
+	}
+	return entity.ParseUserStatus(rcv.Status)
+}
+
+func (s *Service) uniqueDocument() bool {
+	return s.cfg.DuplicateDocuments == RejectDuplicateDocuments
+}
+
+func (s *Service) warnThirdPartyPixKey(rcv *entity.Receiver) {
+	if rcv.ThirdPartyPixKey() {
+		s.log.Warn(fmt.Sprintf("receiver %s registered with a third party %s pix key", rcv.Id(), rcv.PixKey().KeyType()))
+	}
+}
+
+// newBankAccount builds the optional TED destination of a receiver request
+func newBankAccount(req *dtos.BankAccountRequest) (*vo.BankAccount, error) {
+	if req == nil {
+		return nil, nil
+	}
+	return vo.NewBankAccount(req.BankCode, req.Branch, req.Number, req.Digit, req.AccountType)
+}
+
+// sameDocument compares two documents regardless of their masks, an invalid owner document never matches
+func sameDocument(ownerDoc, receiverDoc string) bool {
+	doc, err := vo.NewCpfCnpj(ownerDoc)
+	if err != nil {
+		return false
+	}
+	return doc.GetValue() == receiverDoc
+}
+
+func parsePixKeyIDs(id string, keyID string) (uuid.UUID, uuid.UUID, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid id provided: %w", err)
+	}
+	parsedKeyID, err := uuid.Parse(keyID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid pix key id provided: %w", err)
+	}
+	return parsedID, parsedKeyID, nil
 }
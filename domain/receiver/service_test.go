@@ -2,36 +2,61 @@ package receiver
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/google/uuid"
+	"github.com/lucasszmt/transfeera-challenge/domain/brcode"
 	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
 	"github.com/lucasszmt/transfeera-challenge/domain/entity"
 	"github.com/lucasszmt/transfeera-challenge/domain/vo"
 	"github.com/lucasszmt/transfeera-challenge/infra/log"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type receiverRepoMock struct {
 	Err                error
-	CreateReceiverMock func(receiver *entity.Receiver) (*entity.Receiver, error)
+	CreateReceiverMock func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error)
 	UpdateDraftMock    func(receiver *entity.Receiver) (*entity.Receiver, error)
 	UpdateValidMock    func(id uuid.UUID, email string) error
-	DeleteMock         func(id ...uuid.UUID) error
+	UpdateStatusMock   func(id uuid.UUID, from, to entity.UserStatus) error
+	SaveValidationMock func(id uuid.UUID, reason string) error
+	HistoryMock        func(id uuid.UUID) ([]dtos.HistoryEntryResponse, error)
+	DeleteMock         func(audit dtos.Audit, id ...uuid.UUID) error
+	RestoreMock        func(uniqueDocument bool, id ...uuid.UUID) (int64, error)
+	PurgeMock          func(retention time.Duration) (int64, error)
 	GetByIDMock        func(id uuid.UUID) (*dtos.GetReceiverResponse, error)
-	GetMock            func(query string, limit int) ([]dtos.GetReceiverResponse, error)
-	ListMock           func(page int) ([]dtos.ListReceiversResponse, error)
+	SearchMock         func(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error)
+	ListMock           func(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error)
+	ListFromMock       func(filter Filter, cursor Cursor, limit int) ([]dtos.ListReceiversResponse, error)
+	CountMock          func(filter Filter) (int, error)
+	ListPixKeysMock    func(receiverID uuid.UUID) ([]dtos.PixKeyResponse, error)
+	AddPixKeyMock      func(key *entity.ReceiverPixKey) error
+	RemovePixKeyMock   func(receiverID, keyID uuid.UUID) error
+	SetPreferredMock   func(receiverID, keyID uuid.UUID) error
+	CreateBatchMock    func(receivers []*entity.Receiver) error
+	ExportMock         func(query SearchQuery, filter Filter, each func(receiver *dtos.GetReceiverResponse) error) error
 }
 
-func (r receiverRepoMock) Create(rec *entity.Receiver) (*entity.Receiver, error) {
+func (r receiverRepoMock) Create(audit dtos.Audit, rec *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
 	switch {
 	case r.CreateReceiverMock != nil:
-		return r.CreateReceiverMock(rec)
+		return r.CreateReceiverMock(rec, uniqueDocument)
 	default:
 		return nil, r.Err
 	}
 }
 
-func (r receiverRepoMock) UpdateDraft(rec *entity.Receiver) (*entity.Receiver, error) {
+func (r receiverRepoMock) CreateBatch(audit dtos.Audit, receivers []*entity.Receiver, uniqueDocument bool) error {
+	switch {
+	case r.CreateBatchMock != nil:
+		return r.CreateBatchMock(receivers)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverRepoMock) UpdateDraft(audit dtos.Audit, rec *entity.Receiver, version int, uniqueDocument bool) (*entity.Receiver, error) {
 	switch {
 	case r.UpdateDraftMock != nil:
 		return r.UpdateDraftMock(rec)
@@ -40,7 +65,7 @@ func (r receiverRepoMock) UpdateDraft(rec *entity.Receiver) (*entity.Receiver, e
 	}
 }
 
-func (r receiverRepoMock) UpdateValid(id uuid.UUID, email string) error {
+func (r receiverRepoMock) UpdateValid(audit dtos.Audit, id uuid.UUID, email string, version int) error {
 	switch {
 	case r.UpdateValidMock != nil:
 		return r.UpdateValidMock(id, email)
@@ -49,15 +74,33 @@ func (r receiverRepoMock) UpdateValid(id uuid.UUID, email string) error {
 	}
 }
 
-func (r receiverRepoMock) Delete(id ...uuid.UUID) error {
+func (r receiverRepoMock) Delete(audit dtos.Audit, id ...uuid.UUID) error {
 	switch {
 	case r.DeleteMock != nil:
-		return r.DeleteMock(id...)
+		return r.DeleteMock(audit, id...)
 	default:
 		return r.Err
 	}
 }
 
+func (r receiverRepoMock) Restore(audit dtos.Audit, uniqueDocument bool, id ...uuid.UUID) (int64, error) {
+	switch {
+	case r.RestoreMock != nil:
+		return r.RestoreMock(uniqueDocument, id...)
+	default:
+		return 0, r.Err
+	}
+}
+
+func (r receiverRepoMock) Purge(audit dtos.Audit, retention time.Duration) (int64, error) {
+	switch {
+	case r.PurgeMock != nil:
+		return r.PurgeMock(retention)
+	default:
+		return 0, r.Err
+	}
+}
+
 func (r receiverRepoMock) GetByID(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
 	switch {
 	case r.GetByIDMock != nil:
@@ -67,28 +110,130 @@ func (r receiverRepoMock) GetByID(id uuid.UUID) (*dtos.GetReceiverResponse, erro
 	}
 }
 
-func (r receiverRepoMock) Get(query string, limit int) ([]dtos.GetReceiverResponse, error) {
+func (r receiverRepoMock) Search(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error) {
+	switch {
+	case r.SearchMock != nil:
+		return r.SearchMock(query, filter, limit)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverRepoMock) Export(query SearchQuery, filter Filter, each func(receiver *dtos.GetReceiverResponse) error) error {
+	switch {
+	case r.ExportMock != nil:
+		return r.ExportMock(query, filter, each)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverRepoMock) UpdateStatus(audit dtos.Audit, id uuid.UUID, from, to entity.UserStatus) error {
+	switch {
+	case r.UpdateStatusMock != nil:
+		return r.UpdateStatusMock(id, from, to)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverRepoMock) SaveValidation(audit dtos.Audit, id uuid.UUID, reason string) error {
+	switch {
+	case r.SaveValidationMock != nil:
+		return r.SaveValidationMock(id, reason)
+	default:
+		return r.Err
+	}
+}
+
+type keyDirectoryMock map[string]KeyOwner
+
+func (d keyDirectoryMock) Lookup(key string) (*KeyOwner, error) {
+	owner, ok := d[key]
+	if !ok {
+		return nil, ErrKeyNotInDirectory
+	}
+	return &owner, nil
+}
+
+func (r receiverRepoMock) History(id uuid.UUID) ([]dtos.HistoryEntryResponse, error) {
 	switch {
-	case r.GetMock != nil:
-		return r.GetMock(query, limit)
+	case r.HistoryMock != nil:
+		return r.HistoryMock(id)
 	default:
 		return nil, r.Err
 	}
 }
 
-func (r receiverRepoMock) List(page int) ([]dtos.ListReceiversResponse, error) {
+func (r receiverRepoMock) List(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) {
 	switch {
 	case r.ListMock != nil:
-		return r.ListMock(page)
+		return r.ListMock(filter, offset, limit)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverRepoMock) ListFrom(filter Filter, cursor Cursor, limit int) ([]dtos.ListReceiversResponse, error) {
+	switch {
+	case r.ListFromMock != nil:
+		return r.ListFromMock(filter, cursor, limit)
+	default:
+		return nil, r.Err
+	}
+}
+
+func (r receiverRepoMock) Count(filter Filter) (int, error) {
+	switch {
+	case r.CountMock != nil:
+		return r.CountMock(filter)
+	default:
+		return 0, r.Err
+	}
+}
+
+func (r receiverRepoMock) ListPixKeys(receiverID uuid.UUID) ([]dtos.PixKeyResponse, error) {
+	switch {
+	case r.ListPixKeysMock != nil:
+		return r.ListPixKeysMock(receiverID)
 	default:
 		return nil, r.Err
 	}
 }
 
+func (r receiverRepoMock) AddPixKey(audit dtos.Audit, key *entity.ReceiverPixKey) error {
+	switch {
+	case r.AddPixKeyMock != nil:
+		return r.AddPixKeyMock(key)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverRepoMock) RemovePixKey(audit dtos.Audit, receiverID, keyID uuid.UUID) error {
+	switch {
+	case r.RemovePixKeyMock != nil:
+		return r.RemovePixKeyMock(receiverID, keyID)
+	default:
+		return r.Err
+	}
+}
+
+func (r receiverRepoMock) SetPreferredPixKey(audit dtos.Audit, receiverID, keyID uuid.UUID) error {
+	switch {
+	case r.SetPreferredMock != nil:
+		return r.SetPreferredMock(receiverID, keyID)
+	default:
+		return r.Err
+	}
+}
+
 func TestService_CreateReceiver(t *testing.T) {
+	duplicated := &DuplicateReceiverError{ExistingID: uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"), Field: "document"}
 	type fields struct {
 		log  log.Logger
 		repo Repository
+		cfg  Config
 	}
 	type args struct {
 		r dtos.CreateReceiverRequest
@@ -105,7 +250,7 @@ func TestService_CreateReceiver(t *testing.T) {
 			fields: fields{
 				log: log.MockLogger{},
 				repo: receiverRepoMock{
-					CreateReceiverMock: func(receiver *entity.Receiver) (*entity.Receiver, error) {
+					CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
 						return receiver, nil
 					},
 				},
@@ -124,7 +269,7 @@ func TestService_CreateReceiver(t *testing.T) {
 			fields: fields{
 				log: log.MockLogger{},
 				repo: receiverRepoMock{
-					CreateReceiverMock: func(receiver *entity.Receiver) (*entity.Receiver, error) {
+					CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
 						return receiver, nil
 					},
 				},
@@ -139,6 +284,26 @@ func TestService_CreateReceiver(t *testing.T) {
 			wantErr:     true,
 			expectedErr: vo.ErrInvalidCPFCNPJ,
 		},
+		{
+			name: "Should return an err for a cpf pix key of another document",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
+						return receiver, nil
+					},
+				},
+			},
+			args: args{dtos.CreateReceiverRequest{
+				Name:       "Anthony Kieds",
+				Email:      "rhcp@chilipeppers.com",
+				Doc:        "471.550.590-80",
+				PixKeyType: vo.CPFKey,
+				PixKey:     "419.267.660-59",
+			}},
+			wantErr:     true,
+			expectedErr: entity.ErrPixKeyNotOwned,
+		},
 		{
 			name: "Should get an err on creating a receiver on the repo ",
 			fields: fields{
@@ -157,14 +322,38 @@ func TestService_CreateReceiver(t *testing.T) {
 			wantErr:     true,
 			expectedErr: sql.ErrConnDone,
 		},
+		{
+			name: "Should ask the repo to reject duplicated documents when configured",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
+						if !uniqueDocument {
+							return receiver, nil
+						}
+						return nil, duplicated
+					},
+				},
+				cfg: Config{DuplicateDocuments: RejectDuplicateDocuments},
+			},
+			args: args{dtos.CreateReceiverRequest{
+				Name:       "Anthony Kieds",
+				Doc:        "471.550.590-80",
+				PixKeyType: vo.CPFKey,
+				PixKey:     "471.550.590-80",
+			}},
+			wantErr:     true,
+			expectedErr: duplicated,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Service{
 				log:  tt.fields.log,
 				repo: tt.fields.repo,
+				cfg:  tt.fields.cfg,
 			}
-			_, err := s.CreateReceiver(tt.args.r)
+			_, err := s.CreateReceiver(dtos.Audit{}, tt.args.r)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateReceiver() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -176,7 +365,68 @@ func TestService_CreateReceiver(t *testing.T) {
 	}
 }
 
+func TestService_CreateReceiverFromBRCode(t *testing.T) {
+	cpfCode, _ := brcode.Encode(brcode.StaticPayload{PixKey: "47155059080", MerchantName: "Anthony Kieds", MerchantCity: "BRASILIA"})
+	emailCode, _ := brcode.Encode(brcode.StaticPayload{PixKey: "rhcp@chilipeppers.com", MerchantName: "Anthony Kieds", MerchantCity: "BRASILIA"})
+	randomCode, _ := brcode.Encode(brcode.StaticPayload{PixKey: "123E4567-E12B-12D1-A456-426655440000", MerchantName: "Anthony Kieds", MerchantCity: "BRASILIA"})
+	create := receiverRepoMock{CreateReceiverMock: func(receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
+		return receiver, nil
+	}}
+	tests := []struct {
+		name        string
+		req         dtos.CreateFromBRCodeRequest
+		wantKeyType string
+		expectedErr error
+	}{
+		{
+			name:        "Should use a cpf key as the receiver document",
+			req:         dtos.CreateFromBRCodeRequest{BRCode: cpfCode},
+			wantKeyType: string(vo.CPFKey),
+		},
+		{
+			name:        "Should create a receiver with an email key and the given document",
+			req:         dtos.CreateFromBRCodeRequest{BRCode: emailCode, Doc: "471.550.590-80"},
+			wantKeyType: string(vo.EmailKey),
+		},
+		{
+			name:        "Should create a receiver with an uppercase random key",
+			req:         dtos.CreateFromBRCodeRequest{BRCode: randomCode, Doc: "471.550.590-80"},
+			wantKeyType: string(vo.RandomKey),
+		},
+		{
+			name:        "Should return an err for a receiver without document",
+			req:         dtos.CreateFromBRCodeRequest{BRCode: emailCode},
+			expectedErr: vo.ErrInvalidCPFCNPJ,
+		},
+		{
+			name:        "Should return an err for a tampered br code",
+			req:         dtos.CreateFromBRCodeRequest{BRCode: cpfCode[:len(cpfCode)-1] + "0"},
+			expectedErr: brcode.ErrInvalidCRC,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  log.MockLogger{},
+				repo: create,
+			}
+			got, err := s.CreateReceiverFromBRCode(dtos.Audit{}, tt.req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("CreateReceiverFromBRCode() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if err == nil && got.PixKey().KeyType() != tt.wantKeyType {
+				t.Errorf("CreateReceiverFromBRCode() key type = %v, want %v", got.PixKey().KeyType(), tt.wantKeyType)
+			}
+		})
+	}
+}
+
 func TestService_UpdateReceiver(t *testing.T) {
+	storedStatus := func(status string) func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+		return func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+			return &dtos.GetReceiverResponse{Id: id, Status: status, Version: 3}, nil
+		}
+	}
 	type fields struct {
 		log  log.Logger
 		repo Repository
@@ -196,6 +446,7 @@ func TestService_UpdateReceiver(t *testing.T) {
 			fields: fields{
 				log: log.MockLogger{},
 				repo: receiverRepoMock{
+					GetByIDMock: storedStatus("draft"),
 					UpdateDraftMock: func(r *entity.Receiver) (*entity.Receiver, error) {
 						return &entity.Receiver{}, nil
 					},
@@ -209,6 +460,7 @@ func TestService_UpdateReceiver(t *testing.T) {
 				PixKeyType: vo.CPFKey,
 				PixKey:     "471.550.590-80",
 				Status:     "draft",
+				Version:    3,
 			}},
 			wantErr:     false,
 			ExpectedErr: nil,
@@ -218,15 +470,17 @@ func TestService_UpdateReceiver(t *testing.T) {
 			fields: fields{
 				log: log.MockLogger{},
 				repo: receiverRepoMock{
+					GetByIDMock: storedStatus("valid"),
 					UpdateValidMock: func(id uuid.UUID, email string) error {
 						return nil
 					},
 				},
 			},
 			args: args{dtos.UpdateReceiverRequest{
-				Id:     "624b2913-ecf3-4445-9b68-588e41038593",
-				Email:  "chadsmith@rhcp.com",
-				Status: "valid",
+				Id:      "624b2913-ecf3-4445-9b68-588e41038593",
+				Email:   "chadsmith@rhcp.com",
+				Status:  "valid",
+				Version: 3,
 			}},
 			wantErr:     false,
 			ExpectedErr: nil,
@@ -245,15 +499,31 @@ func TestService_UpdateReceiver(t *testing.T) {
 				PixKeyType: vo.CPFKey,
 				PixKey:     "471.550.590-80",
 				Status:     "draft",
+				Version:    3,
 			}},
 			wantErr:     true,
 			ExpectedErr: sql.ErrNoRows,
 		},
 		{
-			name: "Should return an err if receiver not found",
+			name: "Should return an err for an unknown status",
 			fields: fields{
 				log:  log.MockLogger{},
-				repo: receiverRepoMock{Err: sql.ErrNoRows},
+				repo: receiverRepoMock{GetByIDMock: storedStatus("valid")},
+			},
+			args: args{dtos.UpdateReceiverRequest{
+				Id:      "624b2913-ecf3-4445-9b68-588e41038593",
+				Email:   "chadsmith@rhcp.com",
+				Status:  "active",
+				Version: 3,
+			}},
+			wantErr:     true,
+			ExpectedErr: entity.ErrInvalidStatus,
+		},
+		{
+			name: "Should not update a valid receiver as a draft one",
+			fields: fields{
+				log:  log.MockLogger{},
+				repo: receiverRepoMock{GetByIDMock: storedStatus("valid")},
 			},
 			args: args{dtos.UpdateReceiverRequest{
 				Id:         "624b2913-ecf3-4445-9b68-588e41038593",
@@ -262,10 +532,80 @@ func TestService_UpdateReceiver(t *testing.T) {
 				Doc:        "471.550.590-80",
 				PixKeyType: vo.CPFKey,
 				PixKey:     "471.550.590-80",
-				Status:     "active",
+				Status:     "draft",
+				Version:    3,
 			}},
 			wantErr:     true,
-			ExpectedErr: sql.ErrNoRows,
+			ExpectedErr: ErrStatusMismatch,
+		},
+		{
+			name: "Should not update a blocked receiver",
+			fields: fields{
+				log:  log.MockLogger{},
+				repo: receiverRepoMock{GetByIDMock: storedStatus("blocked")},
+			},
+			args: args{dtos.UpdateReceiverRequest{
+				Id:      "624b2913-ecf3-4445-9b68-588e41038593",
+				Email:   "chadsmith@rhcp.com",
+				Status:  "blocked",
+				Version: 3,
+			}},
+			wantErr:     true,
+			ExpectedErr: entity.ErrReceiverNotEditable,
+		},
+		{
+			name: "Should require the version the update is based on",
+			fields: fields{
+				log:  log.MockLogger{},
+				repo: receiverRepoMock{GetByIDMock: storedStatus("valid")},
+			},
+			args: args{dtos.UpdateReceiverRequest{
+				Id:     "624b2913-ecf3-4445-9b68-588e41038593",
+				Email:  "chadsmith@rhcp.com",
+				Status: "valid",
+			}},
+			wantErr:     true,
+			ExpectedErr: ErrVersionRequired,
+		},
+		{
+			name: "Should not update a receiver changed since the version was read",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					GetByIDMock: storedStatus("valid"),
+					UpdateValidMock: func(id uuid.UUID, email string) error {
+						return errors.New("update should not be called")
+					},
+				},
+			},
+			args: args{dtos.UpdateReceiverRequest{
+				Id:      "624b2913-ecf3-4445-9b68-588e41038593",
+				Email:   "chadsmith@rhcp.com",
+				Status:  "valid",
+				Version: 2,
+			}},
+			wantErr:     true,
+			ExpectedErr: ErrVersionMismatch,
+		},
+		{
+			name: "Should return the version mismatch detected by the update",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					GetByIDMock: storedStatus("valid"),
+					UpdateValidMock: func(id uuid.UUID, email string) error {
+						return ErrVersionMismatch
+					},
+				},
+			},
+			args: args{dtos.UpdateReceiverRequest{
+				Id:      "624b2913-ecf3-4445-9b68-588e41038593",
+				Email:   "chadsmith@rhcp.com",
+				Status:  "valid",
+				Version: 3,
+			}},
+			wantErr:     true,
+			ExpectedErr: ErrVersionMismatch,
 		},
 	}
 	for _, tt := range tests {
@@ -274,177 +614,424 @@ func TestService_UpdateReceiver(t *testing.T) {
 				log:  tt.fields.log,
 				repo: tt.fields.repo,
 			}
-			if err := s.UpdateReceiver(tt.args.req); (err != nil) != tt.wantErr {
+			err := s.UpdateReceiver(dtos.Audit{}, tt.args.req)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("UpdateReceiver() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !errors.Is(err, tt.ExpectedErr) {
+				t.Errorf("UpdateReceiver() error = %v, expectedErr %v", err, tt.ExpectedErr)
+			}
 		})
 	}
 }
 
-func TestService_ListReceivers(t *testing.T) {
-	type fields struct {
-		log  log.Logger
-		repo Repository
-	}
-	type args struct {
-		page int
+func TestService_TransitionReceiver(t *testing.T) {
+	const receiverID = "624b2913-ecf3-4445-9b68-588e41038593"
+	storedStatus := func(status string) func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+		return func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+			return &dtos.GetReceiverResponse{Id: id, Status: status}, nil
+		}
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    []dtos.ListReceiversResponse
-		wantErr bool
+		name        string
+		repo        receiverRepoMock
+		status      string
+		expectedErr error
 	}{
 		{
-			name: "Should return a list of receivers",
-			fields: fields{
-				log: log.MockLogger{},
-				repo: receiverRepoMock{ListMock: func(page int) ([]dtos.ListReceiversResponse, error) {
-					return []dtos.ListReceiversResponse{
-						{
-							Id:       uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"),
-							Name:     "Chad Smith",
-							Document: "47155059080",
-							Status:   "draft",
-						}, {
-							Id:       uuid.MustParse("65f8b6c5-11a0-4396-a1f2-75cf6f315700"),
-							Name:     "Anthony Kieds",
-							Document: "47155059089",
-							Status:   "active",
-						}, {
-							Id:       uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
-							Name:     "Flea",
-							Document: "47155059080",
-							Status:   "active",
-						},
-					}, nil
-				}}},
-			args: args{3},
-			want: []dtos.ListReceiversResponse{
-				{
-					Id:       uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"),
-					Name:     "Chad Smith",
-					Document: "47155059080",
-					Status:   "draft",
-				}, {
-					Id:       uuid.MustParse("65f8b6c5-11a0-4396-a1f2-75cf6f315700"),
-					Name:     "Anthony Kieds",
-					Document: "47155059089",
-					Status:   "active",
-				}, {
-					Id:       uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
-					Name:     "Flea",
-					Document: "47155059080",
-					Status:   "active",
+			name: "Should block a valid receiver",
+			repo: receiverRepoMock{
+				GetByIDMock: storedStatus("valid"),
+				UpdateStatusMock: func(id uuid.UUID, from, to entity.UserStatus) error {
+					if from != entity.Valid || to != entity.Blocked {
+						return errors.New("unexpected transition")
+					}
+					return nil
 				},
 			},
-			wantErr: false,
+			status: "blocked",
 		},
 		{
-			name: "Should return an error if no receviers found",
-			fields: fields{
-				log:  log.MockLogger{},
-				repo: receiverRepoMock{Err: sql.ErrNoRows},
+			name:        "Should refuse an archived receiver becoming valid",
+			repo:        receiverRepoMock{GetByIDMock: storedStatus("archived")},
+			status:      "valid",
+			expectedErr: entity.ErrInvalidStatusTransition,
+		},
+		{
+			name:        "Should refuse an unknown status",
+			repo:        receiverRepoMock{GetByIDMock: storedStatus("draft")},
+			status:      "active",
+			expectedErr: entity.ErrInvalidStatus,
+		},
+		{
+			name: "Should return the err of a concurrent transition",
+			repo: receiverRepoMock{
+				GetByIDMock: storedStatus("draft"),
+				Err:         ErrStatusChanged,
 			},
-			want:    nil,
-			wantErr: true,
+			status:      "archived",
+			expectedErr: ErrStatusChanged,
+		},
+		{
+			name:        "Should return an err for an unknown receiver",
+			repo:        receiverRepoMock{Err: ErrReceiverNotFound},
+			status:      "archived",
+			expectedErr: ErrReceiverNotFound,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Service{
-				log:  tt.fields.log,
-				repo: tt.fields.repo,
-			}
-			got, err := s.ListReceivers(tt.args.page)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("ListReceivers() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				log:  log.MockLogger{},
+				repo: tt.repo,
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ListReceivers() got = %v, want %v", got, tt.want)
+			err := s.TransitionReceiver(dtos.Audit{}, receiverID, dtos.TransitionStatusRequest{Status: tt.status})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("TransitionReceiver() error = %v, expectedErr %v", err, tt.expectedErr)
 			}
 		})
 	}
 }
 
-func TestService_SearchReceivers(t *testing.T) {
-	type fields struct {
-		log  log.Logger
-		repo Repository
+func TestService_ListReceivers(t *testing.T) {
+	createdAt := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)
+	receivers := []dtos.ListReceiversResponse{
+		{
+			Id:        uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"),
+			Name:      "Chad Smith",
+			Document:  "47155059080",
+			Status:    "draft",
+			CreatedAt: createdAt,
+		}, {
+			Id:        uuid.MustParse("65f8b6c5-11a0-4396-a1f2-75cf6f315700"),
+			Name:      "Anthony Kieds",
+			Document:  "47155059089",
+			Status:    "valid",
+			CreatedAt: createdAt,
+		}, {
+			Id:        uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
+			Name:      "Flea",
+			Document:  "47155059080",
+			Status:    "valid",
+			CreatedAt: createdAt.Add(time.Second),
+		},
 	}
-	type args struct {
-		request dtos.SearchRequest
+	byCreation := Sort{Field: SortCreatedAt}
+	byName := Sort{Field: SortName, Desc: true}
+	cursor := func(i int, backward bool) string {
+		return cursorAt(receivers[i], byCreation, backward).Encode()
 	}
+	total := 42
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    []dtos.GetReceiverResponse
-		wantErr bool
+		name        string
+		repo        Repository
+		req         dtos.ListReceiversRequest
+		filter      Filter
+		want        *dtos.ListReceiversPage
+		expectedErr error
 	}{
 		{
-			name: "Should return receivers with a limit of 10",
-			fields: fields{
-				log: log.MockLogger{},
-				repo: receiverRepoMock{
-					GetMock: func(query string, limit int) ([]dtos.GetReceiverResponse, error) {
-						return []dtos.GetReceiverResponse{
-							{
-								Id:       uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"),
-								Name:     "Jhon Jhones",
-								Email:    "jjone@email.ocom",
-								Document: "08412535952",
-								Pixkey:   "08412535952",
-								PixType:  "cpf",
-								Status:   "draft",
-							},
-							{
-								Id:       uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
-								Name:     "Jhon Cormier",
-								Email:    "jcomrier@mail.com",
-								Document: "08412535932",
-								Pixkey:   "08412535932",
-								PixType:  "cpf",
-								Status:   "draft",
-							},
-						}, nil
-					},
-				},
-			},
-			args: args{dtos.SearchRequest{
-				Query: "Jhon",
+			name: "Should return the first page with the cursor of the next one",
+			repo: receiverRepoMock{ListMock: func(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) {
+				if offset != 0 || limit != 3 {
+					return nil, errors.New("unexpected page")
+				}
+				return receivers, nil
 			}},
-			want: []dtos.GetReceiverResponse{
-				{
-					Id:       uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"),
-					Name:     "Jhon Jhones",
-					Email:    "jjone@email.ocom",
-					Document: "08412535952",
-					Pixkey:   "08412535952",
-					PixType:  "cpf",
-					Status:   "draft",
-				},
-				{
-					Id:       uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
-					Name:     "Jhon Cormier",
-					Email:    "jcomrier@mail.com",
-					Document: "08412535932",
-					Pixkey:   "08412535932",
-					PixType:  "cpf",
-					Status:   "draft",
+			req: dtos.ListReceiversRequest{PageSize: 2},
+			want: &dtos.ListReceiversPage{
+				Receivers:  receivers[:2],
+				Pagination: dtos.Pagination{PageSize: 2, NextCursor: cursor(1, false)},
+			},
+		},
+		{
+			name: "Should keep listing by page number with the default page size",
+			repo: receiverRepoMock{ListMock: func(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) {
+				if offset != 20 || limit != DefaultPageSize+1 {
+					return nil, errors.New("unexpected page")
+				}
+				return receivers, nil
+			}},
+			req: dtos.ListReceiversRequest{Page: 3},
+			want: &dtos.ListReceiversPage{
+				Receivers:  receivers,
+				Pagination: dtos.Pagination{PageSize: DefaultPageSize, Page: 3, PrevCursor: cursor(0, true)},
+			},
+		},
+		{
+			name: "Should limit the page size",
+			repo: receiverRepoMock{ListMock: func(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) {
+				if limit != MaxPageSize+1 {
+					return nil, errors.New("unexpected page size")
+				}
+				return receivers, nil
+			}},
+			req: dtos.ListReceiversRequest{PageSize: 1000},
+			want: &dtos.ListReceiversPage{
+				Receivers:  receivers,
+				Pagination: dtos.Pagination{PageSize: MaxPageSize},
+			},
+		},
+		{
+			name: "Should return the page after a cursor",
+			repo: receiverRepoMock{ListFromMock: func(filter Filter, c Cursor, limit int) ([]dtos.ListReceiversResponse, error) {
+				if c.Id != receivers[0].Id || c.Backward || limit != 3 {
+					return nil, errors.New("unexpected cursor")
+				}
+				return receivers[1:], nil
+			}},
+			req: dtos.ListReceiversRequest{PageSize: 2, Cursor: cursor(0, false)},
+			want: &dtos.ListReceiversPage{
+				Receivers:  receivers[1:],
+				Pagination: dtos.Pagination{PageSize: 2, PrevCursor: cursor(1, true)},
+			},
+		},
+		{
+			name: "Should return the page before a cursor",
+			repo: receiverRepoMock{ListFromMock: func(filter Filter, c Cursor, limit int) ([]dtos.ListReceiversResponse, error) {
+				if c.Id != receivers[2].Id || !c.Backward {
+					return nil, errors.New("unexpected cursor")
+				}
+				return receivers, nil
+			}},
+			req: dtos.ListReceiversRequest{PageSize: 2, Cursor: cursor(2, true)},
+			want: &dtos.ListReceiversPage{
+				Receivers:  receivers[1:],
+				Pagination: dtos.Pagination{PageSize: 2, NextCursor: cursor(2, false), PrevCursor: cursor(1, true)},
+			},
+		},
+		{
+			name: "Should count the receivers matching the filter when asked",
+			repo: receiverRepoMock{
+				ListMock: func(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) { return nil, nil },
+				CountMock: func(filter Filter) (int, error) {
+					if filter.DocType != vo.CNPJ {
+						return 0, errors.New("unexpected filter")
+					}
+					return total, nil
+				},
+			},
+			req:    dtos.ListReceiversRequest{Total: true},
+			filter: Filter{DocType: vo.CNPJ},
+			want:   &dtos.ListReceiversPage{Pagination: dtos.Pagination{PageSize: DefaultPageSize, Total: &total}},
+		},
+		{
+			name: "Should list the receivers matching the filter in its sort",
+			repo: receiverRepoMock{ListMock: func(filter Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) {
+				if filter.Sort != byName || len(filter.Statuses) != 1 || filter.Statuses[0] != entity.Valid {
+					return nil, errors.New("unexpected filter")
+				}
+				return []dtos.ListReceiversResponse{receivers[2], receivers[1], receivers[0]}, nil
+			}},
+			req:    dtos.ListReceiversRequest{PageSize: 2},
+			filter: Filter{Statuses: []entity.UserStatus{entity.Valid}, Sort: byName},
+			want: &dtos.ListReceiversPage{
+				Receivers:  []dtos.ListReceiversResponse{receivers[2], receivers[1]},
+				Pagination: dtos.Pagination{PageSize: 2, NextCursor: cursorAt(receivers[1], byName, false).Encode()},
+			},
+		},
+		{
+			name: "Should page after a cursor of the filter sort",
+			repo: receiverRepoMock{ListFromMock: func(filter Filter, c Cursor, limit int) ([]dtos.ListReceiversResponse, error) {
+				if c.Sort != byName || c.Name != "Anthony Kieds" || c.Status != entity.Valid {
+					return nil, errors.New("unexpected cursor")
+				}
+				return receivers[:1], nil
+			}},
+			req:    dtos.ListReceiversRequest{PageSize: 2, Cursor: cursorAt(receivers[1], byName, false).Encode()},
+			filter: Filter{Sort: byName},
+			want: &dtos.ListReceiversPage{
+				Receivers:  receivers[:1],
+				Pagination: dtos.Pagination{PageSize: 2, PrevCursor: cursorAt(receivers[0], byName, true).Encode()},
+			},
+		},
+		{
+			name:        "Should refuse a cursor of another sort",
+			repo:        receiverRepoMock{},
+			req:         dtos.ListReceiversRequest{Cursor: cursor(0, false)},
+			filter:      Filter{Sort: byName},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name:        "Should refuse a malformed cursor",
+			repo:        receiverRepoMock{},
+			req:         dtos.ListReceiversRequest{Cursor: "not-a-cursor"},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name:        "Should return the repository error",
+			repo:        receiverRepoMock{Err: sql.ErrConnDone},
+			expectedErr: sql.ErrConnDone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  log.MockLogger{},
+				repo: tt.repo,
+			}
+			got, err := s.ListReceivers(tt.req, tt.filter)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("ListReceivers() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListReceivers() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_SearchReceivers(t *testing.T) {
+	jhones := dtos.GetReceiverResponse{
+		Id:       uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593"),
+		Name:     "Jhon Jhones",
+		Email:    "jjone@email.ocom",
+		Document: "08412535952",
+		Pixkey:   "08412535952",
+		PixType:  "cpf",
+		Status:   "draft",
+	}
+	cormier := dtos.GetReceiverResponse{
+		Id:       uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad"),
+		Name:     "Jhon Cormier",
+		Email:    "jcomrier@mail.com",
+		Document: "08412535932",
+		Pixkey:   "08412535932",
+		PixType:  "cpf",
+		Status:   "draft",
+	}
+	conceicao := dtos.GetReceiverResponse{
+		Id:       uuid.MustParse("0f45db07-245f-47e1-8b0e-3b9a7905f082"),
+		Name:     "Maria da Conceição",
+		Email:    "maria@conceicao.com",
+		Document: "47155059080",
+		Status:   "valid",
+	}
+	type fields struct {
+		log  log.Logger
+		repo Repository
+	}
+	type args struct {
+		request dtos.SearchRequest
+		filter  Filter
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    []dtos.SearchReceiverResponse
+		wantErr bool
+	}{
+		{
+			name: "Should return receivers with a limit of 10 and highlight the matched fields",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					SearchMock: func(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error) {
+						if limit != 10 || query.TSQuery() != "jhon:*" || query.Document != "" {
+							return nil, errors.New("unexpected search")
+						}
+						return []dtos.SearchReceiverResponse{
+							{GetReceiverResponse: jhones, Rank: 0.6},
+							{GetReceiverResponse: cormier, Rank: 0.6},
+						}, nil
+					},
+				},
+			},
+			args: args{request: dtos.SearchRequest{
+				Query: "Jhon",
+			}},
+			want: []dtos.SearchReceiverResponse{
+				{GetReceiverResponse: jhones, Rank: 0.6, Highlights: map[string]string{"Name": "<mark>Jhon</mark> <mark>Jhon</mark>es"}},
+				{GetReceiverResponse: cormier, Rank: 0.6, Highlights: map[string]string{"Name": "<mark>Jhon</mark> Cormier"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Should search a masked document by its digits",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					SearchMock: func(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error) {
+						if query.Document != "08412535952" {
+							return nil, errors.New("unexpected search")
+						}
+						return []dtos.SearchReceiverResponse{{GetReceiverResponse: jhones, Rank: 1}}, nil
+					},
+				},
+			},
+			args: args{request: dtos.SearchRequest{
+				Query: " 084.125.359-52 ",
+			}},
+			want: []dtos.SearchReceiverResponse{
+				{GetReceiverResponse: jhones, Rank: 1, Highlights: map[string]string{
+					"Document": "<mark>08412535952</mark>",
+					"Pixkey":   "<mark>08412535952</mark>",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Should match accents and case in the highlights",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					SearchMock: func(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error) {
+						if limit != 100 || query.TSQuery() != "conceicao:*" || filter.Sort.Field != SortName {
+							return nil, errors.New("unexpected search")
+						}
+						return []dtos.SearchReceiverResponse{{GetReceiverResponse: conceicao, Rank: 0.9}}, nil
+					},
 				},
 			},
+			args: args{
+				request: dtos.SearchRequest{Query: "CONCEICAO", Limit: 120},
+				filter:  Filter{Sort: Sort{Field: SortName}},
+			},
+			want: []dtos.SearchReceiverResponse{
+				{GetReceiverResponse: conceicao, Rank: 0.9, Highlights: map[string]string{
+					"Name":  "Maria da <mark>Conceição</mark>",
+					"Email": "maria@<mark>conceicao</mark>.com",
+				}},
+			},
 			wantErr: false,
 		},
+		{
+			name: "Should filter by a query of the search query language sorted by name",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					SearchMock: func(query SearchQuery, filter Filter, limit int) ([]dtos.SearchReceiverResponse, error) {
+						want := &Condition{And: []Condition{{Field: FieldStatus, Value: "draft"}, {Field: FieldName, Value: "jhon"}}}
+						if query.Text != "" || !reflect.DeepEqual(filter.Condition, want) || filter.Sort.Field != SortName {
+							return nil, errors.New("unexpected search")
+						}
+						return []dtos.SearchReceiverResponse{{GetReceiverResponse: jhones}}, nil
+					},
+				},
+			},
+			args: args{request: dtos.SearchRequest{Query: "status:draft name:jhon", Syntax: SyntaxQuery}},
+			want: []dtos.SearchReceiverResponse{{GetReceiverResponse: jhones, Highlights: map[string]string{}}},
+		},
+		{
+			name:    "Should refuse a query with a syntax error",
+			fields:  fields{log: log.MockLogger{}, repo: receiverRepoMock{}},
+			args:    args{request: dtos.SearchRequest{Query: "status:draft OR", Syntax: SyntaxQuery}},
+			wantErr: true,
+		},
+		{
+			name:    "Should refuse an unknown syntax",
+			fields:  fields{log: log.MockLogger{}, repo: receiverRepoMock{}},
+			args:    args{request: dtos.SearchRequest{Query: "jhon", Syntax: "regex"}},
+			wantErr: true,
+		},
 		{
 			name: "Should return an error on search",
 			fields: fields{
 				log:  log.MockLogger{},
 				repo: receiverRepoMock{Err: sql.ErrNoRows},
 			},
-			args: args{dtos.SearchRequest{
+			args: args{request: dtos.SearchRequest{
 				Query: "Jhan",
 				Limit: 120,
 			}},
@@ -458,7 +1045,7 @@ func TestService_SearchReceivers(t *testing.T) {
 				log:  tt.fields.log,
 				repo: tt.fields.repo,
 			}
-			got, err := s.SearchReceivers(tt.args.request)
+			got, err := s.SearchReceivers(tt.args.request, tt.args.filter)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SearchReceivers() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -470,6 +1057,32 @@ func TestService_SearchReceivers(t *testing.T) {
 	}
 }
 
+func Test_highlight(t *testing.T) {
+	tests := []struct {
+		value  string
+		terms  []string
+		digits string
+		want   string
+		wantOk bool
+	}{
+		{"João da Silva", []string{"joao"}, "", "<mark>João</mark> da Silva", true},
+		{"João da Silva", []string{"sil", "da"}, "", "João <mark>da</mark> <mark>Sil</mark>va", true},
+		{"Jhon Jhones", []string{"jhon"}, "", "<mark>Jhon</mark> <mark>Jhon</mark>es", true},
+		{"Anna Hanna", []string{"ann"}, "", "<mark>Ann</mark>a Hanna", true},
+		{"Chad <Smith> & Co", []string{"smith"}, "", "Chad &lt;<mark>Smith</mark>&gt; &amp; Co", true},
+		{"47155059080", nil, "550590", "471<mark>550590</mark>80", true},
+		{"Chad Smith", []string{"flea"}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := highlight(tt.value, tt.terms, tt.digits)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("highlight() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
 func TestService_GetReceiver(t *testing.T) {
 	type fields struct {
 		log  log.Logger
@@ -541,3 +1154,445 @@ func TestService_GetReceiver(t *testing.T) {
 		})
 	}
 }
+
+func TestService_AddPixKey(t *testing.T) {
+	getByID := func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+		return &dtos.GetReceiverResponse{Id: id, Document: "47155059080"}, nil
+	}
+	type fields struct {
+		log  log.Logger
+		repo Repository
+	}
+	type args struct {
+		id  string
+		req dtos.AddPixKeyRequest
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		args        args
+		expectedErr error
+	}{
+		{
+			name: "Should add an email key with success",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					GetByIDMock:   getByID,
+					AddPixKeyMock: func(key *entity.ReceiverPixKey) error { return nil },
+				},
+			},
+			args: args{"624b2913-ecf3-4445-9b68-588e41038593", dtos.AddPixKeyRequest{
+				PixKeyType: vo.EmailKey,
+				PixKey:     "chadsmith@rhcp.com",
+			}},
+		},
+		{
+			name: "Should return an err for a cpf key of another document",
+			fields: fields{
+				log:  log.MockLogger{},
+				repo: receiverRepoMock{GetByIDMock: getByID},
+			},
+			args: args{"624b2913-ecf3-4445-9b68-588e41038593", dtos.AddPixKeyRequest{
+				PixKeyType: vo.CPFKey,
+				PixKey:     "419.267.660-59",
+			}},
+			expectedErr: entity.ErrPixKeyNotOwned,
+		},
+		{
+			name: "Should return an err for a key already registered",
+			fields: fields{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					GetByIDMock:   getByID,
+					AddPixKeyMock: func(key *entity.ReceiverPixKey) error { return ErrPixKeyAlreadyRegistered },
+				},
+			},
+			args: args{"624b2913-ecf3-4445-9b68-588e41038593", dtos.AddPixKeyRequest{
+				PixKeyType: vo.EmailKey,
+				PixKey:     "chadsmith@rhcp.com",
+			}},
+			expectedErr: ErrPixKeyAlreadyRegistered,
+		},
+		{
+			name: "Should return an err if receiver not found",
+			fields: fields{
+				log:  log.MockLogger{},
+				repo: receiverRepoMock{Err: ErrReceiverNotFound},
+			},
+			args: args{"624b2913-ecf3-4445-9b68-588e41038593", dtos.AddPixKeyRequest{
+				PixKeyType: vo.EmailKey,
+				PixKey:     "chadsmith@rhcp.com",
+			}},
+			expectedErr: ErrReceiverNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  tt.fields.log,
+				repo: tt.fields.repo,
+			}
+			_, err := s.AddPixKey(dtos.Audit{}, tt.args.id, tt.args.req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("AddPixKey() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestService_RemovePixKey(t *testing.T) {
+	const receiverID = "624b2913-ecf3-4445-9b68-588e41038593"
+	const keyID = "7391dab2-20a3-42be-982e-6f46d6319fad"
+	singleKey := func(receiverID uuid.UUID) ([]dtos.PixKeyResponse, error) {
+		return []dtos.PixKeyResponse{{Id: uuid.MustParse(keyID), Preferred: true}}, nil
+	}
+	tests := []struct {
+		name        string
+		repo        Repository
+		expectedErr error
+	}{
+		{
+			name: "Should not remove the last key of a receiver without bank account",
+			repo: receiverRepoMock{
+				GetByIDMock: func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+					return &dtos.GetReceiverResponse{Id: id}, nil
+				},
+				ListPixKeysMock: singleKey,
+			},
+			expectedErr: entity.ErrNoPaymentDestination,
+		},
+		{
+			name: "Should remove the last key of a receiver with bank account",
+			repo: receiverRepoMock{
+				GetByIDMock: func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+					return &dtos.GetReceiverResponse{Id: id, BankCode: "341"}, nil
+				},
+				ListPixKeysMock:  singleKey,
+				RemovePixKeyMock: func(receiverID, keyID uuid.UUID) error { return nil },
+			},
+		},
+		{
+			name: "Should return an err for an unknown key",
+			repo: receiverRepoMock{
+				GetByIDMock: func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+					return &dtos.GetReceiverResponse{Id: id}, nil
+				},
+				ListPixKeysMock: func(receiverID uuid.UUID) ([]dtos.PixKeyResponse, error) {
+					return []dtos.PixKeyResponse{{Id: uuid.New()}, {Id: uuid.New()}}, nil
+				},
+				RemovePixKeyMock: func(receiverID, keyID uuid.UUID) error { return ErrPixKeyNotFound },
+			},
+			expectedErr: ErrPixKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  log.MockLogger{},
+				repo: tt.repo,
+			}
+			if err := s.RemovePixKey(dtos.Audit{}, receiverID, keyID); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("RemovePixKey() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestService_GenerateBRCode(t *testing.T) {
+	const receiverID = "624b2913-ecf3-4445-9b68-588e41038593"
+	withKey := receiverRepoMock{GetByIDMock: func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+		return &dtos.GetReceiverResponse{Id: id, Name: "Fulano de Tal", Pixkey: "123e4567-e12b-12d1-a456-426655440000"}, nil
+	}}
+	tests := []struct {
+		name        string
+		repo        Repository
+		cfg         Config
+		req         dtos.BRCodeRequest
+		want        string
+		expectedErr error
+	}{
+		{
+			name: "Should generate the br code with the configured city",
+			repo: withKey,
+			cfg:  Config{BRCodeCity: "BRASILIA"},
+			want: "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D",
+		},
+		{
+			name:        "Should require a city when none is configured",
+			repo:        withKey,
+			expectedErr: brcode.ErrMissingMerchantCity,
+		},
+		{
+			name: "Should return an err for a receiver paid only by TED",
+			repo: receiverRepoMock{GetByIDMock: func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+				return &dtos.GetReceiverResponse{Id: id, Name: "Fulano de Tal", BankCode: "341"}, nil
+			}},
+			cfg:         Config{BRCodeCity: "BRASILIA"},
+			expectedErr: ErrReceiverWithoutPixKey,
+		},
+		{
+			name:        "Should return an err for an unknown receiver",
+			repo:        receiverRepoMock{Err: ErrReceiverNotFound},
+			expectedErr: ErrReceiverNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  log.MockLogger{},
+				repo: tt.repo,
+				cfg:  tt.cfg,
+			}
+			got, err := s.GenerateBRCode(receiverID, tt.req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("GenerateBRCode() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateBRCode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_ValidateReceiver(t *testing.T) {
+	const receiverID = "624b2913-ecf3-4445-9b68-588e41038593"
+	directory := keyDirectoryMock{
+		"lucasszmt@gmail.com": {Document: "084.125.359-52", Name: "Lucas Szeremeta", ISPB: "60701190"},
+		"+5541999999999":      {Document: "41926766059", Name: "Ian Mcgregor", ISPB: "18236120"},
+	}
+	stored := func(status, key string) func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+		return func(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
+			return &dtos.GetReceiverResponse{Id: id, Document: "08412535952", Pixkey: key, Status: status}, nil
+		}
+	}
+	tests := []struct {
+		name        string
+		getByID     func(id uuid.UUID) (*dtos.GetReceiverResponse, error)
+		want        *dtos.ValidationResponse
+		wantReason  string
+		expectedErr error
+	}{
+		{
+			name:    "Should validate a receiver owning the key",
+			getByID: stored("draft", "lucasszmt@gmail.com"),
+			want:    &dtos.ValidationResponse{Valid: true, OwnerName: "Lucas Szeremeta", ISPB: "60701190"},
+		},
+		{
+			name:       "Should record a key of another document",
+			getByID:    stored("draft", "+5541999999999"),
+			want:       &dtos.ValidationResponse{Reason: ErrDocumentMismatch.Error(), OwnerName: "Ian Mcgregor", ISPB: "18236120"},
+			wantReason: ErrDocumentMismatch.Error(),
+		},
+		{
+			name:       "Should record a key missing from the directory",
+			getByID:    stored("draft", "+5511988888888"),
+			want:       &dtos.ValidationResponse{Reason: ErrKeyNotInDirectory.Error()},
+			wantReason: ErrKeyNotInDirectory.Error(),
+		},
+		{
+			name:        "Should only validate draft receivers",
+			getByID:     stored("blocked", "lucasszmt@gmail.com"),
+			expectedErr: entity.ErrInvalidStatusTransition,
+		},
+		{
+			name:        "Should require a pix key",
+			getByID:     stored("draft", ""),
+			expectedErr: ErrReceiverWithoutPixKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var savedReason string
+			s := &Service{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{
+					GetByIDMock: tt.getByID,
+					SaveValidationMock: func(id uuid.UUID, reason string) error {
+						savedReason = reason
+						return nil
+					},
+				},
+				directory: directory,
+			}
+			got, err := s.ValidateReceiver(dtos.Audit{}, receiverID)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("ValidateReceiver() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateReceiver() got = %+v, want %+v", got, tt.want)
+			}
+			if savedReason != tt.wantReason {
+				t.Errorf("ValidateReceiver() saved reason = %v, want %v", savedReason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestService_RestoreReceivers(t *testing.T) {
+	ids := []uuid.UUID{uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593")}
+	existing := uuid.MustParse("7391dab2-20a3-42be-982e-6f46d6319fad")
+	tests := []struct {
+		name        string
+		repo        receiverRepoMock
+		policy      DuplicateDocumentPolicy
+		expectedErr error
+	}{
+		{
+			name: "Should restore the deleted receivers",
+			repo: receiverRepoMock{RestoreMock: func(uniqueDocument bool, id ...uuid.UUID) (int64, error) {
+				return int64(len(id)), nil
+			}},
+		},
+		{
+			name: "Should return not found when no receiver was restored",
+			repo: receiverRepoMock{RestoreMock: func(uniqueDocument bool, id ...uuid.UUID) (int64, error) {
+				return 0, nil
+			}},
+			expectedErr: ErrReceiverNotFound,
+		},
+		{
+			name:   "Should refuse to restore a receiver whose document was taken when duplicates are rejected",
+			policy: RejectDuplicateDocuments,
+			repo: receiverRepoMock{RestoreMock: func(uniqueDocument bool, id ...uuid.UUID) (int64, error) {
+				if !uniqueDocument {
+					return int64(len(id)), nil
+				}
+				return 0, &DuplicateReceiverError{ExistingID: existing, Field: "document"}
+			}},
+			expectedErr: ErrReceiverAlreadyExists,
+		},
+		{
+			name:   "Should restore a receiver whose document was taken when duplicates are allowed",
+			policy: AllowDuplicateDocuments,
+			repo: receiverRepoMock{RestoreMock: func(uniqueDocument bool, id ...uuid.UUID) (int64, error) {
+				if !uniqueDocument {
+					return int64(len(id)), nil
+				}
+				return 0, &DuplicateReceiverError{ExistingID: existing, Field: "document"}
+			}},
+		},
+		{
+			name:        "Should return the repo err",
+			repo:        receiverRepoMock{Err: sql.ErrConnDone},
+			expectedErr: sql.ErrConnDone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  log.MockLogger{},
+				repo: tt.repo,
+				cfg:  Config{DuplicateDocuments: tt.policy},
+			}
+			if err := s.RestoreReceivers(dtos.Audit{}, dtos.RestoreReceiversRequest{Ids: ids}); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("RestoreReceivers() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestService_PurgeDeleted(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		want      int64
+		wantPurge bool
+	}{
+		{"Should purge receivers deleted before the retention", 24 * time.Hour, 2, true},
+		{"Should not purge without a retention", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purged := false
+			s := &Service{
+				log: log.MockLogger{},
+				repo: receiverRepoMock{PurgeMock: func(retention time.Duration) (int64, error) {
+					purged = true
+					if retention != tt.retention {
+						return 0, errors.New("purging with another retention")
+					}
+					return 2, nil
+				}},
+				cfg: Config{DeletedRetention: tt.retention},
+			}
+			got, err := s.PurgeDeleted()
+			if err != nil {
+				t.Fatalf("PurgeDeleted() error = %v", err)
+			}
+			if got != tt.want || purged != tt.wantPurge {
+				t.Errorf("PurgeDeleted() got = %v, purged %v, want %v, purged %v", got, purged, tt.want, tt.wantPurge)
+			}
+		})
+	}
+}
+
+func TestService_ReceiverHistory(t *testing.T) {
+	const receiverID = "624b2913-ecf3-4445-9b68-588e41038593"
+	entries := []dtos.HistoryEntryResponse{
+		{Action: "create", After: []byte(`{"name":"Chad Smith"}`), Actor: "operator", RequestID: "1"},
+		{Action: "update", Before: []byte(`{"name":"Chad Smith"}`), After: []byte(`{"name":"Chad Smit"}`)},
+	}
+	tests := []struct {
+		name        string
+		repo        receiverRepoMock
+		want        []dtos.HistoryEntryResponse
+		expectedErr error
+	}{
+		{
+			name: "Should return the receiver history",
+			repo: receiverRepoMock{HistoryMock: func(id uuid.UUID) ([]dtos.HistoryEntryResponse, error) {
+				return entries, nil
+			}},
+			want: entries,
+		},
+		{
+			name: "Should return not found for a receiver without history",
+			repo: receiverRepoMock{HistoryMock: func(id uuid.UUID) ([]dtos.HistoryEntryResponse, error) {
+				return []dtos.HistoryEntryResponse{}, nil
+			}},
+			expectedErr: ErrReceiverNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				log:  log.MockLogger{},
+				repo: tt.repo,
+			}
+			got, err := s.ReceiverHistory(receiverID)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("ReceiverHistory() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReceiverHistory() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuplicateDocumentPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    DuplicateDocumentPolicy
+		wantErr bool
+	}{
+		{"", AllowDuplicateDocuments, false},
+		{"allow", AllowDuplicateDocuments, false},
+		{"REJECT", RejectDuplicateDocuments, false},
+		{" reject ", RejectDuplicateDocuments, false},
+		{"rejects", "", true},
+		{"deny", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuplicateDocumentPolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuplicateDocumentPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuplicateDocumentPolicy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package vo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BankCodeRegexp accepts both the 3 digits COMPE code and the 8 digits ISPB of the institution
+var (
+	BankCodeRegexp      = regexp.MustCompile(`^(?:\d{3}|\d{8})$`)
+	BranchRegexp        = regexp.MustCompile(`^\d{1,4}$`)
+	AccountNumberRegexp = regexp.MustCompile(`^\d{1,20}$`)
+	AccountDigitRegexp  = regexp.MustCompile(`^[0-9X]$`)
+)
+
+type AccountType string
+
+const (
+	CheckingAccount AccountType = "checking"
+	SavingsAccount  AccountType = "savings"
+	PaymentAccount  AccountType = "payment"
+)
+
+// BankAccount is a TED destination, identified by the institution, the branch and the account with its check digit
+type BankAccount struct {
+	bankCode    string
+	branch      string
+	number      string
+	digit       string
+	accountType AccountType
+}
+
+// NewBankAccount validates a bank account, the usual masks are removed from the branch and the account number,
+// and the branch is left padded to its 4 digits
+func NewBankAccount(bankCode, branch, number, digit string, accountType AccountType) (*BankAccount, error) {
+	bankCode = strings.TrimSpace(bankCode)
+	if !BankCodeRegexp.MatchString(bankCode) {
+		return nil, ErrInvalidBankCode
+	}
+	branch = removeMask(branch)
+	if !BranchRegexp.MatchString(branch) {
+		return nil, ErrInvalidBranch
+	}
+	number = removeMask(number)
+	if !AccountNumberRegexp.MatchString(number) {
+		return nil, ErrInvalidAccountNumber
+	}
+	digit = strings.ToUpper(strings.TrimSpace(digit))
+	if !AccountDigitRegexp.MatchString(digit) {
+		return nil, ErrInvalidAccountDigit
+	}
+	switch accountType {
+	case CheckingAccount, SavingsAccount, PaymentAccount:
+	default:
+		return nil, ErrInvalidAccountType
+	}
+	return &BankAccount{
+		bankCode:    bankCode,
+		branch:      strings.Repeat("0", 4-len(branch)) + branch,
+		number:      number,
+		digit:       digit,
+		accountType: accountType,
+	}, nil
+}
+
+func (b *BankAccount) BankCode() string {
+	return b.bankCode
+}
+
+func (b *BankAccount) Branch() string {
+	return b.branch
+}
+
+func (b *BankAccount) Number() string {
+	return b.number
+}
+
+func (b *BankAccount) Digit() string {
+	return b.digit
+}
+
+func (b *BankAccount) AccountType() AccountType {
+	return b.accountType
+}
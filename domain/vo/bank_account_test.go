@@ -0,0 +1,78 @@
+package vo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewBankAccount(t *testing.T) {
+	type args struct {
+		bankCode    string
+		branch      string
+		number      string
+		digit       string
+		accountType AccountType
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *BankAccount
+		wantErr error
+	}{
+		{
+			"Should create a checking account with a COMPE code",
+			args{"341", "0123", "12345", "6", CheckingAccount},
+			&BankAccount{"341", "0123", "12345", "6", CheckingAccount},
+			nil,
+		},
+		{
+			"Should create a savings account with an ISPB and pad the branch",
+			args{"60701190", "12", "12.345", "x", SavingsAccount},
+			&BankAccount{"60701190", "0012", "12345", "X", SavingsAccount},
+			nil,
+		},
+		{
+			"Should not create an account with an invalid bank code",
+			args{"34", "0123", "12345", "6", CheckingAccount},
+			nil,
+			ErrInvalidBankCode,
+		},
+		{
+			"Should not create an account with an invalid branch",
+			args{"341", "12345", "12345", "6", CheckingAccount},
+			nil,
+			ErrInvalidBranch,
+		},
+		{
+			"Should not create an account with an invalid number",
+			args{"341", "0123", "", "6", CheckingAccount},
+			nil,
+			ErrInvalidAccountNumber,
+		},
+		{
+			"Should not create an account with an invalid digit",
+			args{"341", "0123", "12345", "66", CheckingAccount},
+			nil,
+			ErrInvalidAccountDigit,
+		},
+		{
+			"Should not create an account with an unknown type",
+			args{"341", "0123", "12345", "6", AccountType("investment")},
+			nil,
+			ErrInvalidAccountType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBankAccount(tt.args.bankCode, tt.args.branch, tt.args.number, tt.args.digit, tt.args.accountType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewBankAccount() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewBankAccount() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
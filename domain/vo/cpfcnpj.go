@@ -3,12 +3,20 @@ package vo
 import (
 	"bytes"
 	"regexp"
+	"strings"
 	"unicode"
 )
 
+// CNPJRegexp matches both the numeric and the alphanumeric CNPJ, where the first 12 positions may hold
+// uppercase letters and only the check digits are guaranteed to be numbers
 var (
 	CPFRegexp  = regexp.MustCompile(`^\d{3}\.?\d{3}\.?\d{3}-?\d{2}$`)
-	CNPJRegexp = regexp.MustCompile(`^\d{2}\.?\d{3}\.?\d{3}\/?(:?\d{3}[1-9]|\d{2}[1-9]\d|\d[1-9]\d{2}|[1-9]\d{3})-?\d{2}$`)
+	CNPJRegexp = regexp.MustCompile(`^[0-9A-Z]{2}\.?[0-9A-Z]{3}\.?[0-9A-Z]{3}\/?(?:[0-9A-Z]{3}[1-9A-Z]|[0-9A-Z]{2}[1-9A-Z][0-9A-Z]|[0-9A-Z][1-9A-Z][0-9A-Z]{2}|[1-9A-Z][0-9A-Z]{3})-?\d{2}$`)
+)
+
+var (
+	cpfWeights  = []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}
+	cnpjWeights = []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
 )
 
 type DocType string
@@ -23,21 +31,23 @@ type CpfCnpj struct {
 	docType DocType
 }
 
-// TODO arrumar, está aceitando criar uma chave CNPJ on de é passado um cpf
 func NewCpfCnpj(doc string) (*CpfCnpj, error) {
-	//TODO improve CPF/CNPJ validation with sum of digits
 	cpfcpnj := new(CpfCnpj)
-	if CPFRegexp.MatchString(doc) {
+	doc = strings.ToUpper(strings.TrimSpace(doc))
+	switch {
+	case CPFRegexp.MatchString(doc):
 		cpfcpnj.SetValue(doc)
 		cpfcpnj.SetType(CPF)
-		return cpfcpnj, nil
-	}
-	if CNPJRegexp.MatchString(doc) {
+	case CNPJRegexp.MatchString(doc):
 		cpfcpnj.SetValue(doc)
 		cpfcpnj.SetType(CNPJ)
-		return cpfcpnj, nil
+	default:
+		return nil, ErrInvalidCPFCNPJ
+	}
+	if err := cpfcpnj.validateCheckDigits(); err != nil {
+		return nil, err
 	}
-	return nil, ErrInvalidCPFCNPJ
+	return cpfcpnj, nil
 }
 
 func (c *CpfCnpj) GetType() DocType {
@@ -53,14 +63,59 @@ func (c *CpfCnpj) SetType(docType DocType) {
 }
 
 func (c *CpfCnpj) SetValue(doc string) {
-	c.value = removeNonDigits(doc)
+	c.value = removeMask(doc)
+}
+
+// validateCheckDigits runs the modulo 11 verification over the two last digits of the document,
+// documents made of a single repeated digit pass the modulo 11 check, so they are refused beforehand
+func (c *CpfCnpj) validateCheckDigits() error {
+	if isRepeatedSequence(c.value) {
+		return ErrRepeatedDigitsDoc
+	}
+	weights, invalidErr := cpfWeights, ErrInvalidCPFCheckDigits
+	if c.docType == CNPJ {
+		weights, invalidErr = cnpjWeights, ErrInvalidCNPJCheckDigits
+	}
+	size := len(c.value) - 2
+	first := checkDigit(c.value[:size], weights[1:])
+	second := checkDigit(c.value[:size]+string(first), weights)
+	if c.value[size] != first || c.value[size+1] != second {
+		return invalidErr
+	}
+	return nil
+}
+
+// checkDigit calculates the modulo 11 check digit of base, weights are aligned to the right of base.
+// Each character is worth its ASCII code minus 48, so digits keep their value and letters, used by the
+// alphanumeric CNPJ, start at 17 for 'A'
+func checkDigit(base string, weights []int) byte {
+	weights = weights[len(weights)-len(base):]
+	sum := 0
+	for i := range base {
+		sum += int(base[i]-'0') * weights[i]
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+func isRepeatedSequence(doc string) bool {
+	for i := 1; i < len(doc); i++ {
+		if doc[i] != doc[0] {
+			return false
+		}
+	}
+	return true
 }
 
-func removeNonDigits(doc string) string {
+// removeMask strips the document punctuation, keeping only digits and uppercased letters
+func removeMask(doc string) string {
 	buf := bytes.NewBufferString("")
 	for _, r := range doc {
-		if unicode.IsDigit(r) {
-			buf.WriteRune(r)
+		if unicode.IsDigit(r) || unicode.IsLetter(r) {
+			buf.WriteRune(unicode.ToUpper(r))
 		}
 	}
 	return buf.String()
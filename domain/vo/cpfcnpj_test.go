@@ -1,6 +1,7 @@
 package vo
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -39,6 +40,48 @@ func TestNewCpfCnpj(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"Should create a new alphanumeric CNPJ with success",
+			args{"12.ABC.345/01DE-35"},
+			&CpfCnpj{"12ABC34501DE35", CNPJ},
+			false,
+		},
+		{
+			"Should normalize a lowercase alphanumeric CNPJ",
+			args{"12abc34501de35"},
+			&CpfCnpj{"12ABC34501DE35", CNPJ},
+			false,
+		},
+		{
+			"Should get an err for an alphanumeric CNPJ with wrong check digits",
+			args{"12.ABC.345/01DE-53"},
+			nil,
+			true,
+		},
+		{
+			"Should get an err for an alphanumeric CNPJ with letters on the check digits",
+			args{"12.ABC.345/01DE-3A"},
+			nil,
+			true,
+		},
+		{
+			"Should get an err for a CPF with wrong check digits",
+			args{"471.550.590-81"},
+			nil,
+			true,
+		},
+		{
+			"Should get an err for a CNPJ with wrong check digits",
+			args{"84.811.956/0001-36"},
+			nil,
+			true,
+		},
+		{
+			"Should get an err for a CPF made of repeated digits",
+			args{"111.111.111-11"},
+			nil,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -54,7 +97,27 @@ func TestNewCpfCnpj(t *testing.T) {
 	}
 }
 
-func Test_removeNonDigits(t *testing.T) {
+func TestNewCpfCnpj_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want error
+	}{
+		{"Should return an invalid format err", "471.550.59-80", ErrInvalidCPFCNPJ},
+		{"Should return an invalid cpf check digits err", "471.550.590-81", ErrInvalidCPFCheckDigits},
+		{"Should return an invalid cnpj check digits err", "84.811.956/0001-36", ErrInvalidCNPJCheckDigits},
+		{"Should return a repeated digits err", "111.111.111-11", ErrRepeatedDigitsDoc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCpfCnpj(tt.doc); !errors.Is(err, tt.want) {
+				t.Errorf("NewCpfCnpj() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_removeMask(t *testing.T) {
 	type args struct {
 		doc string
 	}
@@ -69,11 +132,13 @@ func Test_removeNonDigits(t *testing.T) {
 		{"Should remove non digits from CNPJ", args{doc: "84.811.956/0001-63"}, "84811956000163"},
 		{"Should remove dots from CNPJ", args{doc: "84.811.956000163"}, "84811956000163"},
 		{"Should remove '/' & '-' from CNPJ", args{doc: "84811956/0001-63"}, "84811956000163"},
+		{"Should keep letters from alphanumeric CNPJ", args{doc: "12.ABC.345/01DE-35"}, "12ABC34501DE35"},
+		{"Should uppercase letters from alphanumeric CNPJ", args{doc: "12.abc.345/01de-35"}, "12ABC34501DE35"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := removeNonDigits(tt.args.doc); got != tt.want {
-				t.Errorf("removeNonDigits() = %v, want %v", got, tt.want)
+			if got := removeMask(tt.args.doc); got != tt.want {
+				t.Errorf("removeMask() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -1,17 +1,78 @@
 package vo
 
-import "net/mail"
+import (
+	"net"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Length limits from RFC 5321, the whole path is limited to 256 octets including the angle brackets
+const (
+	MaxEmailLocalLength = 64
+	MaxEmailLength      = 254
+)
 
 type EmailAddress string
 
+// NewEmail validates a bare email address and returns it in its canonical form, with the domain lowercased
+// and converted to punycode. The local part is kept as provided since it's case-sensitive by the RFC
 func NewEmail(address string) (EmailAddress, error) {
+	address = strings.TrimSpace(address)
 	addr, err := mail.ParseAddress(address)
-	if err != nil {
+	if err != nil || addr.Name != "" || addr.Address != address {
 		return "", ErrInvalidEmail
 	}
-	return EmailAddress(addr.Address), err
+	at := strings.LastIndex(address, "@")
+	local, domain := address[:at], address[at+1:]
+	if len(local) > MaxEmailLocalLength {
+		return "", ErrEmailTooLong
+	}
+	domain, err = normalizeEmailDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	email := local + "@" + domain
+	if len(email) > MaxEmailLength {
+		return "", ErrEmailTooLong
+	}
+	return EmailAddress(email), nil
 }
 
 func (e EmailAddress) GetEmail() string {
 	return string(e)
 }
+
+// normalizeEmailDomain refuses IP hosts and domains without a TLD, converting the remaining ones to
+// lowercased punycode
+func normalizeEmailDomain(domain string) (string, error) {
+	if strings.HasPrefix(domain, "[") || net.ParseIP(domain) != nil {
+		return "", ErrInvalidEmailDomain
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", ErrInvalidEmailDomain
+	}
+	ascii = strings.ToLower(ascii)
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 || !isTLD(labels[len(labels)-1]) {
+		return "", ErrInvalidEmailDomain
+	}
+	return ascii, nil
+}
+
+func isTLD(label string) bool {
+	if strings.HasPrefix(label, "xn--") {
+		return true
+	}
+	if len(label) < 2 {
+		return false
+	}
+	for _, r := range label {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
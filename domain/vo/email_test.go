@@ -1,6 +1,9 @@
 package vo
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestEmailAddress_GetEmail(t *testing.T) {
 	tests := []struct {
@@ -44,6 +47,48 @@ func TestNewEmail(t *testing.T) {
 			EmailAddress(""),
 			true,
 		},
+		{
+			"Should lowercase the domain and keep the local part",
+			args{"Dwayne.Johnson@GMail.COM"},
+			EmailAddress("Dwayne.Johnson@gmail.com"),
+			false,
+		},
+		{
+			"Should convert an internationalized domain to punycode",
+			args{"hans@Müller.de"},
+			EmailAddress("hans@xn--mller-kva.de"),
+			false,
+		},
+		{
+			"Should not accept a display name",
+			args{"Bob <bob@x.com>"},
+			EmailAddress(""),
+			true,
+		},
+		{
+			"Should not accept a domain without a TLD",
+			args{"bob@localhost"},
+			EmailAddress(""),
+			true,
+		},
+		{
+			"Should not accept an IP literal host",
+			args{"bob@[192.168.0.1]"},
+			EmailAddress(""),
+			true,
+		},
+		{
+			"Should not accept a local part longer than 64 characters",
+			args{strings.Repeat("a", 65) + "@gmail.com"},
+			EmailAddress(""),
+			true,
+		},
+		{
+			"Should not accept an address longer than 254 characters",
+			args{strings.Repeat("a", 60) + "@" + strings.Repeat("b", 63) + "." + strings.Repeat("c", 63) + "." + strings.Repeat("d", 63) + ".com"},
+			EmailAddress(""),
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
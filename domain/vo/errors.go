@@ -3,10 +3,23 @@ package vo
 import "errors"
 
 var (
-	ErrInvalidName       = errors.New("invalid name provided")
-	ErrInvalidEmail      = errors.New("invalid email provided")
-	ErrInvalidCPFCNPJ    = errors.New("invalid cpf or cnpj provided")
-	ErrInvalidPhone      = errors.New("invalid phone provided")
-	ErrInvalidRandomKey  = errors.New("invalid random key provided")
-	ErrInvalidPixKeyType = errors.New("invalid pix key type provided")
+	ErrInvalidName            = errors.New("invalid name provided")
+	ErrNameTooLong            = errors.New("name longer than 255 characters provided")
+	ErrInvalidEmail           = errors.New("invalid email provided")
+	ErrInvalidEmailDomain     = errors.New("invalid email domain provided")
+	ErrEmailTooLong           = errors.New("email longer than allowed provided")
+	ErrInvalidCPFCNPJ         = errors.New("invalid cpf or cnpj provided")
+	ErrInvalidCPFCheckDigits  = errors.New("invalid cpf check digits provided")
+	ErrInvalidCNPJCheckDigits = errors.New("invalid cnpj check digits provided")
+	ErrRepeatedDigitsDoc      = errors.New("cpf or cnpj made of repeated digits provided")
+	ErrInvalidPhone           = errors.New("invalid phone provided")
+	ErrInvalidPhoneDDD        = errors.New("invalid phone area code (DDD) provided")
+	ErrInvalidRandomKey       = errors.New("invalid random key provided")
+	ErrInvalidPixKeyType      = errors.New("invalid pix key type provided")
+	ErrPixKeyDocTypeMismatch  = errors.New("pix key value does not match the declared cpf or cnpj key type")
+	ErrInvalidBankCode        = errors.New("invalid bank code provided, it should be a COMPE or ISPB code")
+	ErrInvalidBranch          = errors.New("invalid bank branch provided")
+	ErrInvalidAccountNumber   = errors.New("invalid bank account number provided")
+	ErrInvalidAccountDigit    = errors.New("invalid bank account check digit provided")
+	ErrInvalidAccountType     = errors.New("invalid bank account type provided")
 )
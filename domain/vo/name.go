@@ -1,14 +1,38 @@
 package vo
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
 
-var NameRegexp = regexp.MustCompile(`^[a-zA-Z]+(([',. -][a-zA-Z ])?[a-zA-Z]*)*$`)
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxNameLength matches the size of the name column on the receiver table
+const MaxNameLength = 255
+
+// PersonNameRegexp accepts latin letters, accented ones included, split by spaces, apostrophes, hyphens or
+// abbreviation dots, as in "Maria da Conceição", "D'Ávila" or "J. R. Müller"
+// CompanyNameRegexp also accepts digits and the symbols usually found on company names, as in "Casa & Cia 2000 LTDA"
+var (
+	PersonNameRegexp  = regexp.MustCompile(`^\p{Latin}+(?:(?:[',.-] ?| )\p{Latin}+)*\.?$`)
+	CompanyNameRegexp = regexp.MustCompile(`^[\p{Latin}\d][\p{Latin}\d &.,'/()+-]*$`)
+)
 
 type Name string
 
-func NewName(name string) (Name, error) {
-	match := NameRegexp.MatchString(name)
-	if !match {
+// NewName validates a receiver name, using the person name rules for CPF holders and the company name rules
+// for CNPJ holders, surrounding spaces are trimmed and inner ones collapsed before validation
+func NewName(name string, docType DocType) (Name, error) {
+	name = norm.NFC.String(strings.Join(strings.Fields(name), " "))
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrNameTooLong
+	}
+	nameRegexp := PersonNameRegexp
+	if docType == CNPJ {
+		nameRegexp = CompanyNameRegexp
+	}
+	if !nameRegexp.MatchString(name) {
 		return "", ErrInvalidName
 	}
 	return Name(name), nil
@@ -1,10 +1,14 @@
 package vo
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestNewName(t *testing.T) {
 	type args struct {
-		name string
+		name    string
+		docType DocType
 	}
 	tests := []struct {
 		name    string
@@ -14,26 +18,86 @@ func TestNewName(t *testing.T) {
 	}{
 		{
 			"Should create name with success",
-			args{"Jon Cena"},
+			args{"Jon Cena", CPF},
 			Name("Jon Cena"),
 			false,
 		},
 		{
 			"Should not create name",
-			args{"Jon3 Cena"},
+			args{"Jon3 Cena", CPF},
 			Name(""),
 			true,
 		},
 		{
 			"Should not create last name with a number",
-			args{"Jon Cena3"},
+			args{"Jon Cena3", CPF},
+			Name(""),
+			true,
+		},
+		{
+			"Should create name with accented letters",
+			args{"João da Conceição Müller", CPF},
+			Name("João da Conceição Müller"),
+			false,
+		},
+		{
+			"Should create name with apostrophes, hyphens and abbreviations",
+			args{"Ana-Maria D'Ávila J. Souza", CPF},
+			Name("Ana-Maria D'Ávila J. Souza"),
+			false,
+		},
+		{
+			"Should compose decomposed accents",
+			args{"João", CPF},
+			Name("João"),
+			false,
+		},
+		{
+			"Should trim and collapse spaces",
+			args{"  Maria   da  Silva ", CPF},
+			Name("Maria da Silva"),
+			false,
+		},
+		{
+			"Should not create a person name with symbols",
+			args{"Casa & Cia", CPF},
 			Name(""),
 			true,
 		},
+		{
+			"Should create a company name with digits and symbols",
+			args{"Casa & Cia 2000 LTDA", CNPJ},
+			Name("Casa & Cia 2000 LTDA"),
+			false,
+		},
+		{
+			"Should not create a company name starting with a symbol",
+			args{"& Cia LTDA", CNPJ},
+			Name(""),
+			true,
+		},
+		{
+			"Should not create an empty name",
+			args{"   ", CPF},
+			Name(""),
+			true,
+		},
+		{
+			"Should not create a name longer than the column",
+			args{strings.Repeat("á", MaxNameLength+1), CPF},
+			Name(""),
+			true,
+		},
+		{
+			"Should create a name as long as the column",
+			args{strings.Repeat("á", MaxNameLength), CPF},
+			Name(strings.Repeat("á", MaxNameLength)),
+			false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewName(tt.args.name)
+			got, err := NewName(tt.args.name, tt.args.docType)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewName() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -3,13 +3,30 @@ package vo
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
+// PhoneRegexp accepts mobile numbers with or without the country code and with the usual masks,
+// such as "+55 (11) 99999-9999", capturing the DDD, with or without balanced brackets, and the two halves of
+// the number
 var (
-	PhoneRegexp     = regexp.MustCompile(`^((?:\+?55)?)([1-9][0-9])(9[0-9]{8})$`)
+	PhoneRegexp     = regexp.MustCompile(`^(?:\+?55\s?)?(?:\(([1-9][0-9])\)|([1-9][0-9]))\s?(9[0-9]{4})[-\s]?([0-9]{4})$`)
 	RandomKeyRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 )
 
+// brazilianDDDs lists the area codes assigned by Anatel
+var brazilianDDDs = map[string]struct{}{
+	"11": {}, "12": {}, "13": {}, "14": {}, "15": {}, "16": {}, "17": {}, "18": {}, "19": {},
+	"21": {}, "22": {}, "24": {}, "27": {}, "28": {},
+	"31": {}, "32": {}, "33": {}, "34": {}, "35": {}, "37": {}, "38": {},
+	"41": {}, "42": {}, "43": {}, "44": {}, "45": {}, "46": {}, "47": {}, "48": {}, "49": {},
+	"51": {}, "53": {}, "54": {}, "55": {},
+	"61": {}, "62": {}, "63": {}, "64": {}, "65": {}, "66": {}, "67": {}, "68": {}, "69": {},
+	"71": {}, "73": {}, "74": {}, "75": {}, "77": {}, "79": {},
+	"81": {}, "82": {}, "83": {}, "84": {}, "85": {}, "86": {}, "87": {}, "88": {}, "89": {},
+	"91": {}, "92": {}, "93": {}, "94": {}, "95": {}, "96": {}, "97": {}, "98": {}, "99": {},
+}
+
 type PixKeyType string
 
 const (
@@ -28,12 +45,14 @@ type PixKey struct {
 func NewPixKey(keyType PixKeyType, keyValue string) (*PixKey, error) {
 	key := &PixKey{keyType: keyType}
 	switch keyType {
-	//TODO REVIEW THIS
 	case CPFKey, CNPJKey:
 		doc, err := NewCpfCnpj(keyValue)
 		if err != nil {
 			return nil, fmt.Errorf("error creating pix key, cause : %w", err)
 		}
+		if string(doc.GetType()) != string(keyType) {
+			return nil, fmt.Errorf("error creating pix key, cause : %w", ErrPixKeyDocTypeMismatch)
+		}
 		key.value = doc.GetValue()
 	case EmailKey:
 		email, err := NewEmail(keyValue)
@@ -42,10 +61,11 @@ func NewPixKey(keyType PixKeyType, keyValue string) (*PixKey, error) {
 		}
 		key.value = email.GetEmail()
 	case PhoneKey:
-		if err := validatePhone(keyValue); err != nil {
+		phone, err := normalizePhone(keyValue)
+		if err != nil {
 			return nil, fmt.Errorf("error creating pix key, cause : %w", err)
 		}
-		key.value = keyValue
+		key.value = phone
 	case RandomKey:
 		if err := validateRandomKey(keyValue); err != nil {
 			return nil, fmt.Errorf("error creating pix key, cause : %w", err)
@@ -58,6 +78,28 @@ func NewPixKey(keyType PixKeyType, keyValue string) (*PixKey, error) {
 	return key, nil
 }
 
+// DetectPixKeyType infers the type of a key written on its own, as in BR Codes, where phones always
+// carry the +55 prefix and so can't be taken for a CPF. The key is returned as NewPixKey takes it for
+// that type, random keys written in uppercase are lowercased
+func DetectPixKeyType(value string) (PixKeyType, string, error) {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+	switch {
+	case strings.Contains(value, "@"):
+		return EmailKey, value, nil
+	case strings.HasPrefix(value, "+"):
+		return PhoneKey, value, nil
+	case RandomKeyRegexp.MatchString(lower):
+		return RandomKey, lower, nil
+	case CPFRegexp.MatchString(value):
+		return CPFKey, value, nil
+	case CNPJRegexp.MatchString(strings.ToUpper(value)):
+		return CNPJKey, value, nil
+	default:
+		return "", "", ErrInvalidPixKeyType
+	}
+}
+
 func (p *PixKey) KeyType() string {
 	return string(p.keyType)
 }
@@ -70,11 +112,18 @@ func (p *PixKey) Value() string {
 	return p.value
 }
 
-func validatePhone(phoneNumber string) error {
-	if !PhoneRegexp.MatchString(phoneNumber) {
-		return ErrInvalidPhone
+// normalizePhone parses a brazilian mobile number into its E.164 form, +55DDD9XXXXXXXX
+func normalizePhone(phoneNumber string) (string, error) {
+	match := PhoneRegexp.FindStringSubmatch(strings.TrimSpace(phoneNumber))
+	if match == nil {
+		return "", ErrInvalidPhone
 	}
-	return nil
+	// only one of the DDD groups is matched, the other one is empty
+	ddd := match[1] + match[2]
+	if _, ok := brazilianDDDs[ddd]; !ok {
+		return "", ErrInvalidPhoneDDD
+	}
+	return "+55" + ddd + match[3] + match[4], nil
 }
 
 func validateRandomKey(phoneNumber string) error {
@@ -0,0 +1,146 @@
+package vo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewPixKey(t *testing.T) {
+	type args struct {
+		keyType  PixKeyType
+		keyValue string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *PixKey
+		wantErr error
+	}{
+		{
+			"Should create a CPF pix key with success",
+			args{CPFKey, "471.550.590-80"},
+			&PixKey{"47155059080", CPFKey},
+			nil,
+		},
+		{
+			"Should create a CNPJ pix key with success",
+			args{CNPJKey, "84.811.956/0001-63"},
+			&PixKey{"84811956000163", CNPJKey},
+			nil,
+		},
+		{
+			"Should not create a CPF pix key with a CNPJ value",
+			args{CPFKey, "84.811.956/0001-63"},
+			nil,
+			ErrPixKeyDocTypeMismatch,
+		},
+		{
+			"Should not create a CNPJ pix key with a CPF value",
+			args{CNPJKey, "471.550.590-80"},
+			nil,
+			ErrPixKeyDocTypeMismatch,
+		},
+		{
+			"Should normalize a phone pix key without country code",
+			args{PhoneKey, "11999999999"},
+			&PixKey{"+5511999999999", PhoneKey},
+			nil,
+		},
+		{
+			"Should normalize a phone pix key with country code",
+			args{PhoneKey, "5511999999999"},
+			&PixKey{"+5511999999999", PhoneKey},
+			nil,
+		},
+		{
+			"Should keep a phone pix key already in E.164",
+			args{PhoneKey, "+5511999999999"},
+			&PixKey{"+5511999999999", PhoneKey},
+			nil,
+		},
+		{
+			"Should normalize a masked phone pix key",
+			args{PhoneKey, "(11) 99999-9999"},
+			&PixKey{"+5511999999999", PhoneKey},
+			nil,
+		},
+		{
+			"Should normalize a masked phone pix key with country code",
+			args{PhoneKey, "+55 (21) 98765-4321"},
+			&PixKey{"+5521987654321", PhoneKey},
+			nil,
+		},
+		{
+			"Should not create a phone pix key with an unknown DDD",
+			args{PhoneKey, "(20) 99999-9999"},
+			nil,
+			ErrInvalidPhoneDDD,
+		},
+		{
+			"Should not create a landline phone pix key",
+			args{PhoneKey, "(11) 3333-4444"},
+			nil,
+			ErrInvalidPhone,
+		},
+		{
+			"Should not create a phone pix key without the closing bracket of the DDD",
+			args{PhoneKey, "(11 99999-9999"},
+			nil,
+			ErrInvalidPhone,
+		},
+		{
+			"Should not create a phone pix key without the opening bracket of the DDD",
+			args{PhoneKey, "11) 99999-9999"},
+			nil,
+			ErrInvalidPhone,
+		},
+		{
+			"Should not create a pix key of an unknown type",
+			args{PixKeyType("bank"), "471.550.590-80"},
+			nil,
+			ErrInvalidPixKeyType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPixKey(tt.args.keyType, tt.args.keyValue)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewPixKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPixKey() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectPixKeyType(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PixKeyType
+		wantKey string
+		wantErr error
+	}{
+		{"08412535952", CPFKey, "08412535952", nil},
+		{"84811956000163", CNPJKey, "84811956000163", nil},
+		{"12ABC34501DE35", CNPJKey, "12ABC34501DE35", nil},
+		{"lucasszmt@gmail.com", EmailKey, "lucasszmt@gmail.com", nil},
+		{"+5511999999999", PhoneKey, "+5511999999999", nil},
+		{"123e4567-e12b-12d1-a456-426655440000", RandomKey, "123e4567-e12b-12d1-a456-426655440000", nil},
+		{"123E4567-E12B-12D1-A456-426655440000", RandomKey, "123e4567-e12b-12d1-a456-426655440000", nil},
+		{"not a key", "", "", ErrInvalidPixKeyType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, key, err := DetectPixKeyType(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectPixKeyType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || key != tt.wantKey {
+				t.Errorf("DetectPixKeyType() got = %v, %v, want %v, %v", got, key, tt.want, tt.wantKey)
+			}
+		})
+	}
+}
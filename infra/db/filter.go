@@ -0,0 +1,214 @@
+package db
+
+import (
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"strconv"
+	"strings"
+)
+
+// sortColumns are the only columns a sort reaches the sql through, a field missing from it is refused
+var sortColumns = map[receiver.SortField]string{
+	receiver.SortCreatedAt: "r.created_at",
+	receiver.SortName:      "r.name",
+	receiver.SortStatus:    "r.status",
+}
+
+// documentMask removes the mask of a document typed in a search query
+var documentMask = strings.NewReplacer(".", "", "-", "", "/", "", " ", "")
+
+// documentLengths tells the document types apart, as documents are stored without their masks
+var documentLengths = map[vo.DocType]int{
+	vo.CPF:  11,
+	vo.CNPJ: 14,
+}
+
+// sqlBuilder completes a query already filtering on its WHERE. Values are always sent as arguments, numbered after
+// the ones the query takes itself
+type sqlBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (b *sqlBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *sqlBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *sqlBuilder) filter(f receiver.Filter) {
+	if len(f.Statuses) > 0 {
+		statuses := make([]int64, len(f.Statuses))
+		for i, status := range f.Statuses {
+			statuses[i] = int64(status)
+		}
+		b.where("r.status = ANY(" + b.arg(pq.Array(statuses)) + ")")
+	}
+	if len(f.PixKeyTypes) > 0 {
+		keyTypes := make([]string, len(f.PixKeyTypes))
+		for i, keyType := range f.PixKeyTypes {
+			keyTypes[i] = string(keyType)
+		}
+		b.where(`EXISTS(SELECT 1
+		                 FROM receiver_pix_key fk
+		                          JOIN pix_key_type fkt on fk.pix_key_type = fkt.id
+		                 WHERE fk.receiver_id = r.id AND fkt.name = ANY(` + b.arg(pq.Array(keyTypes)) + `))`)
+	}
+	if length, ok := documentLengths[f.DocType]; ok {
+		b.where("length(r.document) = " + b.arg(length))
+	}
+	if !f.CreatedFrom.IsZero() {
+		b.where("r.created_at >= " + b.arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		b.where("r.created_at <= " + b.arg(f.CreatedTo))
+	}
+	if f.HasEmail != nil {
+		if *f.HasEmail {
+			b.where("COALESCE(r.email, '') <> ''")
+		} else {
+			b.where("COALESCE(r.email, '') = ''")
+		}
+	}
+	if f.Condition != nil {
+		b.where(b.condition(*f.Condition))
+	}
+}
+
+// condition writes a search query condition, each field is matched by a fixed expression of this builder
+func (b *sqlBuilder) condition(c receiver.Condition) string {
+	var sql string
+	switch {
+	case len(c.And) > 0:
+		sql = b.join(c.And, " AND ")
+	case len(c.Or) > 0:
+		sql = b.join(c.Or, " OR ")
+	default:
+		sql = b.term(c)
+	}
+	if c.Not {
+		return "NOT " + sql
+	}
+	return sql
+}
+
+func (b *sqlBuilder) join(conditions []receiver.Condition, operator string) string {
+	sql := make([]string, len(conditions))
+	for i, c := range conditions {
+		sql[i] = b.condition(c)
+	}
+	return "(" + strings.Join(sql, operator) + ")"
+}
+
+func (b *sqlBuilder) term(c receiver.Condition) string {
+	switch c.Field {
+	case receiver.FieldStatus:
+		status, _ := entity.ParseUserStatus(c.Value)
+		return "(r.status = " + b.arg(int64(status)) + ")"
+	case receiver.FieldPixKeyType:
+		return `EXISTS(SELECT 1
+		               FROM receiver_pix_key fk
+		                        JOIN pix_key_type fkt on fk.pix_key_type = fkt.id
+		               WHERE fk.receiver_id = r.id AND fkt.name = ` + b.arg(c.Value) + `)`
+	case receiver.FieldDocument:
+		return "(" + b.document(c) + ")"
+	case receiver.FieldName:
+		return "(" + b.name(c) + ")"
+	case receiver.FieldEmail:
+		return "(" + b.email(c) + ")"
+	case receiver.FieldPixKey:
+		return b.pixKey(c)
+	}
+	document := receiver.Condition{Value: strings.ToUpper(documentMask.Replace(c.Value)), Prefix: c.Prefix}
+	return "(" + b.name(c) + " OR " + b.email(c) + " OR r.document LIKE " + b.arg(likePattern(document)) + " OR " +
+		b.pixKey(c) + ")"
+}
+
+func (b *sqlBuilder) name(c receiver.Condition) string {
+	return "f_unaccent(lower(r.name)) LIKE f_unaccent(lower(" + b.arg(likePattern(c)) + "))"
+}
+
+func (b *sqlBuilder) email(c receiver.Condition) string {
+	return "lower(COALESCE(r.email, '')) LIKE lower(" + b.arg(likePattern(c)) + ")"
+}
+
+// document compares the documents as stored, without their masks, which were removed from the value with the query
+// parsed. Terms without a field contain the document instead, once their mask is removed
+func (b *sqlBuilder) document(c receiver.Condition) string {
+	if c.Prefix {
+		return "r.document LIKE " + b.arg(escapeLike(c.Value)+"%")
+	}
+	return "r.document = " + b.arg(c.Value)
+}
+
+func (b *sqlBuilder) pixKey(c receiver.Condition) string {
+	return `EXISTS(SELECT 1
+	               FROM receiver_pix_key fk
+	               WHERE fk.receiver_id = r.id AND lower(fk.pix_key) LIKE lower(` + b.arg(likePattern(c)) + `))`
+}
+
+// likePattern matches the text fields containing the value, or starting with it for a prefix
+func likePattern(c receiver.Condition) string {
+	if c.Prefix {
+		return escapeLike(c.Value) + "%"
+	}
+	return "%" + escapeLike(c.Value) + "%"
+}
+
+// escapeLike keeps the LIKE wildcards typed in a value from matching anything
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// after keeps the receivers after the cursor in the order of its sort, or before it when it points backwards
+func (b *sqlBuilder) after(cursor receiver.Cursor) error {
+	column, ok := sortColumns[cursor.Sort.Field]
+	if !ok {
+		return fmt.Errorf("%w: unknown sort field %q", receiver.ErrInvalidFilter, cursor.Sort.Field)
+	}
+	var value interface{}
+	switch cursor.Sort.Field {
+	case receiver.SortName:
+		value = cursor.Name
+	case receiver.SortStatus:
+		value = int64(cursor.Status)
+	default:
+		value = cursor.CreatedAt
+	}
+	operator := ">"
+	if cursor.Sort.Desc != cursor.Backward {
+		operator = "<"
+	}
+	b.where("(" + column + ", r.id) " + operator + " (" + b.arg(value) + ", " + b.arg(cursor.Id) + ")")
+	return nil
+}
+
+// orderBy sorts by the field and then by id, reversed when the page is read backwards
+func (b *sqlBuilder) orderBy(sort receiver.Sort, backward bool) (string, error) {
+	column, ok := sortColumns[sort.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown sort field %q", receiver.ErrInvalidFilter, sort.Field)
+	}
+	direction := "ASC"
+	if sort.Desc != backward {
+		direction = "DESC"
+	}
+	return " ORDER BY " + column + " " + direction + ", r.id " + direction, nil
+}
+
+// sql joins the conditions to the WHERE of query
+func (b *sqlBuilder) sql(query string) string {
+	var sql strings.Builder
+	sql.WriteString(query)
+	for _, condition := range b.conditions {
+		sql.WriteString(" AND ")
+		sql.WriteString(condition)
+	}
+	return sql.String()
+}
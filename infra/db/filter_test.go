@@ -0,0 +1,163 @@
+package db
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_sqlBuilder_filter(t *testing.T) {
+	from := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	withEmail := false
+	tests := []struct {
+		name     string
+		filter   receiver.Filter
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "Should keep the query as is without filters",
+			filter:   receiver.Filter{},
+			wantSQL:  "WHERE true",
+			wantArgs: []interface{}{"search"},
+		},
+		{
+			name: "Should number the arguments after the ones of the query",
+			filter: receiver.Filter{
+				Statuses:    []entity.UserStatus{entity.Draft, entity.Blocked},
+				DocType:     vo.CNPJ,
+				CreatedFrom: from,
+				HasEmail:    &withEmail,
+			},
+			wantSQL: "WHERE true AND r.status = ANY($2) AND length(r.document) = $3 AND r.created_at >= $4 AND " +
+				"COALESCE(r.email, '') = ''",
+			wantArgs: []interface{}{"search", pq.Array([]int64{0, 2}), 14, from},
+		},
+		{
+			name: "Should escape the LIKE wildcards of a value",
+			filter: receiver.Filter{Condition: &receiver.Condition{
+				Field: receiver.FieldName,
+				Value: `50%_off\`,
+			}},
+			wantSQL:  "WHERE true AND (f_unaccent(lower(r.name)) LIKE f_unaccent(lower($2)))",
+			wantArgs: []interface{}{"search", `%50\%\_off\\%`},
+		},
+		{
+			name: "Should write the conditions of a query with their operators",
+			filter: receiver.Filter{Condition: &receiver.Condition{Or: []receiver.Condition{
+				{Field: receiver.FieldDocument, Value: "123", Prefix: true},
+				{Field: receiver.FieldStatus, Value: "valid", Not: true},
+			}}},
+			wantSQL:  "WHERE true AND ((r.document LIKE $2) OR NOT (r.status = $3))",
+			wantArgs: []interface{}{"search", "123%", int64(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := sqlBuilder{args: []interface{}{"search"}}
+			b.filter(tt.filter)
+			if got := b.sql("WHERE true"); got != tt.wantSQL {
+				t.Errorf("sql() got = %s, want %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(b.args, tt.wantArgs) {
+				t.Errorf("args got = %#v, want %#v", b.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_sqlBuilder_after(t *testing.T) {
+	id := uuid.MustParse("624b2913-ecf3-4445-9b68-588e41038593")
+	createdAt := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		cursor   receiver.Cursor
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  error
+	}{
+		{
+			name:     "Should read forward in ascending order",
+			cursor:   receiver.Cursor{Sort: receiver.Sort{Field: receiver.SortCreatedAt}, CreatedAt: createdAt, Id: id},
+			wantSQL:  "(r.created_at, r.id) > ($1, $2)",
+			wantArgs: []interface{}{createdAt, id},
+		},
+		{
+			name:     "Should read forward in descending order",
+			cursor:   receiver.Cursor{Sort: receiver.Sort{Field: receiver.SortName, Desc: true}, Name: "Flea", Id: id},
+			wantSQL:  "(r.name, r.id) < ($1, $2)",
+			wantArgs: []interface{}{"Flea", id},
+		},
+		{
+			name:     "Should read backward in ascending order",
+			cursor:   receiver.Cursor{Sort: receiver.Sort{Field: receiver.SortStatus}, Status: entity.Valid, Id: id, Backward: true},
+			wantSQL:  "(r.status, r.id) < ($1, $2)",
+			wantArgs: []interface{}{int64(1), id},
+		},
+		{
+			name:     "Should read backward in descending order",
+			cursor:   receiver.Cursor{Sort: receiver.Sort{Field: receiver.SortName, Desc: true}, Name: "Flea", Id: id, Backward: true},
+			wantSQL:  "(r.name, r.id) > ($1, $2)",
+			wantArgs: []interface{}{"Flea", id},
+		},
+		{
+			name:    "Should refuse a field out of the sort columns",
+			cursor:  receiver.Cursor{Sort: receiver.Sort{Field: "document; DROP TABLE receiver"}, Id: id},
+			wantErr: receiver.ErrInvalidFilter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := sqlBuilder{}
+			err := b.after(tt.cursor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("after() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(b.conditions) > 0 || len(b.args) > 0 {
+					t.Errorf("after() wrote %v %v for a refused cursor", b.conditions, b.args)
+				}
+				return
+			}
+			if got := b.sql(""); got != " AND "+tt.wantSQL {
+				t.Errorf("sql() got = %s, want %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(b.args, tt.wantArgs) {
+				t.Errorf("args got = %#v, want %#v", b.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_sqlBuilder_orderBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     receiver.Sort
+		backward bool
+		want     string
+		wantErr  error
+	}{
+		{"Should sort ascending", receiver.Sort{Field: receiver.SortName}, false, " ORDER BY r.name ASC, r.id ASC", nil},
+		{"Should sort descending", receiver.Sort{Field: receiver.SortCreatedAt, Desc: true}, false, " ORDER BY r.created_at DESC, r.id DESC", nil},
+		{"Should reverse the order backwards", receiver.Sort{Field: receiver.SortStatus, Desc: true}, true, " ORDER BY r.status ASC, r.id ASC", nil},
+		{"Should refuse a field out of the sort columns", receiver.Sort{Field: "r.email"}, false, "", receiver.ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := sqlBuilder{}
+			got, err := b.orderBy(tt.sort, tt.backward)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("orderBy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("orderBy() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+)
+
+// actions recorded in the receiver history
+const (
+	actionCreate     = "create"
+	actionUpdate     = "update"
+	actionStatus     = "status_change"
+	actionValidation = "validation"
+	actionDelete     = "delete"
+	actionRestore    = "restore"
+	actionPurge      = "purge"
+	actionPixKey     = "pix_key_change"
+)
+
+func (r *Receiver) History(id uuid.UUID) ([]dtos.HistoryEntryResponse, error) {
+	resp := []dtos.HistoryEntryResponse{}
+	if err := r.db.Select(&resp, QueryReceiverHistory, id); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// audited runs change in a transaction, recording in the receiver history a snapshot of each receiver taken
+// before and after it and incrementing the version of the receivers it changed. Receivers left untouched by
+// the change get no history entry and keep their version
+func (r *Receiver) audited(audit dtos.Audit, action string, ids []uuid.UUID, change func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = auditedTx(tx, audit, action, ids, change); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// auditedTx is audited running in the transaction of the caller, which is left for it to commit
+func auditedTx(tx *sqlx.Tx, audit dtos.Audit, action string, ids []uuid.UUID, change func(tx *sqlx.Tx) error) error {
+	var err error
+	before := make([][]byte, len(ids))
+	for i, id := range ids {
+		if _, err = tx.Exec(LockReceiverByID, id); err != nil {
+			return err
+		}
+		if before[i], err = snapshot(tx, id); err != nil {
+			return err
+		}
+	}
+	if err = change(tx); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		after, err := snapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(before[i], after) {
+			continue
+		}
+		if _, err = tx.Exec(IncrementReceiverVersion, id); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(InsertReceiverHistory, uuid.New(), id, action, nullableJSON(before[i]),
+			nullableJSON(after), audit.Actor, audit.RequestID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot returns the receiver, including deleted ones, and its pix keys as json, or nil when it doesn't exist
+func snapshot(tx *sqlx.Tx, id uuid.UUID) ([]byte, error) {
+	var s []byte
+	if err := tx.Get(&s, QueryReceiverSnapshot, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+func nullableJSON(s []byte) interface{} {
+	if s == nil {
+		return nil
+	}
+	return string(s)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lucasszmt/transfeera-challenge/domain/idempotency"
+	"time"
+)
+
+type IdempotencyKeys struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyKeys(db *sqlx.DB) *IdempotencyKeys {
+	return &IdempotencyKeys{db: db}
+}
+
+type idempotencyRecord struct {
+	Fingerprint string        `db:"fingerprint"`
+	StatusCode  sql.NullInt64 `db:"status_code"`
+	ContentType string        `db:"content_type"`
+	Body        []byte        `db:"body"`
+}
+
+// Reserve relies on the primary key of idempotency_key, a concurrent request inserting the same key waits for
+// the first insert and then finds the key taken
+func (k *IdempotencyKeys) Reserve(key, fingerprint string, now, expiresAt, staleBefore time.Time) (*idempotency.Record, error) {
+	res, err := k.db.Exec(ReserveIdempotencyKey, key, fingerprint, now, expiresAt, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected > 0 {
+		return nil, err
+	}
+	rec := idempotencyRecord{}
+	if err = k.db.Get(&rec, QueryIdempotencyKey, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// released by the request holding it in the meantime
+			return nil, idempotency.ErrRequestInProgress
+		}
+		return nil, err
+	}
+	record := &idempotency.Record{Fingerprint: rec.Fingerprint}
+	if rec.StatusCode.Valid {
+		record.Response = &idempotency.Response{
+			StatusCode:  int(rec.StatusCode.Int64),
+			ContentType: rec.ContentType,
+			Body:        rec.Body,
+		}
+	}
+	return record, nil
+}
+
+func (k *IdempotencyKeys) Complete(key, fingerprint string, resp idempotency.Response) error {
+	_, err := k.db.Exec(CompleteIdempotencyKey, key, fingerprint, resp.StatusCode, resp.ContentType, resp.Body)
+	return err
+}
+
+func (k *IdempotencyKeys) Release(key, fingerprint string) error {
+	_, err := k.db.Exec(ReleaseIdempotencyKey, key, fingerprint)
+	return err
+}
+
+func (k *IdempotencyKeys) Purge(expiredBefore time.Time) (int64, error) {
+	res, err := k.db.Exec(PurgeIdempotencyKeys, expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"time"
+)
+
+type ImportJobs struct {
+	db *sqlx.DB
+}
+
+func NewImportJobs(db *sqlx.DB) *ImportJobs {
+	return &ImportJobs{db: db}
+}
+
+type claimedImportJob struct {
+	Id           uuid.UUID `db:"id"`
+	Claim        uuid.UUID `db:"claim"`
+	DryRun       bool      `db:"dry_run"`
+	AllOrNothing bool      `db:"all_or_nothing"`
+	File         []byte    `db:"file"`
+	Actor        string    `db:"actor"`
+	RequestID    string    `db:"request_id"`
+	Line         int       `db:"line"`
+	Total        int       `db:"total_rows"`
+	Processed    int       `db:"processed_rows"`
+	Created      int       `db:"created_rows"`
+	Failed       int       `db:"failed_rows"`
+}
+
+func (j *ImportJobs) CreateImportJob(job *receiver.ImportJob) error {
+	_, err := j.db.Exec(InsertImportJob, job.Id, job.DryRun, job.AllOrNothing, job.File,
+		job.Audit.Actor, job.Audit.RequestID, job.Total)
+	return err
+}
+
+func (j *ImportJobs) GetImportJob(id uuid.UUID) (*dtos.ImportJobResponse, error) {
+	resp := dtos.ImportJobResponse{}
+	if err := j.db.Get(&resp, QueryImportJob, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, receiver.ErrImportJobNotFound
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (j *ImportJobs) ImportJobErrors(id uuid.UUID) ([]dtos.ImportJobError, error) {
+	resp := []dtos.ImportJobError{}
+	if err := j.db.Select(&resp, QueryImportJobErrors, id); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (j *ImportJobs) ClaimImportJob(claim uuid.UUID, staleBefore time.Time) (*receiver.ImportJob, error) {
+	claimed := claimedImportJob{}
+	if err := j.db.Get(&claimed, ClaimImportJob, claim, staleBefore, time.Now()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &receiver.ImportJob{
+		Id:           claimed.Id,
+		Claim:        claimed.Claim,
+		DryRun:       claimed.DryRun,
+		AllOrNothing: claimed.AllOrNothing,
+		File:         claimed.File,
+		Audit:        dtos.Audit{Actor: claimed.Actor, RequestID: claimed.RequestID},
+		Line:         claimed.Line,
+		Total:        claimed.Total,
+		Processed:    claimed.Processed,
+		Created:      claimed.Created,
+		Failed:       claimed.Failed,
+	}, nil
+}
+
+// SaveImportChunk creates each receiver within a savepoint, so a receiver refused by the database only fails its
+// own row. The job counters are only updated once the transaction commits
+func (j *ImportJobs) SaveImportChunk(job *receiver.ImportJob, rows []receiver.ImportChunkRow, create bool, uniqueDocument bool) error {
+	tx, err := j.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = lockClaimedImportJob(tx, job); err != nil {
+		return err
+	}
+	created, failed := job.Created, job.Failed
+	for i := range rows {
+		row := &rows[i]
+		if row.Err == nil && create {
+			row.Err = createImportedReceiver(tx, job.Audit, row.Receiver, uniqueDocument)
+			if row.Err == nil {
+				created++
+			}
+		}
+		if row.Err != nil {
+			if _, err = tx.Exec(InsertImportJobError, job.Id, row.Line, row.Err.Error()); err != nil {
+				return err
+			}
+			failed++
+		}
+	}
+	line, processed := job.Line, job.Processed+len(rows)
+	if len(rows) > 0 {
+		line = rows[len(rows)-1].Line
+	}
+	if _, err = tx.Exec(UpdateImportJobProgress, job.Id, line, processed, created, failed, time.Now()); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	job.Line, job.Processed, job.Created, job.Failed = line, processed, created, failed
+	return nil
+}
+
+func (j *ImportJobs) FinishImportJob(job *receiver.ImportJob, receivers []*entity.Receiver, uniqueDocument bool) error {
+	tx, err := j.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = lockClaimedImportJob(tx, job); err != nil {
+		return err
+	}
+	for i, rcv := range receivers {
+		if err = createImportedReceiver(tx, job.Audit, rcv, uniqueDocument); err != nil {
+			return &receiver.BatchError{Index: i, Err: err}
+		}
+	}
+	created := job.Created + len(receivers)
+	if _, err = tx.Exec(CompleteImportJob, job.Id, created, job.Failed, time.Now()); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	job.Created = created
+	return nil
+}
+
+func (j *ImportJobs) SaveImportError(job *receiver.ImportJob, line int, rowErr error) error {
+	tx, err := j.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = lockClaimedImportJob(tx, job); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(InsertImportJobError, job.Id, line, rowErr.Error()); err != nil {
+		return err
+	}
+	failed := job.Failed + 1
+	if _, err = tx.Exec(UpdateImportJobProgress, job.Id, job.Line, job.Processed, job.Created, failed, time.Now()); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	job.Failed = failed
+	return nil
+}
+
+func (j *ImportJobs) FailImportJob(job *receiver.ImportJob, reason string) error {
+	_, err := j.db.Exec(FailImportJob, job.Id, job.Claim, reason, time.Now())
+	return err
+}
+
+// lockClaimedImportJob locks the job until the end of the transaction, making sure it's still held by the claim
+// of the worker, a worker taken as stale must not store anything else
+func lockClaimedImportJob(tx *sqlx.Tx, job *receiver.ImportJob) error {
+	var id uuid.UUID
+	if err := tx.Get(&id, LockClaimedImportJob, job.Id, job.Claim); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return receiver.ErrImportJobReclaimed
+		}
+		return err
+	}
+	return nil
+}
+
+// createImportedReceiver creates the receiver within a savepoint, rolling back to it when the receiver is refused,
+// which keeps the transaction usable for the next ones
+func createImportedReceiver(tx *sqlx.Tx, audit dtos.Audit, rcv *entity.Receiver, uniqueDocument bool) error {
+	if _, err := tx.Exec(SavepointImportRow); err != nil {
+		return err
+	}
+	err := auditedTx(tx, audit, actionCreate, []uuid.UUID{rcv.Id()}, func(tx *sqlx.Tx) error {
+		return insertReceiver(tx, rcv, uniqueDocument)
+	})
+	if err != nil {
+		if _, rollbackErr := tx.Exec(RollbackImportRow); rollbackErr != nil {
+			return fmt.Errorf("%s: %w", err, rollbackErr)
+		}
+		return duplicatePixKey(tx, rcv, err)
+	}
+	_, err = tx.Exec(ReleaseImportRow)
+	return err
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
+	"github.com/lucasszmt/transfeera-challenge/domain/entity"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+)
+
+const uniqueViolation = "23505"
+
+func (r *Receiver) ListPixKeys(receiverID uuid.UUID) ([]dtos.PixKeyResponse, error) {
+	resp := []dtos.PixKeyResponse{}
+	if err := r.db.Select(&resp, QueryPixKeysByReceiver, receiverID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AddPixKey registers a new key to the receiver, a preferred key takes the place of the current preferred one
+func (r *Receiver) AddPixKey(audit dtos.Audit, key *entity.ReceiverPixKey) error {
+	return r.audited(audit, actionPixKey, []uuid.UUID{key.ReceiverID()}, func(tx *sqlx.Tx) error {
+		if key.Preferred() {
+			if _, err := tx.Exec(UnsetPreferredPixKey, key.ReceiverID()); err != nil {
+				return err
+			}
+		}
+		return insertPixKey(tx, key.Id(), key.ReceiverID(), key.Key(), key.Preferred(), key.ThirdParty())
+	})
+}
+
+// RemovePixKey removes the key of the receiver, when it was the preferred one the oldest remaining key is promoted
+func (r *Receiver) RemovePixKey(audit dtos.Audit, receiverID, keyID uuid.UUID) error {
+	return r.audited(audit, actionPixKey, []uuid.UUID{receiverID}, func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(DeletePixKeyByID, keyID, receiverID)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return receiver.ErrPixKeyNotFound
+		}
+		_, err = tx.Exec(PromoteOldestPixKey, receiverID)
+		return err
+	})
+}
+
+func (r *Receiver) SetPreferredPixKey(audit dtos.Audit, receiverID, keyID uuid.UUID) error {
+	return r.audited(audit, actionPixKey, []uuid.UUID{receiverID}, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(UnsetPreferredPixKey, receiverID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(SetPreferredPixKeyByID, keyID, receiverID)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return receiver.ErrPixKeyNotFound
+		}
+		return nil
+	})
+}
+
+func insertPixKey(tx *sqlx.Tx, id, receiverID uuid.UUID, key *vo.PixKey, preferred, thirdParty bool) error {
+	var pixTypeId uuid.UUID
+	if err := tx.Get(&pixTypeId, QueryPixTypeByName, key.KeyType()); err != nil {
+		return err
+	}
+	_, err := tx.Exec(InsertPixKeyQuery, id, receiverID, key.Value(), pixTypeId, preferred, thirdParty)
+	if isUniqueViolation(err) {
+		return receiver.ErrPixKeyAlreadyRegistered
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
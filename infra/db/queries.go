@@ -1,66 +1,390 @@
 package db
 
 const (
-	QueryUser = `SELECT r.id,
+	// searchMatches finds the receivers matching a search, one branch per index so each of them can be used: $1 is
+	// the search text, $2 the prefix tsquery of its terms and $3 the digits of a document typed with its mask. Every
+	// pix key of the receiver is matched, not only the preferred one
+	searchMatches = `SELECT r.id FROM receiver r WHERE r.search_vector @@ to_tsquery('simple', $2)
+				 UNION
+				 SELECT r.id FROM receiver r WHERE f_unaccent(lower($1)) <% f_unaccent(lower(r.name))
+				 UNION
+				 SELECT r.id FROM receiver r WHERE lower($1) <% lower(r.email)
+				 UNION
+				 SELECT r.id FROM receiver r WHERE $3 <> '' AND r.document LIKE '%' || $3 || '%'
+				 UNION
+				 SELECT rpk.receiver_id FROM receiver_pix_key rpk WHERE lower($1) <% lower(rpk.pix_key)
+				 UNION
+				 SELECT rpk.receiver_id
+				 FROM receiver_pix_key rpk
+					      JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+				 WHERE pkt.name = lower($1)`
+
+	// QuerySearchReceivers ranks the matches by the best of their full text rank, the similarity of the search to
+	// their name, email or closest pix key, and how much of their document it covers. Only the preferred pix key is
+	// returned. An empty search text only filters the
+	// receivers, which are all ranked the same. It is completed by the filter conditions, the order and the limit
+	QuerySearchReceivers = `SELECT r.id,
+			         r.name,
+			         r.email,
+			         r.document,
+			         COALESCE(rpk.pix_key, '') pixkey,
+			         COALESCE(pkt.name, '') pix_type,
+			         COALESCE(r.bank_code, '') bank_code,
+			         COALESCE(r.bank_branch, '') bank_branch,
+			         COALESCE(r.bank_account, '') bank_account,
+			         COALESCE(r.bank_account_digit, '') bank_account_digit,
+			         COALESCE(r.bank_account_type, '') bank_account_type,
+			         COALESCE(rpk.third_party, false) third_party_pix_key,
+			         case r.status
+			             when 0 then 'draft'
+			             when 1 then 'valid'
+			             when 2 then 'blocked'
+			             when 3 then 'archived' END AS status,
+			         COALESCE(r.validation_error, '') validation_error,
+			         r.version,
+			         GREATEST(ts_rank(r.search_vector, to_tsquery('simple', $2)),
+			                  word_similarity(f_unaccent(lower($1)), f_unaccent(lower(r.name))),
+			                  word_similarity(lower($1), lower(COALESCE(r.email, ''))),
+			                  (SELECT COALESCE(max(word_similarity(lower($1), lower(k.pix_key))), 0)
+			                   FROM receiver_pix_key k
+			                   WHERE k.receiver_id = r.id),
+			                  CASE
+			                      WHEN $3 = '' THEN 0
+			                      WHEN r.document = $3 THEN 1
+			                      WHEN r.document LIKE '%' || $3 || '%' THEN 0.5
+			                      ELSE 0 END)::float8 AS rank
+				 FROM receiver r
+					      LEFT JOIN receiver_pix_key rpk on rpk.receiver_id = r.id AND rpk.preferred
+					      LEFT JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+				 WHERE r.deleted_at IS NULL
+				 AND ($1 = '' OR r.id IN (` + searchMatches + `))`
+
+	// QueryExportReceivers filters the receivers as QuerySearchReceivers does, without a limit, all of them are
+	// exported when the search text is empty. It is completed by the filter conditions and the order
+	QueryExportReceivers = `SELECT r.id,
 			         r.name,
 			         r.email,
 			         r.document,
-			         r.pixKey,
-			         pkt.name pix_type,
+			         COALESCE(rpk.pix_key, '') pixkey,
+			         COALESCE(pkt.name, '') pix_type,
+			         COALESCE(r.bank_code, '') bank_code,
+			         COALESCE(r.bank_branch, '') bank_branch,
+			         COALESCE(r.bank_account, '') bank_account,
+			         COALESCE(r.bank_account_digit, '') bank_account_digit,
+			         COALESCE(r.bank_account_type, '') bank_account_type,
+			         COALESCE(rpk.third_party, false) third_party_pix_key,
 			         case r.status
 			             when 0 then 'draft'
-			             when 1 then 'active' END AS status 
+			             when 1 then 'valid'
+			             when 2 then 'blocked'
+			             when 3 then 'archived' END AS status,
+			         COALESCE(r.validation_error, '') validation_error,
+			         r.version
 				 FROM receiver r
-					      LEFT JOIN pix_key_type pkt on r.pixKeyType = pkt.id
-				 WHERE r.name LIKE $1
-				 OR r.document LIKE $1
-				 OR r.email LIKE $1
-				 OR pkt.name LIKE $1
-				 ORDER BY r.id
-			     LIMIT $2;`
+					      LEFT JOIN receiver_pix_key rpk on rpk.receiver_id = r.id AND rpk.preferred
+					      LEFT JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+				 WHERE r.deleted_at IS NULL
+				 AND ($1 = '' OR r.id IN (` + searchMatches + `))`
 
 	QueryUserByID = `SELECT r.id,
 			         r.name,
 			         r.email,
 			         r.document,
-			         r.pixKey,
-			         pkt.name pix_type,
+			         COALESCE(rpk.pix_key, '') pixkey,
+			         COALESCE(pkt.name, '') pix_type,
+			         COALESCE(r.bank_code, '') bank_code,
+			         COALESCE(r.bank_branch, '') bank_branch,
+			         COALESCE(r.bank_account, '') bank_account,
+			         COALESCE(r.bank_account_digit, '') bank_account_digit,
+			         COALESCE(r.bank_account_type, '') bank_account_type,
+			         COALESCE(rpk.third_party, false) third_party_pix_key,
 			         case r.status
 			             when 0 then 'draft'
-			             when 1 then 'active' END AS status
+			             when 1 then 'valid'
+			             when 2 then 'blocked'
+			             when 3 then 'archived' END AS status,
+			         COALESCE(r.validation_error, '') validation_error,
+			         r.version
 					 FROM receiver r
-					          LEFT JOIN pix_key_type pkt on r.pixKeyType = pkt.id
-					 WHERE r.id = $1 LIMIT 1`
+					          LEFT JOIN receiver_pix_key rpk on rpk.receiver_id = r.id AND rpk.preferred
+					          LEFT JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+					 WHERE r.id = $1 AND r.deleted_at IS NULL LIMIT 1`
 
 	QueryPixTypeByName = `SELECT id FROM pix_key_type WHERE name = $1 LIMIT 1`
 
+	// QueryListOfReceivers is completed by the filter conditions, the order and the page of a list
 	QueryListOfReceivers = `SELECT r.id,
 								   r.name,
 								   r.document,
 								   case r.status
-									   WHEN 0 THEN 'draft'
-									   when 1 then 'active' END AS status
+									   when 0 then 'draft'
+									   when 1 then 'valid'
+									   when 2 then 'blocked'
+									   when 3 then 'archived' END AS status,
+								   r.created_at
 								FROM receiver r
-										 LEFT JOIN pix_key_type pkt on r.pixKeyType = pkt.id
-								ORDER BY r.id
-								OFFSET $2
-								LIMIT $1`
+								WHERE r.deleted_at IS NULL`
+
+	QueryCountReceivers = `SELECT count(*) FROM receiver r WHERE r.deleted_at IS NULL`
 
-	InsertNewReceiverQuery = `INSERT INTO receiver (id, name, email, document, pixKey, pixKeyType, status)
-							  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	InsertNewReceiverQuery = `INSERT INTO receiver (id, name, email, document, status,
+	                                                bank_code, bank_branch, bank_account, bank_account_digit, bank_account_type)
+							  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
 
 	UpdateReceiverByID = `UPDATE receiver
-					  SET name       = $1,
-					      email      = $2,
-					      document   = $3,
-					      pixKey     = $4,
-					      pixKeyType = $5,
-					      status     = $6
-					  WHERE receiver.id = $7`
+					  SET name               = $1,
+					      email              = $2,
+					      document           = $3,
+					      status             = $4,
+					      bank_code          = $5,
+					      bank_branch        = $6,
+					      bank_account       = $7,
+					      bank_account_digit = $8,
+					      bank_account_type  = $9
+					  WHERE receiver.id = $10 AND version = $11 AND deleted_at IS NULL`
+
+	UpdateReceiverStatusByID = `UPDATE receiver
+								SET status = $1
+								WHERE receiver.id = $2 AND status = $3 AND deleted_at IS NULL`
+
+	MarkReceiverValid = `UPDATE receiver
+						 SET status           = 1,
+						     validation_error = NULL,
+						     validated_at     = now()
+						 WHERE receiver.id = $1 AND status = 0 AND deleted_at IS NULL`
+
+	SetReceiverValidationError = `UPDATE receiver
+								  SET validation_error = $1,
+								      validated_at     = now()
+								  WHERE receiver.id = $2 AND status = 0 AND deleted_at IS NULL`
 
 	UpdateReceiverEmailByID = `UPDATE receiver
 							   SET email = $1
-							   WHERE receiver.id = $2`
+							   WHERE receiver.id = $2 AND version = $3 AND deleted_at IS NULL`
+
+	SoftDeleteReceiversByID = `UPDATE receiver
+							   SET deleted_at = now(),
+							       deleted_by = ?
+							   WHERE id IN (?) AND deleted_at IS NULL`
+
+	RestoreReceiversByID = `UPDATE receiver
+							SET deleted_at = NULL,
+							    deleted_by = NULL
+							WHERE id IN (?) AND deleted_at IS NOT NULL`
+
+	QueryDeletedReceiverDocuments = `SELECT id, document
+									 FROM receiver
+									 WHERE id IN (?) AND deleted_at IS NOT NULL
+									 ORDER BY document`
+
+	// QueryPurgeableReceivers compares the deletion time to the database clock that wrote it, $1 is the retention in
+	// microseconds
+	QueryPurgeableReceivers = `SELECT id
+							   FROM receiver
+							   WHERE deleted_at < now() - $1 * interval '1 microsecond'
+							   ORDER BY id`
+
+	PurgeReceiversByID = `DELETE FROM receiver WHERE id IN (?) AND deleted_at IS NOT NULL`
+
+	QueryPixKeysByReceiver = `SELECT rpk.id,
+									 rpk.pix_key,
+									 pkt.name pix_type,
+									 rpk.preferred,
+									 rpk.third_party
+							  FROM receiver_pix_key rpk
+									   JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+							  WHERE rpk.receiver_id = $1
+							  ORDER BY rpk.preferred DESC, rpk.created_at`
+
+	InsertPixKeyQuery = `INSERT INTO receiver_pix_key (id, receiver_id, pix_key, pix_key_type, preferred, third_party)
+						 VALUES ($1, $2, $3, $4, $5, $6)`
+
+	UnsetPreferredPixKey = `UPDATE receiver_pix_key
+							SET preferred = false
+							WHERE receiver_id = $1 AND preferred`
+
+	SetPreferredPixKeyByID = `UPDATE receiver_pix_key
+							  SET preferred = true
+							  WHERE id = $1 AND receiver_id = $2`
+
+	DeletePixKeyByID = `DELETE FROM receiver_pix_key WHERE id = $1 AND receiver_id = $2`
+
+	DeleteReplacedPixKey = `DELETE FROM receiver_pix_key
+							WHERE receiver_id = $1 AND (preferred OR pix_key = $2)`
+
+	PromoteOldestPixKey = `UPDATE receiver_pix_key
+						   SET preferred = true
+						   WHERE id = (SELECT id
+									   FROM receiver_pix_key
+									   WHERE receiver_id = $1
+									   ORDER BY created_at
+									   LIMIT 1)
+							 AND NOT EXISTS(SELECT 1 FROM receiver_pix_key WHERE receiver_id = $1 AND preferred)`
+
+	LockReceiverByID = `SELECT 1 FROM receiver WHERE id = $1 FOR UPDATE`
+
+	QueryReceiverNotDeleted = `SELECT EXISTS(SELECT 1 FROM receiver WHERE id = $1 AND deleted_at IS NULL)`
+
+	LockReceiverDocument = `SELECT pg_advisory_xact_lock(hashtext('receiver_document:' || $1))`
+
+	QueryReceiverByDocument = `SELECT id
+							   FROM receiver
+							   WHERE document = $1 AND id <> $2 AND deleted_at IS NULL
+							   ORDER BY id
+							   LIMIT 1`
+
+	QueryPixKeyOwner = `SELECT receiver_id FROM receiver_pix_key WHERE pix_key = $1`
+
+	IncrementReceiverVersion = `UPDATE receiver SET version = version + 1 WHERE id = $1`
+
+	QueryReceiverSnapshot = `SELECT to_jsonb(s)
+							 FROM (SELECT r.id,
+										  r.name,
+										  r.email,
+										  r.document,
+										  case r.status
+											  when 0 then 'draft'
+											  when 1 then 'valid'
+											  when 2 then 'blocked'
+											  when 3 then 'archived' END AS status,
+										  r.bank_code,
+										  r.bank_branch,
+										  r.bank_account,
+										  r.bank_account_digit,
+										  r.bank_account_type,
+										  r.validation_error,
+										  r.deleted_at,
+										  r.deleted_by,
+										  (SELECT COALESCE(jsonb_agg(jsonb_build_object(
+												  'pix_key', rpk.pix_key,
+												  'pix_key_type', pkt.name,
+												  'preferred', rpk.preferred,
+												  'third_party', rpk.third_party) ORDER BY rpk.created_at), '[]')
+										   FROM receiver_pix_key rpk
+													JOIN pix_key_type pkt on rpk.pix_key_type = pkt.id
+										   WHERE rpk.receiver_id = r.id) pix_keys
+								   FROM receiver r
+								   WHERE r.id = $1) s`
+
+	InsertReceiverHistory = `INSERT INTO receiver_history (id, receiver_id, action, before, after, actor, request_id)
+							 VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	QueryReceiverHistory = `SELECT id,
+								   action,
+								   before,
+								   after,
+								   COALESCE(actor, '') actor,
+								   COALESCE(request_id, '') request_id,
+								   created_at
+							FROM receiver_history
+							WHERE receiver_id = $1
+							ORDER BY created_at, seq`
+
+	// ReserveIdempotencyKey inserts the key, or takes over an expired or stale one, returning no rows when the
+	// key is held by another request
+	ReserveIdempotencyKey = `INSERT INTO idempotency_key (key, fingerprint, created_at, expires_at)
+							 VALUES ($1, $2, $3, $4)
+							 ON CONFLICT (key) DO UPDATE
+								 SET fingerprint  = EXCLUDED.fingerprint,
+									 status_code  = NULL,
+									 content_type = NULL,
+									 body         = NULL,
+									 created_at   = EXCLUDED.created_at,
+									 expires_at   = EXCLUDED.expires_at
+							 WHERE idempotency_key.expires_at < $3
+								OR (idempotency_key.status_code IS NULL AND idempotency_key.created_at < $5)`
+
+	QueryIdempotencyKey = `SELECT fingerprint,
+								  status_code,
+								  COALESCE(content_type, '') content_type,
+								  body
+						   FROM idempotency_key
+						   WHERE key = $1`
+
+	CompleteIdempotencyKey = `UPDATE idempotency_key
+							  SET status_code  = $3,
+								  content_type = $4,
+								  body         = $5
+							  WHERE key = $1 AND fingerprint = $2 AND status_code IS NULL`
+
+	ReleaseIdempotencyKey = `DELETE FROM idempotency_key WHERE key = $1 AND fingerprint = $2 AND status_code IS NULL`
+
+	PurgeIdempotencyKeys = `DELETE FROM idempotency_key WHERE expires_at < $1`
+
+	InsertImportJob = `INSERT INTO import_job (id, dry_run, all_or_nothing, file, actor, request_id, total_rows)
+					   VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	QueryImportJob = `SELECT id,
+							 status,
+							 dry_run,
+							 all_or_nothing,
+							 total_rows,
+							 processed_rows,
+							 created_rows,
+							 failed_rows,
+							 COALESCE(error, '') error,
+							 created_at,
+							 started_at,
+							 finished_at
+					  FROM import_job
+					  WHERE id = $1`
+
+	QueryImportJobErrors = `SELECT line, error FROM import_job_error WHERE job_id = $1 ORDER BY line`
+
+	// ClaimImportJob takes the oldest queued job, or a running one whose worker stopped sending heartbeats,
+	// skipping the jobs being claimed by concurrent workers
+	ClaimImportJob = `UPDATE import_job
+					  SET status       = 'running',
+						  claim        = $1,
+						  started_at   = COALESCE(started_at, $3),
+						  heartbeat_at = $3
+					  WHERE id = (SELECT id
+								  FROM import_job
+								  WHERE status = 'queued'
+									 OR (status = 'running' AND heartbeat_at < $2)
+								  ORDER BY created_at
+								  LIMIT 1 FOR UPDATE SKIP LOCKED)
+					  RETURNING id,
+								claim,
+								dry_run,
+								all_or_nothing,
+								file,
+								COALESCE(actor, '') actor,
+								COALESCE(request_id, '') request_id,
+								line,
+								total_rows,
+								processed_rows,
+								created_rows,
+								failed_rows`
+
+	LockClaimedImportJob = `SELECT id FROM import_job WHERE id = $1 AND claim = $2 AND status = 'running' FOR UPDATE`
+
+	InsertImportJobError = `INSERT INTO import_job_error (job_id, line, error)
+							VALUES ($1, $2, $3)
+							ON CONFLICT (job_id, line) DO UPDATE SET error = EXCLUDED.error`
+
+	UpdateImportJobProgress = `UPDATE import_job
+							   SET line           = $2,
+								   processed_rows = $3,
+								   created_rows   = $4,
+								   failed_rows    = $5,
+								   heartbeat_at   = $6
+							   WHERE id = $1`
+
+	CompleteImportJob = `UPDATE import_job
+						 SET status       = 'completed',
+							 created_rows = $2,
+							 failed_rows  = $3,
+							 finished_at  = $4
+						 WHERE id = $1`
+
+	FailImportJob = `UPDATE import_job
+					 SET status      = 'failed',
+						 error       = $3,
+						 finished_at = $4
+					 WHERE id = $1 AND claim = $2 AND status = 'running'`
 
-	DelteReceiversByID = `DELETE FROM receiver WHERE id IN (?)`
+	SavepointImportRow = `SAVEPOINT import_row`
+	RollbackImportRow  = `ROLLBACK TO SAVEPOINT import_row`
+	ReleaseImportRow   = `RELEASE SAVEPOINT import_row`
 )
@@ -3,13 +3,14 @@ package db
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/lucasszmt/transfeera-challenge/domain/dtos"
 	"github.com/lucasszmt/transfeera-challenge/domain/entity"
 	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"github.com/lucasszmt/transfeera-challenge/domain/vo"
+	"time"
 )
 
 type Receiver struct {
@@ -20,10 +21,19 @@ func NewReceiver(db *sqlx.DB) *Receiver {
 	return &Receiver{db: db}
 }
 
-func (r *Receiver) Get(query string, limit int) ([]dtos.GetReceiverResponse, error) {
-	var resp []dtos.GetReceiverResponse
-	query = fmt.Sprint("%", query, "%")
-	if err := r.db.Select(&resp, QueryUser, query, limit); err != nil {
+// Search orders the receivers by relevance, unless the filter sorts them otherwise
+func (r *Receiver) Search(query receiver.SearchQuery, filter receiver.Filter, limit int) ([]dtos.SearchReceiverResponse, error) {
+	b := sqlBuilder{args: []interface{}{query.Text, query.TSQuery(), query.Document}}
+	b.filter(filter)
+	order := " ORDER BY rank DESC, r.id"
+	if filter.Sort.Field != "" {
+		var err error
+		if order, err = b.orderBy(filter.Sort, false); err != nil {
+			return nil, err
+		}
+	}
+	var resp []dtos.SearchReceiverResponse
+	if err := r.db.Select(&resp, b.sql(QuerySearchReceivers)+order+" LIMIT "+b.arg(limit), b.args...); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, receiver.ErrReceiverNotFound
 		}
@@ -32,64 +42,187 @@ func (r *Receiver) Get(query string, limit int) ([]dtos.GetReceiverResponse, err
 	return resp, nil
 }
 
-func (r *Receiver) Create(receiver *entity.Receiver) (*entity.Receiver, error) {
-	var pixTypeId uuid.UUID
-	if err := r.db.Get(&pixTypeId, QueryPixTypeByName, receiver.PixKey().KeyType()); err != nil {
-		return receiver, err
+// Export scans the receivers one at a time while the rows arrive, so memory doesn't grow with the result
+func (r *Receiver) Export(query receiver.SearchQuery, filter receiver.Filter, each func(receiver *dtos.GetReceiverResponse) error) error {
+	b := sqlBuilder{args: []interface{}{query.Text, query.TSQuery(), query.Document}}
+	b.filter(filter)
+	order := " ORDER BY r.id"
+	if filter.Sort.Field != "" {
+		var err error
+		if order, err = b.orderBy(filter.Sort, false); err != nil {
+			return err
+		}
 	}
-	stmt, err := r.db.Preparex(InsertNewReceiverQuery)
+	rows, err := r.db.Queryx(b.sql(QueryExportReceivers)+order, b.args...)
 	if err != nil {
-		return receiver, err
-	}
-	_, err = stmt.Exec(
-		receiver.Id(),
-		receiver.Name(),
-		receiver.Email(),
-		receiver.Doc(),
-		receiver.PixKey().Value(),
-		pixTypeId.String(),
-		receiver.Status())
+		return err
+	}
+	defer rows.Close()
+	rcv := dtos.GetReceiverResponse{}
+	for rows.Next() {
+		if err = rows.StructScan(&rcv); err != nil {
+			return err
+		}
+		if err = each(&rcv); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *Receiver) Create(audit dtos.Audit, receiver *entity.Receiver, uniqueDocument bool) (*entity.Receiver, error) {
+	err := r.audited(audit, actionCreate, []uuid.UUID{receiver.Id()}, func(tx *sqlx.Tx) error {
+		return insertReceiver(tx, receiver, uniqueDocument)
+	})
 	if err != nil {
-		return receiver, err
+		return nil, duplicatePixKey(r.db, receiver, err)
 	}
 	return receiver, nil
 }
 
-func (r *Receiver) UpdateDraft(receiver *entity.Receiver) (*entity.Receiver, error) {
+func (r *Receiver) CreateBatch(audit dtos.Audit, receivers []*entity.Receiver, uniqueDocument bool) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, rcv := range receivers {
+		err = auditedTx(tx, audit, actionCreate, []uuid.UUID{rcv.Id()}, func(tx *sqlx.Tx) error {
+			return insertReceiver(tx, rcv, uniqueDocument)
+		})
+		if err != nil {
+			return &receiver.BatchError{Index: i, Err: duplicatePixKey(r.db, rcv, err)}
+		}
+	}
+	return tx.Commit()
+}
+
+func insertReceiver(tx *sqlx.Tx, rcv *entity.Receiver, uniqueDocument bool) error {
+	if uniqueDocument {
+		if err := checkDuplicateDocument(tx, rcv.Id(), rcv.Doc()); err != nil {
+			return err
+		}
+	}
+	args := []interface{}{
+		rcv.Id(),
+		rcv.Name(),
+		rcv.Email(),
+		rcv.Doc(),
+		rcv.Status(),
+	}
+	if _, err := tx.Exec(InsertNewReceiverQuery, append(args, bankAccountArgs(rcv.BankAccount())...)...); err != nil {
+		return err
+	}
+	if key := rcv.PixKey(); key != nil {
+		return insertPixKey(tx, uuid.New(), rcv.Id(), key, true, rcv.ThirdPartyPixKey())
+	}
+	return nil
+}
+
+// UpdateDraft overwrites the receiver data, its pix key, when provided, replaces the preferred one while
+// the other keys of the receiver are kept. The version is checked in the update statement itself, so a
+// receiver changed after it was read is never overwritten
+func (r *Receiver) UpdateDraft(audit dtos.Audit, receiver *entity.Receiver, version int, uniqueDocument bool) (*entity.Receiver, error) {
 	_, err := r.GetByID(receiver.Id())
 	if err != nil {
 		return nil, err
 	}
-	var pixTypeId uuid.UUID
-	if err := r.db.Get(&pixTypeId, QueryPixTypeByName, receiver.PixKey().KeyType()); err != nil {
-		return receiver, err
-	}
-	stmt, err := r.db.Preparex(UpdateReceiverByID)
+	err = r.audited(audit, actionUpdate, []uuid.UUID{receiver.Id()}, func(tx *sqlx.Tx) error {
+		if uniqueDocument {
+			if err := checkDuplicateDocument(tx, receiver.Id(), receiver.Doc()); err != nil {
+				return err
+			}
+		}
+		args := []interface{}{
+			receiver.Name(),
+			receiver.Email(),
+			receiver.Doc(),
+			receiver.Status(),
+		}
+		args = append(args, bankAccountArgs(receiver.BankAccount())...)
+		res, err := tx.Exec(UpdateReceiverByID, append(args, receiver.Id(), version)...)
+		if err != nil {
+			return err
+		}
+		if err = checkVersion(tx, res, receiver.Id()); err != nil {
+			return err
+		}
+		if key := receiver.PixKey(); key != nil {
+			if _, err := tx.Exec(DeleteReplacedPixKey, receiver.Id(), key.Value()); err != nil {
+				return err
+			}
+			return insertPixKey(tx, uuid.New(), receiver.Id(), key, true, receiver.ThirdPartyPixKey())
+		}
+		return nil
+	})
 	if err != nil {
-		return receiver, err
+		return nil, duplicatePixKey(r.db, receiver, err)
 	}
-	_, err = stmt.Exec(
-		receiver.Name(),
-		receiver.Email(),
-		receiver.Doc(),
-		receiver.PixKey().Value(),
-		pixTypeId.String(),
-		receiver.Status(),
-		receiver.Id())
-	return receiver, err
+	return receiver, nil
 }
 
-func (r *Receiver) UpdateValid(id uuid.UUID, email string) error {
+func (r *Receiver) UpdateValid(audit dtos.Audit, id uuid.UUID, email string, version int) error {
 	_, err := r.GetByID(id)
 	if err != nil {
 		return err
 	}
-	stmt, err := r.db.Preparex(UpdateReceiverEmailByID)
+	return r.audited(audit, actionUpdate, []uuid.UUID{id}, func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(UpdateReceiverEmailByID, email, id, version)
+		if err != nil {
+			return err
+		}
+		return checkVersion(tx, res, id)
+	})
+}
+
+// UpdateStatus moves the receiver from one status to another, the current status is checked in the same
+// statement so concurrent transitions can't both succeed
+func (r *Receiver) UpdateStatus(audit dtos.Audit, id uuid.UUID, from, to entity.UserStatus) error {
+	var affected int64
+	err := r.audited(audit, actionStatus, []uuid.UUID{id}, func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(UpdateReceiverStatusByID, to, id, from)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	if _, err = stmt.Exec(email, id); err != nil {
+	if affected == 0 {
+		if _, err = r.GetByID(id); err != nil {
+			return err
+		}
+		return receiver.ErrStatusChanged
+	}
+	return nil
+}
+
+// SaveValidation records the result of a key directory validation, an empty reason marks the draft
+// receiver as valid while any other is kept as its validation error. Either is only saved while the
+// receiver is still a draft that wasn't deleted, ErrStatusChanged is returned otherwise
+func (r *Receiver) SaveValidation(audit dtos.Audit, id uuid.UUID, reason string) error {
+	var affected int64
+	err := r.audited(audit, actionValidation, []uuid.UUID{id}, func(tx *sqlx.Tx) error {
+		var res sql.Result
+		var err error
+		if len(reason) > 0 {
+			res, err = tx.Exec(SetReceiverValidationError, reason, id)
+		} else {
+			res, err = tx.Exec(MarkReceiverValid, id)
+		}
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
 		return err
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return receiver.ErrStatusChanged
 	}
 	return nil
 }
@@ -105,34 +238,189 @@ func (r *Receiver) GetByID(id uuid.UUID) (*dtos.GetReceiverResponse, error) {
 	return &resp, nil
 }
 
-func (r *Receiver) List(page int) ([]dtos.ListReceiversResponse, error) {
-	limit := 10
-	offset := limit * (page - 1)
-	stmt, err := r.db.Preparex(QueryListOfReceivers)
+func (r *Receiver) List(filter receiver.Filter, offset, limit int) ([]dtos.ListReceiversResponse, error) {
+	b := sqlBuilder{}
+	b.filter(filter)
+	order, err := b.orderBy(filter.Sort, false)
 	if err != nil {
 		return nil, err
 	}
-	var resp []dtos.ListReceiversResponse
-	err = stmt.Select(&resp, limit, offset)
+	query := b.sql(QueryListOfReceivers) + order + " OFFSET " + b.arg(offset) + " LIMIT " + b.arg(limit)
+	resp := []dtos.ListReceiversResponse{}
+	if err = r.db.Select(&resp, query, b.args...); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListFrom compares the sort field and id of the receivers as a row, which the created_at index serves for the
+// default sort. Backward pages are read in the reverse order and their rows reversed once read
+func (r *Receiver) ListFrom(filter receiver.Filter, cursor receiver.Cursor, limit int) ([]dtos.ListReceiversResponse, error) {
+	b := sqlBuilder{}
+	b.filter(filter)
+	if err := b.after(cursor); err != nil {
+		return nil, err
+	}
+	order, err := b.orderBy(cursor.Sort, cursor.Backward)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, receiver.ErrReceiverNotFound
-		}
 		return nil, err
 	}
+	query := b.sql(QueryListOfReceivers) + order + " LIMIT " + b.arg(limit)
+	resp := []dtos.ListReceiversResponse{}
+	if err = r.db.Select(&resp, query, b.args...); err != nil {
+		return nil, err
+	}
+	if cursor.Backward {
+		for i, j := 0, len(resp)-1; i < j; i, j = i+1, j-1 {
+			resp[i], resp[j] = resp[j], resp[i]
+		}
+	}
 	return resp, nil
 }
 
-func (r *Receiver) Delete(id ...uuid.UUID) error {
-	query, args, err := sqlx.In(DelteReceiversByID, id)
+func (r *Receiver) Count(filter receiver.Filter) (int, error) {
+	b := sqlBuilder{}
+	b.filter(filter)
+	var total int
+	err := r.db.Get(&total, b.sql(QueryCountReceivers), b.args...)
+	return total, err
+}
+
+// Delete soft deletes the receivers, they are hidden from every query until restored or purged
+func (r *Receiver) Delete(audit dtos.Audit, id ...uuid.UUID) error {
+	query, args, err := sqlx.In(SoftDeleteReceiversByID, audit.Actor, id)
 	if err != nil {
 		return err
 	}
-	query = r.db.Rebind(query)
-	res, err := r.db.Exec(query, args...)
+	return r.audited(audit, actionDelete, id, func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(query), args...)
+		return err
+	})
+}
+
+func (r *Receiver) Restore(audit dtos.Audit, uniqueDocument bool, id ...uuid.UUID) (int64, error) {
+	query, args, err := sqlx.In(RestoreReceiversByID, id)
+	if err != nil {
+		return 0, err
+	}
+	documentsQuery, documentsArgs, err := sqlx.In(QueryDeletedReceiverDocuments, id)
 	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	err = r.audited(audit, actionRestore, id, func(tx *sqlx.Tx) error {
+		var restored []struct {
+			Id       uuid.UUID `db:"id"`
+			Document string    `db:"document"`
+		}
+		if uniqueDocument {
+			if err := tx.Select(&restored, tx.Rebind(documentsQuery), documentsArgs...); err != nil {
+				return err
+			}
+		}
+		res, err := tx.Exec(tx.Rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		// checked once restored, so receivers restored together with the same document refuse each other
+		for _, rcv := range restored {
+			if err = checkDuplicateDocument(tx, rcv.Id, rcv.Document); err != nil {
+				return err
+			}
+		}
+		affected, err = res.RowsAffected()
 		return err
+	})
+	return affected, err
+}
+
+// Purge permanently removes the receivers soft deleted longer than the retention, along with their pix keys. Their
+// history is kept, ending with the purge entry
+func (r *Receiver) Purge(audit dtos.Audit, retention time.Duration) (int64, error) {
+	var ids []uuid.UUID
+	if err := r.db.Select(&ids, QueryPurgeableReceivers, retention.Microseconds()); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	query, args, err := sqlx.In(PurgeReceiversByID, ids)
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	err = r.audited(audit, actionPurge, ids, func(tx *sqlx.Tx) error {
+		// receivers restored since they were listed are kept, as the deletion is checked again
+		res, err := tx.Exec(tx.Rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
+	return affected, err
+}
+
+// checkDuplicateDocument looks for another receiver, not deleted, with the same document. Receivers with the same
+// document are serialized by an advisory lock, held until the end of the transaction, so two of them can't be
+// stored at the same time
+func checkDuplicateDocument(tx *sqlx.Tx, id uuid.UUID, document string) error {
+	if _, err := tx.Exec(LockReceiverDocument, document); err != nil {
+		return err
+	}
+	var existing uuid.UUID
+	if err := tx.Get(&existing, QueryReceiverByDocument, document, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	return &receiver.DuplicateReceiverError{ExistingID: existing, Field: "document"}
+}
+
+// duplicatePixKey translates the unique violation of the receiver pix key into a DuplicateReceiverError holding
+// the receiver that owns the key, deleted receivers included since their keys stay reserved
+func duplicatePixKey(q sqlx.Queryer, rcv *entity.Receiver, err error) error {
+	if !errors.Is(err, receiver.ErrPixKeyAlreadyRegistered) || rcv.PixKey() == nil {
+		return err
+	}
+	var existing uuid.UUID
+	if ownerErr := sqlx.Get(q, &existing, QueryPixKeyOwner, rcv.PixKey().Value()); ownerErr != nil {
+		return err
+	}
+	return &receiver.DuplicateReceiverError{ExistingID: existing, Field: "pix key"}
+}
+
+// checkVersion reports a version mismatch when the versioned update statement didn't match the receiver, unless
+// the receiver itself is missing or was deleted in the meantime
+func checkVersion(tx *sqlx.Tx, res sql.Result, id uuid.UUID) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	var exists bool
+	if err = tx.Get(&exists, QueryReceiverNotDeleted, id); err != nil {
+		return err
+	}
+	if !exists {
+		return receiver.ErrReceiverNotFound
+	}
+	return receiver.ErrVersionMismatch
+}
+
+// bankAccountArgs flattens the optional bank account into its nullable columns
+func bankAccountArgs(account *vo.BankAccount) []interface{} {
+	if account == nil {
+		return []interface{}{nil, nil, nil, nil, nil}
+	}
+	return []interface{}{
+		account.BankCode(),
+		account.Branch(),
+		account.Number(),
+		account.Digit(),
+		string(account.AccountType()),
 	}
-	fmt.Println(res.RowsAffected())
-	return nil
 }
@@ -0,0 +1,53 @@
+package dict
+
+import (
+	"errors"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_Lookup(t *testing.T) {
+	directory := NewMemory()
+	directory.Register("lucasszmt@gmail.com", receiver.KeyOwner{Document: "08412535952", Name: "Lucas Szeremeta", ISPB: "60701190"})
+	directory.Register("+5541999999999", receiver.KeyOwner{Document: "41926766059", Name: "Ian Mcgregor", ISPB: "18236120"})
+	server := httptest.NewServer(NewHandler(directory))
+	defer server.Close()
+	client := NewClient(server.URL+"/", time.Second)
+
+	tests := []struct {
+		name    string
+		key     string
+		want    *receiver.KeyOwner
+		wantErr error
+	}{
+		{
+			name: "email key",
+			key:  "lucasszmt@gmail.com",
+			want: &receiver.KeyOwner{Document: "08412535952", Name: "Lucas Szeremeta", ISPB: "60701190"},
+		},
+		{
+			name: "phone key",
+			key:  "+5541999999999",
+			want: &receiver.KeyOwner{Document: "41926766059", Name: "Ian Mcgregor", ISPB: "18236120"},
+		},
+		{
+			name:    "unknown key",
+			key:     "123e4567-e12b-12d1-a456-426655440000",
+			wantErr: receiver.ErrKeyNotInDirectory,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Lookup(tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
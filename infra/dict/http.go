@@ -0,0 +1,52 @@
+package dict
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Entry is the body returned by the directory for a registered key
+type Entry struct {
+	Key           string `json:"key"`
+	OwnerDocument string `json:"owner_document"`
+	OwnerName     string `json:"owner_name"`
+	ISPB          string `json:"ispb"`
+}
+
+// Client looks keys up in a key directory served over http, such as the stand-in server in cmd/dict
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) Lookup(key string) (*receiver.KeyOwner, error) {
+	resp, err := c.http.Get(fmt.Sprintf("%s/entries/%s", c.baseURL, url.PathEscape(key)))
+	if err != nil {
+		return nil, fmt.Errorf("error querying the key directory: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, receiver.ErrKeyNotInDirectory
+	default:
+		return nil, fmt.Errorf("unexpected key directory response status %d", resp.StatusCode)
+	}
+	entry := Entry{}
+	if err = json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("invalid key directory response: %w", err)
+	}
+	return &receiver.KeyOwner{Document: entry.OwnerDocument, Name: entry.OwnerName, ISPB: entry.ISPB}, nil
+}
@@ -0,0 +1,33 @@
+package dict
+
+import (
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"sync"
+)
+
+// Memory is an in memory key directory, used in tests and as the backing store of the stand-in server
+type Memory struct {
+	mu      sync.RWMutex
+	entries map[string]receiver.KeyOwner
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]receiver.KeyOwner)}
+}
+
+// Register adds a key to the directory, replacing its owner when the key is already registered
+func (m *Memory) Register(key string, owner receiver.KeyOwner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = owner
+}
+
+func (m *Memory) Lookup(key string) (*receiver.KeyOwner, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	owner, ok := m.entries[key]
+	if !ok {
+		return nil, receiver.ErrKeyNotInDirectory
+	}
+	return &owner, nil
+}
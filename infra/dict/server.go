@@ -0,0 +1,41 @@
+package dict
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/lucasszmt/transfeera-challenge/domain/receiver"
+	"net/http"
+	"strings"
+)
+
+const entriesPath = "/entries/"
+
+// NewHandler serves a key directory over http, answering GET /entries/{key} with the key Entry, or 404
+// when the key isn't registered. It is the local stand-in for the DICT used by Client
+func NewHandler(directory receiver.KeyDirectory) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(entriesPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, entriesPath)
+		owner, err := directory.Lookup(key)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, receiver.ErrKeyNotInDirectory) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Entry{
+			Key:           key,
+			OwnerDocument: owner.Document,
+			OwnerName:     owner.Name,
+			ISPB:          owner.ISPB,
+		})
+	})
+	return mux
+}